@@ -1,7 +1,8 @@
 package main
 
 import (
-	"log"
+	"log/slog"
+	"os"
 	"usersubs/internal"
 )
 
@@ -11,7 +12,8 @@ import (
 // @BasePath /
 
 func main() {
-	if err := internal.Start(); err != nil {
-		log.Printf("%v\n", err)
+	if err := internal.Start(os.Args[1:]); err != nil {
+		slog.Error("Server stopped", "error", err)
+		os.Exit(1)
 	}
 }
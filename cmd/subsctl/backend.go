@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"time"
+	"usersubs/internal/subs/service"
+	"usersubs/internal/utils"
+	"usersubs/pkg/client"
+
+	"github.com/google/uuid"
+)
+
+type sub struct {
+	ID          int32          `json:"id,omitempty"`
+	ServiceName string         `json:"service_name"`
+	Price       int32          `json:"price"`
+	UserID      uuid.UUID      `json:"user_id"`
+	StartedAt   utils.JSONDate `json:"start_date"`
+	EndedAt     utils.JSONDate `json:"end_date,omitzero"`
+	TrialEndsAt time.Time      `json:"trial_ends_at,omitzero"`
+	TrialPrice  int32          `json:"trial_price,omitempty"`
+	// Pauses are only read, import and update leave them as they are.
+	Pauses    []pause    `json:"pauses,omitempty"`
+	Prices    []price    `json:"prices,omitempty"`
+	Discounts []discount `json:"discounts,omitempty"`
+	Members   []member   `json:"members,omitempty"`
+}
+
+type member struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Status    string    `json:"status"`
+	Split     string    `json:"split"`
+	Amount    int32     `json:"amount,omitempty"`
+	JoinedAt  time.Time `json:"joined_at,omitzero"`
+	RemovedAt time.Time `json:"removed_at,omitzero"`
+}
+
+type discount struct {
+	Kind      string         `json:"kind"`
+	Amount    int32          `json:"amount"`
+	StartedAt utils.JSONDate `json:"start_date"`
+	EndsAt    utils.JSONDate `json:"ends_at"`
+}
+
+type price struct {
+	EffectiveFrom utils.JSONDate `json:"effective_from"`
+	Price         int32          `json:"price"`
+	Currency      string         `json:"currency,omitempty"`
+}
+
+type pause struct {
+	ID        int32          `json:"id,omitempty"`
+	StartedAt utils.JSONDate `json:"start_date"`
+	EndedAt   utils.JSONDate `json:"end_date,omitzero"`
+}
+
+// backend is either the database or the HTTP API of a running server.
+type backend interface {
+	// List also returns the subscriptions shared with userID when shared
+	// is set.
+	List(ctx context.Context, userID uuid.UUID, shared bool) ([]sub, error)
+	Get(ctx context.Context, id int32) (sub, error)
+	Create(ctx context.Context, s sub) (int32, error)
+	Update(ctx context.Context, s sub) error
+	Delete(ctx context.Context, id int32) error
+	DeleteUser(ctx context.Context, userID uuid.UUID) ([]int32, error)
+}
+
+// dbBackend works with the database through the service layer, like the
+// server does.
+type dbBackend struct {
+	svc      *service.SubscriptionService
+	tenantID uuid.UUID
+}
+
+func (b dbBackend) List(ctx context.Context, userID uuid.UUID, shared bool) ([]sub, error) {
+	list, err := b.svc.List(ctx, b.tenantID, service.ListFilter{UserID: userID, IncludeShared: shared})
+	subs := make([]sub, 0, len(list))
+	for _, s := range list {
+		subs = append(subs, fromService(s))
+	}
+	return subs, err
+}
+
+func (b dbBackend) Get(ctx context.Context, id int32) (sub, error) {
+	s, err := b.svc.Get(ctx, b.tenantID, id)
+	return fromService(s), err
+}
+
+func (b dbBackend) Create(ctx context.Context, s sub) (int32, error) {
+	created, err := b.svc.Create(ctx, b.tenantID, s.toService())
+	return created.ID, err
+}
+
+func (b dbBackend) Update(ctx context.Context, s sub) error {
+	_, err := b.svc.Update(ctx, b.tenantID, s.toService())
+	return err
+}
+
+func (b dbBackend) Delete(ctx context.Context, id int32) error {
+	_, err := b.svc.Delete(ctx, b.tenantID, id)
+	return err
+}
+
+func (b dbBackend) DeleteUser(ctx context.Context, userID uuid.UUID) ([]int32, error) {
+	return b.svc.DeleteUser(ctx, b.tenantID, userID)
+}
+
+func fromService(s service.Subscription) sub {
+	return sub{
+		ID:          s.ID,
+		ServiceName: s.ServiceName,
+		Price:       s.Price,
+		UserID:      s.UserID,
+		StartedAt:   utils.JSONDate(s.StartDate),
+		EndedAt:     utils.JSONDate(s.EndDate),
+		TrialEndsAt: s.TrialEndsAt,
+		TrialPrice:  s.TrialPrice,
+		Pauses:      fromServicePauses(s.Pauses),
+		Prices:      fromServicePrices(s.Prices),
+		Discounts:   fromServiceDiscounts(s.Discounts),
+		Members:     fromServiceMembers(s.Members),
+	}
+}
+
+func fromServiceMembers(members []service.Member) []member {
+	var res []member
+	for _, m := range members {
+		res = append(res, member{UserID: m.UserID, Status: string(m.Status), Split: string(m.Split), Amount: m.Amount, JoinedAt: m.JoinedAt, RemovedAt: m.RemovedAt})
+	}
+	return res
+}
+
+func fromServiceDiscounts(discounts []service.Discount) []discount {
+	var res []discount
+	for _, d := range discounts {
+		res = append(res, discount{Kind: string(d.Kind), Amount: d.Amount, StartedAt: utils.JSONDate(d.StartDate), EndsAt: utils.JSONDate(d.EndsAt)})
+	}
+	return res
+}
+
+func fromServicePrices(prices []service.Price) []price {
+	var res []price
+	for _, p := range prices {
+		res = append(res, price{EffectiveFrom: utils.JSONDate(p.EffectiveFrom), Price: p.Price, Currency: p.Currency})
+	}
+	return res
+}
+
+func fromServicePauses(pauses []service.Pause) []pause {
+	var res []pause
+	for _, p := range pauses {
+		res = append(res, pause{ID: p.ID, StartedAt: utils.JSONDate(p.StartDate), EndedAt: utils.JSONDate(p.EndDate)})
+	}
+	return res
+}
+
+func (s sub) toService() service.Subscription {
+	return service.Subscription{
+		ID:          s.ID,
+		ServiceName: s.ServiceName,
+		Price:       s.Price,
+		UserID:      s.UserID,
+		StartDate:   time.Time(s.StartedAt),
+		EndDate:     time.Time(s.EndedAt),
+		TrialEndsAt: s.TrialEndsAt,
+		TrialPrice:  s.TrialPrice,
+		Pauses:      toServicePauses(s.Pauses),
+		Prices:      toServicePrices(s.Prices),
+		Discounts:   toServiceDiscounts(s.Discounts),
+		Members:     toServiceMembers(s.Members),
+	}
+}
+
+func toServiceMembers(members []member) []service.Member {
+	var res []service.Member
+	for _, m := range members {
+		res = append(res, service.Member{UserID: m.UserID, Status: service.MemberStatus(m.Status), Split: service.Split(m.Split), Amount: m.Amount, JoinedAt: m.JoinedAt, RemovedAt: m.RemovedAt})
+	}
+	return res
+}
+
+func toServiceDiscounts(discounts []discount) []service.Discount {
+	var res []service.Discount
+	for _, d := range discounts {
+		res = append(res, service.Discount{Kind: service.DiscountKind(d.Kind), Amount: d.Amount, StartDate: time.Time(d.StartedAt), EndsAt: time.Time(d.EndsAt)})
+	}
+	return res
+}
+
+func toServicePrices(prices []price) []service.Price {
+	var res []service.Price
+	for _, p := range prices {
+		res = append(res, service.Price{EffectiveFrom: time.Time(p.EffectiveFrom), Price: p.Price, Currency: p.Currency})
+	}
+	return res
+}
+
+func toServicePauses(pauses []pause) []service.Pause {
+	var res []service.Pause
+	for _, p := range pauses {
+		res = append(res, service.Pause{ID: p.ID, StartDate: time.Time(p.StartedAt), EndDate: time.Time(p.EndedAt)})
+	}
+	return res
+}
+
+// apiBackend calls a running server through pkg/client.
+type apiBackend struct {
+	client *client.Client
+}
+
+func toClient(s sub) client.Subscription {
+	return client.Subscription{
+		ID:          s.ID,
+		ServiceName: s.ServiceName,
+		Price:       s.Price,
+		UserID:      s.UserID,
+		StartDate:   client.Month{Time: time.Time(s.StartedAt)},
+		EndDate:     client.Month{Time: time.Time(s.EndedAt)},
+		TrialEndsAt: s.TrialEndsAt,
+		TrialPrice:  s.TrialPrice,
+	}
+}
+
+func fromClient(s client.Subscription) sub {
+	return sub{
+		ID:          s.ID,
+		ServiceName: s.ServiceName,
+		Price:       s.Price,
+		UserID:      s.UserID,
+		StartedAt:   utils.JSONDate(s.StartDate.Time),
+		EndedAt:     utils.JSONDate(s.EndDate.Time),
+		TrialEndsAt: s.TrialEndsAt,
+		TrialPrice:  s.TrialPrice,
+		Pauses:      fromClientPauses(s.Pauses),
+		Prices:      fromClientPrices(s.Prices),
+		Discounts:   fromClientDiscounts(s.Discounts),
+		Members:     fromClientMembers(s.Members),
+	}
+}
+
+func fromClientMembers(members []client.Member) []member {
+	var res []member
+	for _, m := range members {
+		res = append(res, member{UserID: m.UserID, Status: m.Status, Split: m.Split, Amount: m.Amount, JoinedAt: m.JoinedAt, RemovedAt: m.RemovedAt})
+	}
+	return res
+}
+
+func fromClientDiscounts(discounts []client.Discount) []discount {
+	var res []discount
+	for _, d := range discounts {
+		res = append(res, discount{Kind: d.Kind, Amount: d.Amount, StartedAt: utils.JSONDate(d.StartDate.Time), EndsAt: utils.JSONDate(d.EndsAt.Time)})
+	}
+	return res
+}
+
+func fromClientPrices(prices []client.Price) []price {
+	var res []price
+	for _, p := range prices {
+		res = append(res, price{EffectiveFrom: utils.JSONDate(p.EffectiveFrom.Time), Price: p.Price, Currency: p.Currency})
+	}
+	return res
+}
+
+func fromClientPauses(pauses []client.Pause) []pause {
+	var res []pause
+	for _, p := range pauses {
+		res = append(res, pause{ID: p.ID, StartedAt: utils.JSONDate(p.StartDate.Time), EndedAt: utils.JSONDate(p.EndDate.Time)})
+	}
+	return res
+}
+
+func (b apiBackend) List(ctx context.Context, userID uuid.UUID, shared bool) ([]sub, error) {
+	var subs []sub
+	for s, err := range b.client.AllSubs(ctx, client.ListOptions{UserID: userID, IncludeShared: shared}) {
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, fromClient(s))
+	}
+	return subs, nil
+}
+
+func (b apiBackend) Get(ctx context.Context, id int32) (sub, error) {
+	s, err := b.client.GetSub(ctx, id)
+	return fromClient(s), err
+}
+
+func (b apiBackend) Create(ctx context.Context, s sub) (int32, error) {
+	created, err := b.client.CreateSub(ctx, toClient(s))
+	return created.ID, err
+}
+
+func (b apiBackend) Update(ctx context.Context, s sub) error {
+	_, err := b.client.UpdateSub(ctx, toClient(s))
+	return err
+}
+
+func (b apiBackend) Delete(ctx context.Context, id int32) error {
+	return b.client.DeleteSub(ctx, id)
+}
+
+func (b apiBackend) DeleteUser(ctx context.Context, userID uuid.UUID) ([]int32, error) {
+	return b.client.DeleteUserSubs(ctx, userID)
+}
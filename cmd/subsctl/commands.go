@@ -0,0 +1,394 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+	"usersubs/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+func parseID(args []string) (int32, error) {
+	if len(args) == 0 {
+		return 0, errors.New("subscription id is required")
+	}
+	id, err := strconv.ParseInt(args[0], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse id - %v", err)
+	}
+	return int32(id), nil
+}
+
+func parseDate(s string) (utils.JSONDate, error) {
+	if s == "" {
+		return utils.JSONDate{}, nil
+	}
+	t, err := time.Parse(dateFormat, s)
+	if err != nil {
+		return utils.JSONDate{}, fmt.Errorf("could not parse date %q, expected MM-YYYY - %v", s, err)
+	}
+	return utils.JSONDate(t), nil
+}
+
+func parseUser(s string) (uuid.UUID, error) {
+	if s == "" {
+		return uuid.Nil, nil
+	}
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("could not parse user id - %v", err)
+	}
+	return id, nil
+}
+
+func (a app) list(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	user := flags.String("user", "", "only subscriptions of this user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	userID, err := parseUser(*user)
+	if err != nil {
+		return err
+	}
+
+	subs, err := a.backend.List(ctx, userID, false)
+	if err != nil {
+		return err
+	}
+	return writeSubs(os.Stdout, a.output, subs)
+}
+
+func (a app) show(ctx context.Context, args []string) error {
+	id, err := parseID(args)
+	if err != nil {
+		return err
+	}
+
+	s, err := a.backend.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if a.output == "json" {
+		return write(os.Stdout, a.output, nil, nil, s)
+	}
+	return writeSubs(os.Stdout, a.output, []sub{s})
+}
+
+// subFlags binds the editable fields of s to flags and returns a function
+// applying the ones that were set.
+func subFlags(flags *flag.FlagSet, s *sub) func() error {
+	service := flags.String("service", "", "service name")
+	price := flags.Int("price", 0, "monthly price")
+	user := flags.String("user", "", "user id")
+	start := flags.String("start", "", "start month, MM-YYYY")
+	end := flags.String("end", "", "end month, MM-YYYY")
+
+	return func() error {
+		var err error
+		flags.Visit(func(f *flag.Flag) {
+			if err != nil {
+				return
+			}
+			switch f.Name {
+			case "service":
+				s.ServiceName = *service
+			case "price":
+				s.Price = int32(*price)
+			case "user":
+				s.UserID, err = parseUser(*user)
+			case "start":
+				s.StartedAt, err = parseDate(*start)
+			case "end":
+				s.EndedAt, err = parseDate(*end)
+			}
+		})
+		return err
+	}
+}
+
+func (a app) create(ctx context.Context, args []string) error {
+	var s sub
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	apply := subFlags(flags, &s)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := apply(); err != nil {
+		return err
+	}
+	if s.ServiceName == "" || s.UserID == uuid.Nil || time.Time(s.StartedAt).IsZero() {
+		return errors.New("-service, -user and -start are required")
+	}
+
+	id, err := a.backend.Create(ctx, s)
+	if err != nil {
+		return err
+	}
+	s.ID = id
+	return writeSubs(os.Stdout, a.output, []sub{s})
+}
+
+func (a app) update(ctx context.Context, args []string) error {
+	id, err := parseID(args)
+	if err != nil {
+		return err
+	}
+
+	s, err := a.backend.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("update", flag.ContinueOnError)
+	apply := subFlags(flags, &s)
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := apply(); err != nil {
+		return err
+	}
+
+	if err := a.backend.Update(ctx, s); err != nil {
+		return err
+	}
+	return writeSubs(os.Stdout, a.output, []sub{s})
+}
+
+func (a app) delete(ctx context.Context, args []string) error {
+	id, err := parseID(args)
+	if err != nil {
+		return err
+	}
+
+	if err := a.backend.Delete(ctx, id); err != nil {
+		return err
+	}
+	fmt.Printf("deleted %d\n", id)
+	return nil
+}
+
+func (a app) deleteUser(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("user id is required")
+	}
+	userID, err := parseUser(args[0])
+	if err != nil {
+		return err
+	}
+
+	ids, err := a.backend.DeleteUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("deleted %d subscriptions\n", len(ids))
+	return nil
+}
+
+// periodFlags adds -from and -to, both defaulting to the current month.
+func periodFlags(flags *flag.FlagSet) func() (time.Time, time.Time, error) {
+	now := time.Now().Format(dateFormat)
+	from := flags.String("from", now, "first month, MM-YYYY")
+	to := flags.String("to", now, "last month, MM-YYYY")
+
+	return func() (time.Time, time.Time, error) {
+		f, err := parseDate(*from)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		t, err := parseDate(*to)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		if time.Time(t).Before(time.Time(f)) {
+			return time.Time{}, time.Time{}, errors.New("-to is before -from")
+		}
+		return time.Time(f), time.Time(t), nil
+	}
+}
+
+func (a app) total(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("total", flag.ContinueOnError)
+	period := periodFlags(flags)
+	user := flags.String("user", "", "only the share of this user in their own and shared subscriptions")
+	service := flags.String("service", "", "only subscriptions of this service")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	from, to, err := period()
+	if err != nil {
+		return err
+	}
+	userID, err := parseUser(*user)
+	if err != nil {
+		return err
+	}
+
+	subs, err := a.backend.List(ctx, userID, true)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, s := range subs {
+		if *service != "" && s.ServiceName != *service {
+			continue
+		}
+		if userID != uuid.Nil {
+			total += s.toService().ShareCost(userID, from, to)
+		} else {
+			total += s.toService().Cost(from, to)
+		}
+	}
+
+	res := struct {
+		From  string `json:"from"`
+		To    string `json:"to"`
+		Total int64  `json:"total"`
+	}{from.Format(dateFormat), to.Format(dateFormat), total}
+	return write(os.Stdout, a.output, []string{"from", "to", "total"},
+		[][]string{{res.From, res.To, strconv.FormatInt(total, 10)}}, res)
+}
+
+type serviceReport struct {
+	ServiceName   string `json:"service_name"`
+	Subscriptions int    `json:"subscriptions"`
+	Users         int    `json:"users"`
+	Total         int64  `json:"total"`
+}
+
+func (a app) report(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	period := periodFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	from, to, err := period()
+	if err != nil {
+		return err
+	}
+
+	subs, err := a.backend.List(ctx, uuid.Nil, false)
+	if err != nil {
+		return err
+	}
+
+	byService := map[string]*serviceReport{}
+	users := map[string]map[uuid.UUID]bool{}
+	for _, s := range subs {
+		months := s.toService().ActiveMonths(from, to)
+		if months == 0 {
+			continue
+		}
+		r, ok := byService[s.ServiceName]
+		if !ok {
+			r = &serviceReport{ServiceName: s.ServiceName}
+			byService[s.ServiceName] = r
+			users[s.ServiceName] = map[uuid.UUID]bool{}
+		}
+		r.Subscriptions++
+		r.Total += s.toService().Cost(from, to)
+		users[s.ServiceName][s.UserID] = true
+	}
+
+	reports := make([]serviceReport, 0, len(byService))
+	for name, r := range byService {
+		r.Users = len(users[name])
+		reports = append(reports, *r)
+	}
+	slices.SortFunc(reports, func(a, b serviceReport) int { return cmp.Compare(b.Total, a.Total) })
+
+	rows := make([][]string, 0, len(reports))
+	for _, r := range reports {
+		rows = append(rows, []string{r.ServiceName, strconv.Itoa(r.Subscriptions), strconv.Itoa(r.Users), strconv.FormatInt(r.Total, 10)})
+	}
+	return write(os.Stdout, a.output, []string{"service_name", "subscriptions", "users", "total"}, rows, reports)
+}
+
+// importCSV reads rows in the export format, the id column is ignored.
+func (a app) importCSV(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("file is required, - for stdin")
+	}
+
+	var in io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = len(subHeader)
+	records, err := r.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) > 0 && slices.Equal(records[0], subHeader) {
+		records = records[1:]
+	}
+
+	for i, rec := range records {
+		s, err := parseRecord(rec)
+		if err != nil {
+			return fmt.Errorf("line %d - %v", i+2, err)
+		}
+		if _, err := a.backend.Create(ctx, s); err != nil {
+			return fmt.Errorf("line %d - %v", i+2, err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "imported %d subscriptions\n", len(records))
+	return nil
+}
+
+func parseRecord(rec []string) (sub, error) {
+	price, err := strconv.ParseInt(rec[2], 10, 32)
+	if err != nil {
+		return sub{}, fmt.Errorf("could not parse price - %v", err)
+	}
+	userID, err := parseUser(rec[3])
+	if err != nil {
+		return sub{}, err
+	}
+	start, err := parseDate(rec[4])
+	if err != nil {
+		return sub{}, err
+	}
+	end, err := parseDate(rec[5])
+	if err != nil {
+		return sub{}, err
+	}
+
+	return sub{ServiceName: rec[1], Price: int32(price), UserID: userID, StartedAt: start, EndedAt: end}, nil
+}
+
+func (a app) exportCSV(ctx context.Context, args []string) error {
+	out := os.Stdout
+	if len(args) > 0 && args[0] != "-" {
+		f, err := os.Create(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	subs, err := a.backend.List(ctx, uuid.Nil, false)
+	if err != nil {
+		return err
+	}
+	return writeSubs(out, "csv", subs)
+}
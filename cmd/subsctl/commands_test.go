@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"usersubs/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+func month(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := parseDate(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return time.Time(d)
+}
+
+func TestParse(t *testing.T) {
+	if _, err := parseID(nil); err == nil {
+		t.Error("parseID accepted no arguments")
+	}
+	if _, err := parseID([]string{"x"}); err == nil {
+		t.Error("parseID accepted x")
+	}
+	if id, err := parseID([]string{"42", "-price", "1"}); err != nil || id != 42 {
+		t.Errorf("parseID = %d, %v, want 42", id, err)
+	}
+
+	if d, err := parseDate(""); err != nil || !time.Time(d).IsZero() {
+		t.Errorf("parseDate(\"\") = %v, %v, want zero", d, err)
+	}
+	if _, err := parseDate("2025-07"); err == nil {
+		t.Error("parseDate accepted 2025-07")
+	}
+	if got := month(t, "07-2025"); !got.Equal(time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("parseDate(07-2025) = %v", got)
+	}
+
+	if id, err := parseUser(""); err != nil || id != uuid.Nil {
+		t.Errorf("parseUser(\"\") = %v, %v, want nil", id, err)
+	}
+	if _, err := parseUser("bob"); err == nil {
+		t.Error("parseUser accepted bob")
+	}
+}
+
+// Subscriptions keep every field through the service and the API client.
+func TestConversions(t *testing.T) {
+	subs := []sub{
+		{ID: 1, ServiceName: "Netflix", Price: 400, UserID: uuid.New(), StartedAt: utils.JSONDate(month(t, "07-2025"))},
+		{ID: 2, ServiceName: "Spotify", Price: 200, UserID: uuid.New(), StartedAt: utils.JSONDate(month(t, "01-2025")), EndedAt: utils.JSONDate(month(t, "12-2025"))},
+	}
+	for _, s := range subs {
+		if got := fromClient(toClient(s)); !reflect.DeepEqual(got, s) {
+			t.Errorf("client round trip = %+v, want %+v", got, s)
+		}
+	}
+
+	// Pauses are only read, so only the service round trip keeps them.
+	subs[1].Pauses = []pause{{ID: 3, StartedAt: utils.JSONDate(month(t, "03-2025")), EndedAt: utils.JSONDate(month(t, "04-2025"))}}
+	for _, s := range subs {
+		if got := fromService(s.toService()); !reflect.DeepEqual(got, s) {
+			t.Errorf("service round trip = %+v, want %+v", got, s)
+		}
+	}
+}
+
+func TestParseRecord(t *testing.T) {
+	user := uuid.New()
+	s, err := parseRecord([]string{"7", "Netflix", "400", user.String(), "07-2025", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != 0 || s.ServiceName != "Netflix" || s.Price != 400 || s.UserID != user ||
+		!time.Time(s.StartedAt).Equal(month(t, "07-2025")) || !time.Time(s.EndedAt).IsZero() {
+		t.Errorf("parseRecord = %+v", s)
+	}
+
+	for _, rec := range [][]string{
+		{"", "Netflix", "cheap", user.String(), "07-2025", ""},
+		{"", "Netflix", "400", "bob", "07-2025", ""},
+		{"", "Netflix", "400", user.String(), "2025-07", ""},
+	} {
+		if _, err := parseRecord(rec); err == nil {
+			t.Errorf("parseRecord(%q) accepted it", rec)
+		}
+	}
+}
+
+func TestWriteSubs(t *testing.T) {
+	user := uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+	subs := []sub{{ID: 1, ServiceName: "Yandex Plus", Price: 400, UserID: user, StartedAt: utils.JSONDate(month(t, "07-2025"))}}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: "csv", want: "id,service_name,price,user_id,start_date,end_date\n1,Yandex Plus,400," + user.String() + ",07-2025,\n"},
+		{format: "table", want: "id  service_name  price  user_id                               start_date  end_date\n" +
+			"1   Yandex Plus   400    " + user.String() + "  07-2025     \n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeSubs(&buf, tt.format, subs); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s output:\n%q\nwant\n%q", tt.format, buf.String(), tt.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeSubs(&buf, "json", subs); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []sub
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 1 || !reflect.DeepEqual(decoded[0], subs[0]) {
+		t.Errorf("json round trip = %+v", decoded)
+	}
+
+	if err := writeSubs(&buf, "yaml", subs); err == nil || !strings.Contains(err.Error(), "unknown output") {
+		t.Errorf("yaml output error = %v", err)
+	}
+}
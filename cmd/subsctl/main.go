@@ -0,0 +1,160 @@
+// Command subsctl manages subscriptions from the terminal, directly in the
+// database or through the HTTP API of a running server.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"usersubs/internal/config"
+	"usersubs/internal/migrate"
+	"usersubs/internal/subs/service"
+	"usersubs/internal/tenant"
+	"usersubs/pkg/client"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+const usage = `Usage: subsctl [flags] <command> [command flags]
+
+Commands:
+  list [-user ID]                          list subscriptions
+  show ID                                  show a subscription
+  create -service S -price P -user ID -start MM-YYYY [-end MM-YYYY]
+  update ID [-service S] [-price P] [-user ID] [-start MM-YYYY] [-end MM-YYYY]
+  delete ID                                delete a subscription
+  delete-user ID                           delete all subscriptions of a user
+  total -from MM-YYYY -to MM-YYYY [-user ID] [-service S]
+                                           cost of subscriptions in a period
+  report [-from MM-YYYY] [-to MM-YYYY]     cost per service in a period
+  import FILE                              create subscriptions from CSV, - for stdin
+  export [FILE]                            write subscriptions as CSV
+  migrate up|down|status|to N              manage the schema (db mode only)
+
+Flags:
+`
+
+type app struct {
+	backend backend
+	sqlDB   *sql.DB
+	output  string
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "subsctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("subsctl", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	mode := flags.String("mode", envOr("SUBSCTL_MODE", "db"), "db to work with the database, api to call a running server")
+	apiURL := flags.String("api-url", envOr("SUBSCTL_API_URL", "http://localhost:5500"), "base URL of the server in api mode")
+	apiKey := flags.String("api-key", os.Getenv("SUBSCTL_API_KEY"), "X-API-Key for api mode")
+	tenantFlag := flags.String("tenant", envOr("SUBSCTL_TENANT", tenant.DefaultID.String()), "organization ID")
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "server config file, used for the database connection in db mode")
+	output := flags.String("o", "table", "output format: table, json or csv")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("no command given")
+	}
+
+	tenantID, err := uuid.Parse(*tenantFlag)
+	if err != nil {
+		return fmt.Errorf("could not parse tenant - %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	a := app{output: *output}
+	switch *mode {
+	case "db":
+		var cfgArgs []string
+		if *configFile != "" {
+			cfgArgs = []string{"--config", *configFile}
+		}
+		cfg, _, err := config.Load(cfgArgs)
+		if err != nil {
+			return err
+		}
+
+		// Migrations run as the owner of the tables, the rest as the
+		// server does.
+		connection := cfg.DB.Connection
+		if flags.Arg(0) == "migrate" && cfg.DB.MigrateConnection != "" {
+			connection = cfg.DB.MigrateConnection
+		}
+		a.sqlDB, err = sql.Open("postgres", connection)
+		if err != nil {
+			return err
+		}
+		defer a.sqlDB.Close()
+		a.backend = dbBackend{
+			svc: &service.SubscriptionService{
+				Repo:         service.PostgresRepository{DB: a.sqlDB},
+				QueryTimeout: cfg.DB.QueryTimeout,
+			},
+			tenantID: tenantID,
+		}
+	case "api":
+		c, err := client.New(client.Config{BaseURL: *apiURL, APIKey: *apiKey, TenantID: tenantID})
+		if err != nil {
+			return err
+		}
+		a.backend = apiBackend{client: c}
+	default:
+		return fmt.Errorf("unknown mode %q, expected db or api", *mode)
+	}
+
+	command, rest := flags.Arg(0), flags.Args()[1:]
+	switch command {
+	case "list":
+		return a.list(ctx, rest)
+	case "show":
+		return a.show(ctx, rest)
+	case "create":
+		return a.create(ctx, rest)
+	case "update":
+		return a.update(ctx, rest)
+	case "delete":
+		return a.delete(ctx, rest)
+	case "delete-user":
+		return a.deleteUser(ctx, rest)
+	case "total":
+		return a.total(ctx, rest)
+	case "report":
+		return a.report(ctx, rest)
+	case "import":
+		return a.importCSV(ctx, rest)
+	case "export":
+		return a.exportCSV(ctx, rest)
+	case "migrate":
+		if a.sqlDB == nil {
+			return fmt.Errorf("migrate works in db mode only")
+		}
+		return migrate.Run(ctx, a.sqlDB, rest, os.Stdout)
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func envOr(name, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
+}
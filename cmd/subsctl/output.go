@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+	"usersubs/internal/utils"
+)
+
+const dateFormat = "01-2006"
+
+var subHeader = []string{"id", "service_name", "price", "user_id", "start_date", "end_date"}
+
+func formatDate(d utils.JSONDate) string {
+	if time.Time(d).IsZero() {
+		return ""
+	}
+	return time.Time(d).Format(dateFormat)
+}
+
+func subRecord(s sub) []string {
+	return []string{
+		strconv.Itoa(int(s.ID)),
+		s.ServiceName,
+		strconv.Itoa(int(s.Price)),
+		s.UserID.String(),
+		formatDate(s.StartedAt),
+		formatDate(s.EndedAt),
+	}
+}
+
+// write prints rows in the chosen format, v is used for JSON.
+func write(out io.Writer, format string, header []string, rows [][]string, v any) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case "csv":
+		w := csv.NewWriter(out)
+		if err := w.Write(header); err != nil {
+			return err
+		}
+		if err := w.WriteAll(rows); err != nil {
+			return err
+		}
+		return w.Error()
+	case "table":
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		writeTabbed(tw, header)
+		for _, row := range rows {
+			writeTabbed(tw, row)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown output %q, expected table, json or csv", format)
+	}
+}
+
+func writeTabbed(w io.Writer, cells []string) {
+	for i, cell := range cells {
+		if i > 0 {
+			fmt.Fprint(w, "\t")
+		}
+		fmt.Fprint(w, cell)
+	}
+	fmt.Fprintln(w)
+}
+
+func writeSubs(out io.Writer, format string, subs []sub) error {
+	rows := make([][]string, 0, len(subs))
+	for _, s := range subs {
+		rows = append(rows, subRecord(s))
+	}
+	return write(out, format, subHeader, rows, subs)
+}
@@ -0,0 +1,10 @@
+#!/bin/sh
+# The server connects as DB_APP_USER, a member of usersubs_app without
+# superuser or BYPASSRLS, so the row level security policies apply to it.
+# Migrations run as POSTGRES_USER, the owner of the tables.
+set -e
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<-EOSQL
+    CREATE ROLE usersubs_app NOLOGIN;
+    CREATE ROLE "$DB_APP_USER" LOGIN PASSWORD '$DB_APP_PSWD' IN ROLE usersubs_app;
+EOSQL
@@ -0,0 +1,27 @@
+-- name: AddBudget :one
+INSERT INTO budgets (
+    tenant_id,
+    user_id,
+    scope,
+    target,
+    amount,
+    currency,
+    period,
+    enforce
+) VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+) RETURNING *;
+
+-- name: GetBudgets :many
+SELECT * FROM budgets WHERE user_id = $1 AND tenant_id = $2
+ORDER BY id;
+
+-- name: DeleteBudget :one
+DELETE FROM budgets WHERE id = $1 AND user_id = $2 AND tenant_id = $3 RETURNING id;
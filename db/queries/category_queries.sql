@@ -0,0 +1,51 @@
+-- name: AddCategory :one
+INSERT INTO categories (tenant_id, name) VALUES ($1, $2) RETURNING *;
+
+-- name: GetCategories :many
+SELECT * FROM categories WHERE tenant_id = $1
+ORDER BY name;
+
+-- name: GetCategoryByName :one
+SELECT * FROM categories WHERE name = $1 AND tenant_id = $2;
+
+-- name: DeleteCategory :one
+DELETE FROM categories WHERE id = $1 AND tenant_id = $2 RETURNING id;
+
+-- name: AddSubCategory :exec
+INSERT INTO subscription_categories (subscription_id, category_id, tenant_id) VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteSubCategory :one
+DELETE FROM subscription_categories sc USING categories c
+WHERE sc.category_id = c.id AND sc.subscription_id = $1 AND c.name = $2 AND sc.tenant_id = $3
+RETURNING sc.category_id;
+
+-- name: GetCategoriesBySubs :many
+SELECT sc.subscription_id, c.name FROM subscription_categories sc
+JOIN categories c ON c.id = sc.category_id
+WHERE sc.subscription_id = ANY(@subscription_ids::int[]) AND sc.tenant_id = @tenant_id
+ORDER BY sc.subscription_id, c.name;
+
+-- name: UpsertTag :one
+INSERT INTO tags (tenant_id, name) VALUES ($1, $2)
+ON CONFLICT (tenant_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING id;
+
+-- name: GetTags :many
+SELECT name FROM tags WHERE tenant_id = $1
+ORDER BY name;
+
+-- name: AddSubTag :exec
+INSERT INTO subscription_tags (subscription_id, tag_id, tenant_id) VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteSubTag :one
+DELETE FROM subscription_tags st USING tags t
+WHERE st.tag_id = t.id AND st.subscription_id = $1 AND t.name = $2 AND st.tenant_id = $3
+RETURNING st.tag_id;
+
+-- name: GetTagsBySubs :many
+SELECT st.subscription_id, t.name FROM subscription_tags st
+JOIN tags t ON t.id = st.tag_id
+WHERE st.subscription_id = ANY(@subscription_ids::int[]) AND st.tenant_id = @tenant_id
+ORDER BY st.subscription_id, t.name;
@@ -0,0 +1,36 @@
+-- name: AddDiscount :one
+INSERT INTO subscription_discounts (
+    subscription_id,
+    tenant_id,
+    kind,
+    amount,
+    started_at,
+    cycles,
+    until,
+    ends_at
+) VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+) RETURNING *;
+
+-- name: GetDiscountsBySubs :many
+SELECT * FROM subscription_discounts WHERE subscription_id = ANY(@subscription_ids::int[]) AND tenant_id = @tenant_id
+ORDER BY subscription_id, started_at;
+
+-- name: DeleteDiscount :one
+DELETE FROM subscription_discounts WHERE id = $1 AND subscription_id = $2 AND tenant_id = $3 RETURNING id;
+
+-- name: GetExpiringDiscounts :many
+SELECT subscription_discounts.* FROM subscription_discounts
+JOIN subscriptions ON subscriptions.id = subscription_discounts.subscription_id
+WHERE subscription_discounts.tenant_id = sqlc.arg('tenant_id')
+    AND subscription_discounts.ends_at > sqlc.arg('now')::timestamp
+    AND subscription_discounts.ends_at <= sqlc.arg('until')::timestamp
+    AND (sqlc.narg('user_id')::uuid IS NULL OR subscriptions.user_id = sqlc.narg('user_id'))
+ORDER BY subscription_discounts.ends_at, subscription_discounts.id;
@@ -0,0 +1,33 @@
+-- name: AddMember :one
+INSERT INTO subscription_members (
+    subscription_id,
+    tenant_id,
+    user_id,
+    status,
+    split,
+    amount,
+    joined_at
+) VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+) RETURNING *;
+
+-- name: AcceptMember :one
+UPDATE subscription_members SET status = 'active', joined_at = $4
+WHERE subscription_id = $1 AND user_id = $2 AND tenant_id = $3 AND status = 'invited' AND removed_at IS NULL
+RETURNING *;
+
+-- name: RemoveMember :one
+UPDATE subscription_members SET removed_at = $4
+WHERE subscription_id = $1 AND user_id = $2 AND tenant_id = $3 AND removed_at IS NULL
+RETURNING id;
+
+-- name: GetMembersBySubs :many
+-- Removed members are included, see service.Member.
+SELECT * FROM subscription_members WHERE subscription_id = ANY(@subscription_ids::int[]) AND tenant_id = @tenant_id
+ORDER BY subscription_id, id;
@@ -0,0 +1,11 @@
+-- name: GetOrganization :one
+SELECT * FROM organizations WHERE id = $1;
+
+-- name: SetTenant :exec
+SELECT set_config('app.tenant_id', @tenant_id::text, true);
+
+-- name: GetOrganizationIDs :many
+SELECT id FROM organizations ORDER BY id;
+
+-- name: BypassesRowSecurity :one
+SELECT (rolsuper OR rolbypassrls) AS bypass FROM pg_catalog.pg_roles WHERE rolname = current_user;
@@ -0,0 +1,28 @@
+-- name: AddPause :one
+INSERT INTO subscription_pauses (
+    subscription_id,
+    tenant_id,
+    started_at,
+    ended_at
+) VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+) RETURNING *;
+
+-- name: GetPauses :many
+SELECT * FROM subscription_pauses WHERE subscription_id = $1 AND tenant_id = $2
+ORDER BY started_at;
+
+-- name: GetPausesBySubs :many
+SELECT * FROM subscription_pauses WHERE subscription_id = ANY(@subscription_ids::int[]) AND tenant_id = @tenant_id
+ORDER BY subscription_id, started_at;
+
+-- name: DeletePause :one
+DELETE FROM subscription_pauses WHERE id = $1 AND subscription_id = $2 AND tenant_id = $3 RETURNING id;
+
+-- name: EndPause :one
+UPDATE subscription_pauses SET ended_at = $1
+WHERE id = $2 AND subscription_id = $3 AND tenant_id = $4
+RETURNING *;
@@ -0,0 +1,26 @@
+-- name: SetPrice :one
+INSERT INTO subscription_prices (
+    subscription_id,
+    tenant_id,
+    effective_from,
+    price,
+    currency
+) VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (subscription_id, effective_from) DO UPDATE SET price = EXCLUDED.price, currency = EXCLUDED.currency
+RETURNING *;
+
+-- name: GetPricesBySubs :many
+SELECT * FROM subscription_prices WHERE subscription_id = ANY(@subscription_ids::int[]) AND tenant_id = @tenant_id
+ORDER BY subscription_id, effective_from;
+
+-- name: GetServiceSubsForUpdate :many
+SELECT * FROM subscriptions
+WHERE tenant_id = @tenant_id AND service_name = @service_name
+    AND started_at < @effective_from AND (ended_at IS NULL OR ended_at >= @effective_from)
+ORDER BY id FOR UPDATE;
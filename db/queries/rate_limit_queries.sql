@@ -0,0 +1,23 @@
+-- name: TakeRateLimitToken :one
+INSERT INTO rate_limits AS rl (
+    key,
+    tokens,
+    allowed,
+    updated_at
+) VALUES (
+    @key,
+    (@burst::float8) - 1,
+    TRUE,
+    now()
+) ON CONFLICT (key) DO UPDATE SET
+    tokens = CASE
+        WHEN LEAST(@burst::float8, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at)::float8 * @rate::float8) >= 1
+        THEN LEAST(@burst::float8, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at)::float8 * @rate::float8) - 1
+        ELSE LEAST(@burst::float8, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at)::float8 * @rate::float8)
+    END,
+    allowed = LEAST(@burst::float8, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at)::float8 * @rate::float8) >= 1,
+    updated_at = now()
+RETURNING tokens, allowed;
+
+-- name: DeleteIdleRateLimits :exec
+DELETE FROM rate_limits WHERE updated_at < now() - INTERVAL '1 hour';
@@ -0,0 +1,8 @@
+-- name: GetActiveSubsStats :many
+SELECT
+    tenant_id,
+    count(*) AS active,
+    COALESCE(sum(price), 0)::bigint AS monthly_spend
+FROM subscriptions
+WHERE started_at <= now() AND (ended_at IS NULL OR ended_at > now())
+GROUP BY tenant_id;
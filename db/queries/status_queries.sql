@@ -0,0 +1,30 @@
+-- name: GetSubForUpdate :one
+SELECT * FROM subscriptions WHERE id = $1 AND tenant_id = $2 FOR UPDATE;
+
+-- name: SetSubStatus :one
+UPDATE subscriptions SET
+    status = @status,
+    status_changed_at = @changed_at,
+    cancel_at_period_end = @cancel_at_period_end,
+    ended_at = @ended_at,
+    updated_at = @changed_at
+WHERE id = @id AND tenant_id = @tenant_id RETURNING *;
+
+-- name: AddStatusChange :exec
+INSERT INTO subscription_status_changes (
+    subscription_id,
+    tenant_id,
+    from_status,
+    to_status,
+    changed_at
+) VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- name: GetStatusChanges :many
+SELECT * FROM subscription_status_changes WHERE subscription_id = $1 AND tenant_id = $2
+ORDER BY changed_at, id;
@@ -1,11 +1,5 @@
--- name: GetSubs :many
-SELECT * FROM subscriptions;
-
 -- name: GetSub :one
-SELECT * FROM subscriptions WHERE id = $1;
-
--- name: GetUserSubs :many
-SELECT * FROM subscriptions WHERE user_id = $1;
+SELECT * FROM subscriptions WHERE id = $1 AND tenant_id = $2;
 
 -- name: AddSub :one
 INSERT INTO subscriptions (
@@ -13,13 +7,23 @@ INSERT INTO subscriptions (
     price,
     user_id,
     started_at,
-    ended_at
+    ended_at,
+    tenant_id,
+    trial_ends_at,
+    trial_price,
+    status,
+    metadata
 ) VALUES (
     $1,
     $2,
     $3,
     $4,
-    $5
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10
 ) RETURNING id;
 
 -- name: UpdateSub :one
@@ -29,11 +33,81 @@ UPDATE subscriptions SET
     user_id = $3,
     started_at = $4,
     ended_at = $5,
-    updated_at = $6
-WHERE id = $7 RETURNING id;
+    updated_at = $6,
+    trial_ends_at = $9,
+    trial_price = $10,
+    metadata = $11
+WHERE id = $7 AND tenant_id = $8 RETURNING id;
 
 -- name: DeleteSub :one
-DELETE FROM subscriptions WHERE id = $1 RETURNING id;
+DELETE FROM subscriptions WHERE id = $1 AND tenant_id = $2 RETURNING id;
 
 -- name: DeleteUserSubs :many
-DELETE FROM subscriptions WHERE user_id = $1 RETURNING id;
+DELETE FROM subscriptions WHERE user_id = $1 AND tenant_id = $2 RETURNING id;
+
+-- name: FilterSubs :many
+SELECT subscriptions.* FROM subscriptions
+LEFT JOIN LATERAL (
+    -- The price in effect in the current month, prices are filtered by it.
+    SELECT p.price FROM subscription_prices p
+    WHERE p.subscription_id = subscriptions.id AND p.effective_from <= sqlc.arg('current_month')::timestamp
+    ORDER BY p.effective_from DESC LIMIT 1
+) current_price ON true
+WHERE subscriptions.tenant_id = sqlc.arg('tenant_id')
+    AND (sqlc.narg('user_id')::uuid IS NULL OR subscriptions.user_id = sqlc.narg('user_id')
+        OR (sqlc.arg('include_shared')::boolean AND EXISTS (
+            SELECT 1 FROM subscription_members m
+            WHERE m.subscription_id = subscriptions.id AND m.user_id = sqlc.narg('user_id') AND m.joined_at IS NOT NULL
+        )))
+    AND (sqlc.narg('service_name')::text IS NULL OR subscriptions.service_name = sqlc.narg('service_name'))
+    AND (sqlc.narg('category')::text IS NULL OR EXISTS (
+        SELECT 1 FROM subscription_categories sc JOIN categories c ON c.id = sc.category_id
+        WHERE sc.subscription_id = subscriptions.id AND c.name = sqlc.narg('category')
+    ))
+    -- A jsonpath of equality checks, which the jsonb_path_ops index serves.
+    AND (sqlc.narg('metadata')::text IS NULL OR subscriptions.metadata @@ sqlc.narg('metadata')::text::jsonpath)
+    AND (sqlc.narg('tag')::text IS NULL OR EXISTS (
+        SELECT 1 FROM subscription_tags st JOIN tags t ON t.id = st.tag_id
+        WHERE st.subscription_id = subscriptions.id AND t.name = sqlc.narg('tag')
+    ))
+    AND (sqlc.narg('min_price')::int IS NULL OR COALESCE(current_price.price, subscriptions.price) >= sqlc.narg('min_price'))
+    AND (sqlc.narg('max_price')::int IS NULL OR COALESCE(current_price.price, subscriptions.price) <= sqlc.narg('max_price'))
+    AND (sqlc.narg('active_to')::timestamp IS NULL OR subscriptions.started_at <= sqlc.narg('active_to'))
+    AND (sqlc.narg('active_from')::timestamp IS NULL OR subscriptions.ended_at IS NULL OR subscriptions.ended_at >= sqlc.narg('active_from'))
+    -- The status once ended_at or trial_ends_at has passed, like
+    -- service.Subscription.StatusAt.
+    AND (sqlc.narg('status')::text IS NULL OR sqlc.narg('status') = CASE
+        WHEN subscriptions.status IN ('trial', 'active', 'paused') AND subscriptions.ended_at < sqlc.arg('current_month')::timestamp
+            THEN CASE WHEN subscriptions.cancel_at_period_end THEN 'cancelled' ELSE 'expired' END
+        WHEN subscriptions.status = 'trial' AND subscriptions.trial_ends_at <= sqlc.arg('now')::timestamp THEN 'active'
+        ELSE subscriptions.status
+    END)
+    -- Keyset paging, rows inserted or deleted meanwhile do not shift pages.
+    AND (sqlc.narg('after_id')::int IS NULL OR subscriptions.id > sqlc.narg('after_id'))
+ORDER BY subscriptions.id LIMIT sqlc.narg('page_limit') OFFSET sqlc.arg('page_offset');
+
+-- name: GetSubsByIDs :many
+SELECT * FROM subscriptions WHERE id = ANY(@ids::int[]) AND tenant_id = @tenant_id
+ORDER BY id;
+
+-- name: GetSubsByUsers :many
+-- Members removed since are included, they paid a share before.
+SELECT * FROM subscriptions
+WHERE subscriptions.tenant_id = sqlc.arg('tenant_id') AND (subscriptions.user_id = ANY(sqlc.arg('user_ids')::uuid[]) OR EXISTS (
+    SELECT 1 FROM subscription_members m
+    WHERE m.subscription_id = subscriptions.id AND m.user_id = ANY(sqlc.arg('user_ids')::uuid[]) AND m.joined_at IS NOT NULL
+))
+ORDER BY subscriptions.id;
+
+-- name: GetSubsByServices :many
+SELECT * FROM subscriptions WHERE service_name = ANY(@service_names::text[]) AND tenant_id = @tenant_id
+ORDER BY id;
+
+-- name: GetEndingTrials :many
+SELECT * FROM subscriptions
+WHERE tenant_id = @tenant_id
+    AND status = 'trial'
+    AND trial_ends_at > @now::timestamp AND trial_ends_at <= @until::timestamp
+    AND (ended_at IS NULL OR ended_at >= @current_month::timestamp)
+    AND (sqlc.narg('user_id')::uuid IS NULL OR user_id = sqlc.narg('user_id'))
+ORDER BY trial_ends_at, id;
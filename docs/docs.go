@@ -15,9 +15,121 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/categories": {
+            "get": {
+                "description": "Get all categories by name",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetCategories",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "post": {
+                "description": "Add a category of subscriptions",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "PostCategory",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Category",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subs.categoryJSON"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/categories/{category_id}": {
+            "delete": {
+                "description": "Delete a category and take it off every subscription",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "DeleteCategory",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of category",
+                        "name": "category_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/services/{name}/price-change": {
+            "post": {
+                "description": "Change the price of every subscription of a service active in the effective month, in one transaction. With dry_run only the affected subscriptions and the change of monthly spend are returned.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "PostServicePriceChange",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Service name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New price and optional filter",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subs.priceChangeJSON"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
         "/api/sub": {
             "post": {
-                "description": "Create a subscription",
+                "description": "Create a subscription, ` + "`" + `warnings` + "`" + ` lists the budgets of the user it takes over, 409 if one of them is enforced",
                 "consumes": [
                     "application/json"
                 ],
@@ -26,6 +138,13 @@ const docTemplate = `{
                 ],
                 "summary": "PostSub",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
                     {
                         "description": "Structure of new subscription",
                         "name": "request",
@@ -47,6 +166,13 @@ const docTemplate = `{
                 ],
                 "summary": "GetSub",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
                     {
                         "type": "integer",
                         "description": "ID (int) of specific subscription",
@@ -58,7 +184,7 @@ const docTemplate = `{
                 "responses": {}
             },
             "put": {
-                "description": "Update a subscription",
+                "description": "Update a subscription, budgets are checked like on create",
                 "consumes": [
                     "application/json"
                 ],
@@ -67,6 +193,13 @@ const docTemplate = `{
                 ],
                 "summary": "PutSub",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
                     {
                         "type": "integer",
                         "description": "ID of subscription",
@@ -93,74 +226,1624 @@ const docTemplate = `{
                 ],
                 "summary": "DeleteSub",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "patch": {
+                "description": "Update some fields of a subscription and merge its metadata, budgets are checked like on create",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "PatchSub",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
                     {
                         "type": "integer",
                         "description": "ID of subscription",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subs.patchJSON"
+                        }
                     }
                 ],
                 "responses": {}
             }
         },
-        "/api/subs": {
-            "get": {
-                "description": "Get all subscriptions",
+        "/api/sub/{id}/cancel": {
+            "post": {
+                "description": "Cancel a subscription, the current month stays paid",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "GetSubs",
+                "summary": "CancelSub",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID, if need to get all subscriptions of a specific user",
-                        "name": "user_id",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Keep the status until the end of the current month",
+                        "name": "at_period_end",
                         "in": "query"
                     }
                 ],
                 "responses": {}
-            },
+            }
+        },
+        "/api/sub/{id}/categories": {
+            "post": {
+                "description": "Put a subscription in an existing category",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "PostSubCategory",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Category",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subs.labelJSON"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/sub/{id}/categories/{name}": {
             "delete": {
-                "description": "Delete all subscriptions of specific user",
+                "description": "Take a subscription out of a category",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "DeleteUserSubs",
+                "summary": "DeleteSubCategory",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "user_id",
-                        "in": "query",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of category",
+                        "name": "name",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {}
             }
-        }
-    },
-    "definitions": {
-        "subs.subJSON": {
-            "type": "object",
-            "properties": {
-                "end_date": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "price": {
-                    "type": "integer"
-                },
-                "service_name": {
-                    "type": "string"
-                },
-                "start_date": {
-                    "type": "string"
-                },
-                "user_id": {
-                    "type": "string"
+        },
+        "/api/sub/{id}/discounts": {
+            "get": {
+                "description": "Get the discounts of a subscription, the earliest first",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetDiscounts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "post": {
+                "description": "Add a percent or fixed discount for a number of months (cycles) or until a date",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "PostDiscount",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Discount",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subs.discountJSON"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/sub/{id}/discounts/{discount_id}": {
+            "delete": {
+                "description": "Remove a discount",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "DeleteDiscount",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of discount",
+                        "name": "discount_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/sub/{id}/history": {
+            "get": {
+                "description": "Get the status changes of a subscription, oldest first",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetSubHistory",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/sub/{id}/invites": {
+            "post": {
+                "description": "Invite a user to share a subscription, they pay nothing until the invite is accepted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "PostInvite",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Member",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subs.memberJSON"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/sub/{id}/invites/{user_id}/accept": {
+            "post": {
+                "description": "Accept an invite, the user pays their part of the charge from the current month",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "AcceptInvite",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of invited user",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/sub/{id}/members": {
+            "get": {
+                "description": "Get the members and invites of a subscription with their share of the current month, removed members have removed_at",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetMembers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "post": {
+                "description": "Share a subscription with a user, who pays their part of the charge from the current month",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "PostMember",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Member",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subs.memberJSON"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/sub/{id}/members/{user_id}": {
+            "delete": {
+                "description": "Remove a member or an invite, the owner pays their share from the current month, past months keep the split",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "DeleteMember",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of member",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/sub/{id}/pause": {
+            "post": {
+                "description": "Pause an active or trial subscription from the next month until it is resumed",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "PauseSub",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/sub/{id}/pauses": {
+            "get": {
+                "description": "Get the pauses of a subscription, the earliest first. The pause of a paused subscription has no end_date",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetPauses",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "post": {
+                "description": "Pause a subscription for a range of months, both included",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "PostPause",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Months of the pause",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subs.pauseJSON"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/sub/{id}/pauses/{pause_id}": {
+            "delete": {
+                "description": "Remove a pause, its months are charged again",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "DeletePause",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of pause",
+                        "name": "pause_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/sub/{id}/prices": {
+            "get": {
+                "description": "Get the price timeline of a subscription, starting with the price from its start date",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetPrices",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "post": {
+                "description": "Schedule a price change from a month on, earlier months keep their price",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "PostPrice",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New price, currency is RUB if not set",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subs.priceJSON"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/sub/{id}/reactivate": {
+            "post": {
+                "description": "Reactivate a cancelled or expired subscription, the months since it ended are not charged. Also undoes a pending cancel at period end",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "ReactivateSub",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/sub/{id}/resume": {
+            "post": {
+                "description": "Resume a paused subscription, the current month is charged again",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "ResumeSub",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/sub/{id}/tags": {
+            "post": {
+                "description": "Tag a subscription, the tag is created on first use",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "PostSubTag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tag",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subs.labelJSON"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/sub/{id}/tags/{name}": {
+            "delete": {
+                "description": "Remove a tag from a subscription",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "DeleteSubTag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of subscription",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/subs": {
+            "get": {
+                "description": "Get all subscriptions",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetSubs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID, if need to get all subscriptions of a specific user",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "With user_id, also get the subscriptions shared with the user, now or before",
+                        "name": "include_shared",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "trial",
+                            "active",
+                            "paused",
+                            "cancelled",
+                            "expired"
+                        ],
+                        "type": "string",
+                        "description": "Status of subscriptions",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Value of the metadata key, matches the string or the number or boolean it reads as, any number of keys",
+                        "name": "metadata.key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max number of subscriptions, all if not set",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of subscriptions to skip, ordered by ID",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {}
+            },
+            "delete": {
+                "description": "Delete all subscriptions of specific user",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "DeleteUserSubs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/subs/ending-trials": {
+            "get": {
+                "description": "Get trials converting to paid within ` + "`" + `days` + "`" + `, the soonest first, ` + "`" + `first_charge_at` + "`" + ` is the day of the first paid charge",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetEndingTrials",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of days ahead, 7 if not set",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID, if need to get trials of a specific user",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/subs/expiring-discounts": {
+            "get": {
+                "description": "Get discounts ending within ` + "`" + `days` + "`" + `, the soonest first, with the charge before and after",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetExpiringDiscounts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of days ahead, 7 if not set",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID, if need to get discounts of a specific user",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/subs/spend-by-category": {
+            "get": {
+                "description": "Get the cost of the matching subscriptions over a period by category, the largest first. A subscription in several categories counts in each, with user_id only the share of the user counts",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetSpendByCategory",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "First month, MM-YYYY",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last month, MM-YYYY",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID, if need to get the spend of a specific user",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "With user_id, also count the subscriptions shared with the user",
+                        "name": "include_shared",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "trial",
+                            "active",
+                            "paused",
+                            "cancelled",
+                            "expired"
+                        ],
+                        "type": "string",
+                        "description": "Status of subscriptions",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag",
+                        "name": "tag",
+                        "in": "query"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/tags": {
+            "get": {
+                "description": "Get every tag in use by name",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetTags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/users/{id}/budget-status": {
+            "get": {
+                "description": "Compare every budget of a user to the projected spend of their active subscriptions",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetBudgetStatus",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/users/{id}/budgets": {
+            "get": {
+                "description": "Get the budgets of a user",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetBudgets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "post": {
+                "description": "Add a monthly or yearly budget of a user over all subscriptions or the ones of a service or category",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "PostBudget",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Budget",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subs.budgetJSON"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/users/{id}/budgets/{budget_id}": {
+            "delete": {
+                "description": "Remove a budget",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "DeleteBudget",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of budget",
+                        "name": "budget_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/graphql": {
+            "post": {
+                "description": "Query and change subscriptions with GraphQL, the schema is in internal/gql/schema.graphql",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GraphQL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "X-Tenant-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "GraphQL query with operation name and variables",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/gql.request"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Process is alive",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Healthz",
+                "responses": {}
+            }
+        },
+        "/livez": {
+            "get": {
+                "description": "Liveness probe with process details",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Livez",
+                "responses": {}
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Readiness probe: database is reachable and migrated, instance is not draining",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Readyz",
+                "responses": {}
+            }
+        }
+    },
+    "definitions": {
+        "gql.request": {
+            "type": "object",
+            "properties": {
+                "operationName": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": {}
+                }
+            }
+        },
+        "service.BudgetPeriod": {
+            "type": "string",
+            "enum": [
+                "month",
+                "year"
+            ],
+            "x-enum-varnames": [
+                "BudgetMonth",
+                "BudgetYear"
+            ]
+        },
+        "service.BudgetScope": {
+            "type": "string",
+            "enum": [
+                "overall",
+                "service",
+                "category"
+            ],
+            "x-enum-varnames": [
+                "BudgetOverall",
+                "BudgetService",
+                "BudgetCategory"
+            ]
+        },
+        "service.DiscountKind": {
+            "type": "string",
+            "enum": [
+                "percent",
+                "fixed"
+            ],
+            "x-enum-varnames": [
+                "DiscountPercent",
+                "DiscountFixed"
+            ]
+        },
+        "service.MemberStatus": {
+            "type": "string",
+            "enum": [
+                "invited",
+                "active"
+            ],
+            "x-enum-varnames": [
+                "MemberInvited",
+                "MemberActive"
+            ]
+        },
+        "service.Split": {
+            "type": "string",
+            "enum": [
+                "equal",
+                "percent",
+                "fixed"
+            ],
+            "x-enum-varnames": [
+                "SplitEqual",
+                "SplitPercent",
+                "SplitFixed"
+            ]
+        },
+        "service.Status": {
+            "type": "string",
+            "enum": [
+                "trial",
+                "active",
+                "paused",
+                "cancelled",
+                "expired"
+            ],
+            "x-enum-varnames": [
+                "StatusTrial",
+                "StatusActive",
+                "StatusPaused",
+                "StatusCancelled",
+                "StatusExpired"
+            ]
+        },
+        "subs.budgetJSON": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "enforce": {
+                    "description": "Enforce rejects changes taking the user over the budget with 409.",
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "period": {
+                    "enum": [
+                        "month",
+                        "year"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.BudgetPeriod"
+                        }
+                    ]
+                },
+                "scope": {
+                    "enum": [
+                        "overall",
+                        "service",
+                        "category"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.BudgetScope"
+                        }
+                    ]
+                },
+                "target": {
+                    "description": "Target is the service or category name of a service or category\nbudget.",
+                    "type": "string"
+                }
+            }
+        },
+        "subs.budgetStatusJSON": {
+            "type": "object",
+            "properties": {
+                "budget": {
+                    "$ref": "#/definitions/subs.budgetJSON"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "over": {
+                    "type": "boolean"
+                },
+                "remaining": {
+                    "type": "integer"
+                },
+                "spent": {
+                    "description": "Spent is projected for the whole period from the active\nsubscriptions, Remaining is negative when over.",
+                    "type": "integer"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "subs.categoryJSON": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "subs.discountJSON": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "cycles": {
+                    "description": "Either Cycles, a number of months, or Until is set.",
+                    "type": "integer"
+                },
+                "ends_at": {
+                    "description": "EndsAt is the first month charged in full again, read only.",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "enum": [
+                        "percent",
+                        "fixed"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.DiscountKind"
+                        }
+                    ]
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "until": {
+                    "type": "string"
+                }
+            }
+        },
+        "subs.labelJSON": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "subs.memberJSON": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount is a percent or a fixed sum, empty for an equal split.",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "joined_at": {
+                    "description": "The member pays from the month of joined_at up to the month before\nremoved_at.",
+                    "type": "string"
+                },
+                "removed_at": {
+                    "type": "string"
+                },
+                "share": {
+                    "type": "integer"
+                },
+                "split": {
+                    "enum": [
+                        "equal",
+                        "percent",
+                        "fixed"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.Split"
+                        }
+                    ]
+                },
+                "status": {
+                    "description": "Read only.",
+                    "enum": [
+                        "invited",
+                        "active"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.MemberStatus"
+                        }
+                    ]
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "subs.patchJSON": {
+            "type": "object",
+            "properties": {
+                "end_date": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "description": "Metadata is merged, a null value removes its key.",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "subs.pauseJSON": {
+            "type": "object",
+            "properties": {
+                "end_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "start_date": {
+                    "type": "string"
+                }
+            }
+        },
+        "subs.priceChangeJSON": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "effective_from": {
+                    "type": "string"
+                },
+                "old_price": {
+                    "description": "OldPrice only changes subscriptions paying it before the change.",
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "integer"
+                }
+            }
+        },
+        "subs.priceJSON": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "effective_from": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "integer"
+                }
+            }
+        },
+        "subs.subJSON": {
+            "type": "object",
+            "properties": {
+                "cancel_at_period_end": {
+                    "type": "boolean"
+                },
+                "categories": {
+                    "description": "Categories and Tags are set through their endpoints.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "charge": {
+                    "description": "Charge is the amount charged for the current month.",
+                    "type": "integer"
+                },
+                "discounts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subs.discountJSON"
+                    }
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "first_charge_at": {
+                    "description": "FirstChargeAt is the day of the first charge at the full price, the\nend of the trial or the start date.",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subs.memberJSON"
+                    }
+                },
+                "metadata": {
+                    "description": "Metadata is a flat object of up to 4 KB, kept on update when not set.",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "pauses": {
+                    "description": "Read only, set through the pause, price and status endpoints.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subs.pauseJSON"
+                    }
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "prices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subs.priceJSON"
+                    }
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/service.Status"
+                },
+                "status_changed_at": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "trial_ends_at": {
+                    "description": "TrialEndsAt is the day of the first paid charge, months before it\ncost TrialPrice.",
+                    "type": "string"
+                },
+                "trial_price": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "warnings": {
+                    "description": "Warnings are the budgets of the user over after a create or update.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subs.budgetStatusJSON"
+                    }
                 }
             }
         }
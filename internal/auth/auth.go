@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"usersubs/internal/tenant"
+	"usersubs/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// Key is an API key bound to the organization it gives access to.
+type Key struct {
+	TenantID uuid.UUID
+	Secret   string
+}
+
+// ParseKeys reads keys written as `<organization id>:<key>`.
+func ParseKeys(values []string) ([]Key, error) {
+	keys := make([]Key, 0, len(values))
+	for i, v := range values {
+		id, secret, ok := strings.Cut(v, ":")
+		if !ok || secret == "" {
+			return nil, fmt.Errorf("AUTH - api key %d is not `<organization id>:<key>`", i+1)
+		}
+		tenantID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("AUTH - could not parse organization of api key %d - %v", i+1, err)
+		}
+		keys = append(keys, Key{TenantID: tenantID, Secret: secret})
+	}
+	return keys, nil
+}
+
+// APIKeys guards routes with static keys passed in `X-API-Key`. A valid key
+// binds the request to its organization, see tenant.Bind. With no keys
+// configured every request is let through.
+type APIKeys struct {
+	Keys []Key
+}
+
+func (a APIKeys) Middleware(next http.Handler) http.Handler {
+	if len(a.Keys) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := r.Header.Get("X-API-Key")
+		if secret == "" {
+			utils.SendError(w, "Error: header `X-API-Key` is not provided", http.StatusUnauthorized, errors.New("no api key"))
+			return
+		}
+
+		key, ok := a.Lookup(secret)
+		if !ok {
+			utils.SendError(w, "Error: invalid api key", http.StatusUnauthorized, errors.New("invalid api key"))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(tenant.Bind(r.Context(), key.TenantID)))
+	})
+}
+
+// Lookup compares secret with every configured key in constant time.
+func (a APIKeys) Lookup(secret string) (Key, bool) {
+	var found Key
+	ok := false
+	for _, k := range a.Keys {
+		if subtle.ConstantTimeCompare([]byte(k.Secret), []byte(secret)) == 1 {
+			found, ok = k, true
+		}
+	}
+	return found, ok
+}
+
+// Identify names the caller of r by its API key when the key is valid, for
+// rate limiting. The name does not reveal the key.
+func (a APIKeys) Identify(r *http.Request) (string, bool) {
+	return a.Name(r.Header.Get("X-API-Key"))
+}
+
+// Name is Identify for a secret passed other than in `X-API-Key`.
+func (a APIKeys) Name(secret string) (string, bool) {
+	key, ok := a.Lookup(secret)
+	if !ok {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(key.Secret))
+	return "key:" + hex.EncodeToString(sum[:8]), true
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"usersubs/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+var (
+	orgA = uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+	orgB = uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    []Key
+		wantErr bool
+	}{
+		{name: "empty", values: nil, want: []Key{}},
+		{name: "bound", values: []string{orgA.String() + ":secret-a", orgB.String() + ":b:c"}, want: []Key{{orgA, "secret-a"}, {orgB, "b:c"}}},
+		{name: "no organization", values: []string{"secret"}, wantErr: true},
+		{name: "bad organization", values: []string{"org:secret"}, wantErr: true},
+		{name: "no secret", values: []string{orgA.String() + ":"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKeys(tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKeys() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseKeys() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ParseKeys()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareBindsTenant(t *testing.T) {
+	keys := APIKeys{Keys: []Key{{orgA, "secret-a"}, {orgB, "secret-b"}}}
+
+	tests := []struct {
+		name      string
+		key       string
+		wantCode  int
+		wantBound uuid.UUID
+	}{
+		{name: "no key", key: "", wantCode: http.StatusUnauthorized},
+		{name: "invalid key", key: "secret-c", wantCode: http.StatusUnauthorized},
+		{name: "key of a", key: "secret-a", wantCode: http.StatusOK, wantBound: orgA},
+		{name: "key of b", key: "secret-b", wantCode: http.StatusOK, wantBound: orgB},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bound uuid.UUID
+			h := keys.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				bound, _ = tenant.Bound(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/subs", nil)
+			if tt.key != "" {
+				r.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+			if bound != tt.wantBound {
+				t.Errorf("bound tenant = %v, want %v", bound, tt.wantBound)
+			}
+		})
+	}
+}
+
+// A key of one organization must not reach another one by its header, the
+// request is rejected before the organization is looked up.
+func TestForeignTenantHeaderIsForbidden(t *testing.T) {
+	keys := APIKeys{Keys: []Key{{orgA, "secret-a"}}}
+	called := false
+	h := keys.Middleware(tenant.Resolver{}.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})))
+
+	r := httptest.NewRequest(http.MethodDelete, "/api/subs", nil)
+	r.Header.Set("X-API-Key", "secret-a")
+	r.Header.Set(tenant.Header, orgB.String())
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("code = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if called {
+		t.Error("handler was called for a foreign tenant")
+	}
+}
+
+func TestMiddlewareOpenWithoutKeys(t *testing.T) {
+	called := false
+	h := APIKeys{}.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if _, ok := tenant.Bound(r.Context()); ok {
+			t.Error("request without keys is bound to a tenant")
+		}
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/subs", nil))
+
+	if w.Code != http.StatusOK || !called {
+		t.Errorf("code = %d, called = %v, want the handler called", w.Code, called)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	keys := APIKeys{Keys: []Key{{orgA, "secret-a"}}}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/subs", nil)
+	if _, ok := keys.Identify(r); ok {
+		t.Error("request without a key is identified")
+	}
+
+	r.Header.Set("X-API-Key", "secret-b")
+	if _, ok := keys.Identify(r); ok {
+		t.Error("invalid key is identified")
+	}
+
+	r.Header.Set("X-API-Key", "secret-a")
+	id, ok := keys.Identify(r)
+	if !ok || id == "" {
+		t.Fatal("valid key is not identified")
+	}
+	if strings.Contains(id, "secret-a") {
+		t.Errorf("identity %q reveals the key", id)
+	}
+}
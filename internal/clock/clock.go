@@ -0,0 +1,105 @@
+// Package clock gives business logic an injectable "now", so month
+// boundaries and leap years can be exercised without waiting for them.
+package clock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+	"usersubs/internal/utils"
+)
+
+// Header overrides the clock of a single request when the debug option is
+// on.
+const Header = "X-Fake-Now"
+
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the real clock.
+type System struct{}
+
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+// Fake stays at the time it is set to.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+type ctxKey struct{}
+
+// WithNow fixes "now" for everything done with ctx.
+func WithNow(ctx context.Context, now time.Time) context.Context {
+	return context.WithValue(ctx, ctxKey{}, now)
+}
+
+// Now returns the time fixed on ctx by WithNow, else the time of c, else
+// the system time when c is nil.
+func Now(ctx context.Context, c Clock) time.Time {
+	if now, ok := ctx.Value(ctxKey{}).(time.Time); ok {
+		return now
+	}
+	if c == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
+
+// Parse reads an RFC 3339 time or a YYYY-MM-DD date.
+func Parse(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse %q, expected RFC 3339 or YYYY-MM-DD", s)
+	}
+	return t, nil
+}
+
+// Middleware lets a request set "now" with `X-Fake-Now`. It is meant for QA
+// environments and must not be enabled in production.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(Header)
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now, err := Parse(header)
+		if err != nil {
+			utils.SendError(w, "Error: could not parse header `X-Fake-Now`", http.StatusBadRequest, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithNow(r.Context(), now)))
+	})
+}
@@ -0,0 +1,102 @@
+package clock
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "2024-02-29", want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{in: "2025-01-31T23:59:00Z", want: time.Date(2025, 1, 31, 23, 59, 0, 0, time.UTC)},
+		{in: "2025-01-31T23:59:00+03:00", want: time.Date(2025, 1, 31, 20, 59, 0, 0, time.UTC)},
+		{in: "2025-02-30", wantErr: true},
+		{in: "01-2025", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 2, 28, 12, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+	if !f.Now().Equal(start) {
+		t.Errorf("Now() = %v, want %v", f.Now(), start)
+	}
+	f.Advance(24 * time.Hour)
+	if want := time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC); !f.Now().Equal(want) {
+		t.Errorf("after Advance Now() = %v, want %v", f.Now(), want)
+	}
+	f.Set(start)
+	if !f.Now().Equal(start) {
+		t.Errorf("after Set Now() = %v, want %v", f.Now(), start)
+	}
+}
+
+// A time fixed on the context wins over the clock, which wins over the
+// system time.
+func TestNow(t *testing.T) {
+	fixed := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := NewFake(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	if got := Now(WithNow(context.Background(), fixed), fake); !got.Equal(fixed) {
+		t.Errorf("Now(WithNow) = %v, want %v", got, fixed)
+	}
+	if got := Now(context.Background(), fake); !got.Equal(fake.Now()) {
+		t.Errorf("Now(fake) = %v, want %v", got, fake.Now())
+	}
+	if got := Now(context.Background(), nil); time.Since(got) > time.Minute {
+		t.Errorf("Now(nil) = %v, want the system time", got)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantCode int
+		wantNow  time.Time
+	}{
+		{name: "no header", wantCode: http.StatusOK},
+		{name: "date", header: "2024-02-29", wantCode: http.StatusOK, wantNow: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "invalid", header: "tomorrow", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got time.Time
+			h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = r.Context().Value(ctxKey{}).(time.Time)
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/api/subs", nil)
+			if tt.header != "" {
+				r.Header.Set(Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+			if !got.Equal(tt.wantNow) {
+				t.Errorf("now = %v, want %v", got, tt.wantNow)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is loaded from defaults, then a YAML file, then environment
+// variables, then flags, each overriding the previous one.
+//
+// Every field is set with the environment variable from its `env` tag and
+// with a flag named after its YAML path, e.g. `--server.addr`. Fields tagged
+// `secret` can also be read from the file named by `<ENV>_FILE` and are
+// redacted when printed.
+type Config struct {
+	Server    Server    `yaml:"server"`
+	DB        DB        `yaml:"db"`
+	Log       Log       `yaml:"log"`
+	Tracing   Tracing   `yaml:"tracing"`
+	CORS      CORS      `yaml:"cors"`
+	Auth      Auth      `yaml:"auth"`
+	RateLimit RateLimit `yaml:"rate_limit"`
+	Features  Features  `yaml:"features"`
+	Debug     Debug     `yaml:"debug"`
+}
+
+type Server struct {
+	Addr         string        `yaml:"addr" env:"SERVER_ADDR"`
+	GRPCAddr     string        `yaml:"grpc_addr" env:"GRPC_ADDR"`
+	TLSCertFile  string        `yaml:"tls_cert_file" env:"TLS_CERT_FILE"`
+	TLSKeyFile   string        `yaml:"tls_key_file" env:"TLS_KEY_FILE"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" env:"READ_TIMEOUT"`
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"WRITE_TIMEOUT"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" env:"IDLE_TIMEOUT"`
+	DrainTimeout time.Duration `yaml:"drain_timeout" env:"DRAIN_TIMEOUT"`
+	DrainDelay   time.Duration `yaml:"drain_delay" env:"DRAIN_DELAY"`
+	TrustProxy   bool          `yaml:"trust_proxy" env:"TRUST_PROXY"`
+}
+
+type DB struct {
+	// Connection should use a role without superuser or BYPASSRLS, member
+	// of usersubs_app, so the row level security policies apply to it.
+	Connection string `yaml:"connection" env:"DB_CONNECTION" secret:"true"`
+	// MigrateConnection applies migrations as the owner of the tables,
+	// Connection is used when empty.
+	MigrateConnection string        `yaml:"migrate_connection" env:"DB_MIGRATE_CONNECTION" secret:"true"`
+	MaxOpenConns      int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns      int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime   time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	QueryTimeout      time.Duration `yaml:"query_timeout" env:"DB_QUERY_TIMEOUT"`
+	// AutoMigrate applies pending migrations on startup.
+	AutoMigrate bool `yaml:"auto_migrate" env:"DB_AUTO_MIGRATE"`
+}
+
+type Log struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL"`
+	Bodies bool   `yaml:"bodies" env:"LOG_BODIES"`
+}
+
+type Tracing struct {
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER"`
+	File     string `yaml:"file" env:"TRACING_FILE"`
+}
+
+type CORS struct {
+	AllowedOrigins []string `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods []string `yaml:"allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders []string `yaml:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+}
+
+type Auth struct {
+	// APIKeys are accepted in `X-API-Key`, each written as
+	// `<organization id>:<key>` and valid for that organization only.
+	APIKeys []string `yaml:"api_keys" env:"AUTH_API_KEYS" secret:"true"`
+	// AllowOpen lets the server start without APIKeys, any caller then picks
+	// the organization with `X-Tenant-ID`. For local development only.
+	AllowOpen bool `yaml:"allow_open" env:"AUTH_ALLOW_OPEN"`
+}
+
+type RateLimit struct {
+	Store string  `yaml:"store" env:"RATE_LIMIT_STORE"`
+	Rate  float64 `yaml:"rate" env:"RATE_LIMIT_RATE"`
+	Burst int     `yaml:"burst" env:"RATE_LIMIT_BURST"`
+}
+
+type Features struct {
+	Swagger   bool `yaml:"swagger" env:"FEATURE_SWAGGER"`
+	Metrics   bool `yaml:"metrics" env:"FEATURE_METRICS"`
+	RateLimit bool `yaml:"rate_limit" env:"FEATURE_RATE_LIMIT"`
+	GRPC      bool `yaml:"grpc" env:"FEATURE_GRPC"`
+	GraphQL   bool `yaml:"graphql" env:"FEATURE_GRAPHQL"`
+}
+
+// Debug options are for QA environments only.
+type Debug struct {
+	// FakeNow fixes the clock of the server, RFC 3339 or YYYY-MM-DD.
+	FakeNow string `yaml:"fake_now" env:"DEBUG_FAKE_NOW"`
+	// FakeNowHeader lets every request set its own clock with `X-Fake-Now`.
+	FakeNowHeader bool `yaml:"fake_now_header" env:"DEBUG_FAKE_NOW_HEADER"`
+}
+
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:         ":5500",
+			GRPCAddr:     ":5501",
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 15 * time.Second,
+			IdleTimeout:  60 * time.Second,
+			DrainTimeout: 20 * time.Second,
+		},
+		DB: DB{
+			MaxOpenConns:    20,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			QueryTimeout:    5 * time.Second,
+		},
+		Log: Log{Level: "info"},
+		CORS: CORS{
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Content-Type", "X-API-Key", "X-Tenant-ID", "X-Request-ID"},
+		},
+		RateLimit: RateLimit{Store: "memory", Rate: 10, Burst: 20},
+		Features:  Features{Swagger: true, Metrics: true, RateLimit: true, GRPC: true, GraphQL: true},
+	}
+}
+
+// Load builds the config from args (without the program name). It returns
+// printOnly when `--print-config` is given.
+func Load(args []string) (cfg Config, printOnly bool, err error) {
+	cfg = Default()
+
+	flags := flag.NewFlagSet("usersubs", flag.ContinueOnError)
+	file := flags.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML config file")
+	printConfig := flags.Bool("print-config", false, "print the effective config with secrets redacted and exit")
+
+	// Flags are applied after the file and the environment, so the values
+	// are only collected here.
+	set := map[string]string{}
+	for _, f := range walk(&cfg) {
+		flags.Func(f.path, "sets "+f.path+", env "+f.env, func(value string) error {
+			set[f.path] = value
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return cfg, false, err
+	}
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("Error: something wrong with getting .env", "error", err)
+	}
+
+	if *file != "" {
+		if err := loadFile(&cfg, *file); err != nil {
+			return cfg, false, err
+		}
+	}
+
+	fields := walk(&cfg)
+	if err := loadEnv(fields); err != nil {
+		return cfg, false, err
+	}
+
+	for _, f := range fields {
+		if value, ok := set[f.path]; ok {
+			if err := f.set(value); err != nil {
+				return cfg, false, fmt.Errorf("CONFIG - flag --%s - %w", f.path, err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, *printConfig, err
+	}
+
+	return cfg, *printConfig, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("CONFIG - could not open %s - %w", path, err)
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("CONFIG - could not parse %s - %w", path, err)
+	}
+	return nil
+}
+
+func loadEnv(fields []field) error {
+	// SERVER_PORT is kept for setups made before SERVER_ADDR.
+	if port, ok := os.LookupEnv("SERVER_PORT"); ok && port != "" {
+		if _, ok := os.LookupEnv("SERVER_ADDR"); !ok {
+			os.Setenv("SERVER_ADDR", ":"+port)
+		}
+	}
+
+	for _, f := range fields {
+		value, ok := os.LookupEnv(f.env)
+		if f.secret {
+			if path, fileOK := os.LookupEnv(f.env + "_FILE"); fileOK && path != "" {
+				b, err := os.ReadFile(path)
+				if err != nil {
+					return fmt.Errorf("CONFIG - could not read %s_FILE - %w", f.env, err)
+				}
+				value, ok = strings.TrimRight(string(b), "\r\n"), true
+			}
+		}
+		// Empty values, like the ones left in .env.example, keep the default.
+		if !ok || value == "" {
+			continue
+		}
+
+		if err := f.set(value); err != nil {
+			return fmt.Errorf("CONFIG - env %s - %w", f.env, err)
+		}
+	}
+	return nil
+}
+
+// Print writes the config as YAML with secrets redacted.
+func (c Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(redacted(&c)); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
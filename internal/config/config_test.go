@@ -0,0 +1,257 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// clearEnv unsets every variable Load reads for the test.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	cfg := Default()
+	names := []string{"CONFIG_FILE", "SERVER_PORT"}
+	for _, f := range walk(&cfg) {
+		names = append(names, f.env)
+		if f.secret {
+			names = append(names, f.env+"_FILE")
+		}
+	}
+	for _, name := range names {
+		// Setenv restores the variable after the test.
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Each source overrides the previous one: defaults, file, env, flags.
+func TestLoadPrecedence(t *testing.T) {
+	file := "server:\n  addr: \":6000\"\n  read_timeout: 3s\ndb:\n  connection: file-dsn\n"
+
+	tests := []struct {
+		name        string
+		file        string
+		env         map[string]string
+		args        []string
+		wantAddr    string
+		wantTimeout time.Duration
+		wantDSN     string
+	}{
+		{name: "defaults", env: map[string]string{"DB_CONNECTION": "env-dsn"}, wantAddr: ":5500", wantTimeout: 10 * time.Second, wantDSN: "env-dsn"},
+		{name: "file", file: file, wantAddr: ":6000", wantTimeout: 3 * time.Second, wantDSN: "file-dsn"},
+		{name: "env over file", file: file, env: map[string]string{"SERVER_ADDR": ":7000", "DB_CONNECTION": "env-dsn"}, wantAddr: ":7000", wantTimeout: 3 * time.Second, wantDSN: "env-dsn"},
+		{name: "flag over env", file: file, env: map[string]string{"SERVER_ADDR": ":7000"}, args: []string{"--server.addr", ":8000", "--server.read_timeout=1m"}, wantAddr: ":8000", wantTimeout: time.Minute, wantDSN: "file-dsn"},
+		{name: "legacy port", env: map[string]string{"SERVER_PORT": "9000", "DB_CONNECTION": "env-dsn"}, wantAddr: ":9000", wantTimeout: 10 * time.Second, wantDSN: "env-dsn"},
+		{name: "addr over legacy port", env: map[string]string{"SERVER_PORT": "9000", "SERVER_ADDR": ":7000", "DB_CONNECTION": "env-dsn"}, wantAddr: ":7000", wantTimeout: 10 * time.Second, wantDSN: "env-dsn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"--config", writeFile(t, "config.yaml", tt.file)}, args...)
+			}
+
+			cfg, printOnly, err := Load(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if printOnly {
+				t.Error("printOnly without --print-config")
+			}
+			if cfg.Server.Addr != tt.wantAddr || cfg.Server.ReadTimeout != tt.wantTimeout || cfg.DB.Connection != tt.wantDSN {
+				t.Errorf("addr %q, read timeout %v, dsn %q, want %q, %v, %q",
+					cfg.Server.Addr, cfg.Server.ReadTimeout, cfg.DB.Connection, tt.wantAddr, tt.wantTimeout, tt.wantDSN)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		args    []string
+		wantErr string
+	}{
+		{name: "unknown file key", file: "server:\n  port: 1\n", env: map[string]string{"DB_CONNECTION": "dsn"}, wantErr: "could not parse"},
+		{name: "bad env value", env: map[string]string{"DB_CONNECTION": "dsn", "DB_MAX_OPEN_CONNS": "many"}, wantErr: "env DB_MAX_OPEN_CONNS"},
+		{name: "bad flag value", env: map[string]string{"DB_CONNECTION": "dsn"}, args: []string{"--server.read_timeout", "soon"}, wantErr: "flag --server.read_timeout"},
+		{name: "invalid", env: map[string]string{"LOG_LEVEL": "loud"}, wantErr: "db.connection is empty"},
+		{name: "bad fake now", env: map[string]string{"DB_CONNECTION": "dsn", "DEBUG_FAKE_NOW": "tomorrow"}, wantErr: "debug.fake_now"},
+		{name: "unbound api key", env: map[string]string{"DB_CONNECTION": "dsn", "AUTH_API_KEYS": "k1"}, wantErr: "auth.api_keys"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"--config", writeFile(t, "config.yaml", tt.file)}, args...)
+			}
+
+			if _, _, err := Load(args); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantDSN string
+		wantErr bool
+	}{
+		{name: "file without newline", env: map[string]string{"DB_CONNECTION_FILE": "dsn-from-file"}, wantDSN: "dsn-from-file"},
+		{name: "file with newline", env: map[string]string{"DB_CONNECTION_FILE": "dsn-from-file\n"}, wantDSN: "dsn-from-file"},
+		{name: "file over env", env: map[string]string{"DB_CONNECTION": "env-dsn", "DB_CONNECTION_FILE": "dsn-from-file"}, wantDSN: "dsn-from-file"},
+		{name: "missing file", env: map[string]string{"DB_CONNECTION": "env-dsn", "DB_CONNECTION_FILE": ""}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				if strings.HasSuffix(k, "_FILE") {
+					v = filepath.Join(t.TempDir(), "missing")
+					if content := tt.env[k]; content != "" {
+						v = writeFile(t, "secret", content)
+					}
+				}
+				t.Setenv(k, v)
+			}
+
+			cfg, _, err := Load(nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Load() read a missing secret file")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.DB.Connection != tt.wantDSN {
+				t.Errorf("dsn = %q, want %q", cfg.DB.Connection, tt.wantDSN)
+			}
+		})
+	}
+
+	t.Run("only for secrets", func(t *testing.T) {
+		clearEnv(t)
+		t.Setenv("DB_CONNECTION", "dsn")
+		t.Setenv("SERVER_ADDR_FILE", writeFile(t, "addr", ":9999"))
+
+		cfg, _, err := Load(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Server.Addr != ":5500" {
+			t.Errorf("addr = %q, SERVER_ADDR_FILE is not a secret", cfg.Server.Addr)
+		}
+	})
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DB_CONNECTION", "postgres://user:hunter2@db/subs")
+	t.Setenv("AUTH_API_KEYS", "00000000-0000-0000-0000-00000000000a:key-one,00000000-0000-0000-0000-00000000000b:key-two")
+
+	cfg, printOnly, err := Load([]string{"--print-config"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !printOnly {
+		t.Error("printOnly = false with --print-config")
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, secret := range []string{"hunter2", "key-one", "key-two"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("printed config contains %q:\n%s", secret, out)
+		}
+	}
+
+	var printed struct {
+		Server map[string]any `yaml:"server"`
+		DB     map[string]any `yaml:"db"`
+		Auth   map[string]any `yaml:"auth"`
+	}
+	if err := yaml.Unmarshal(buf.Bytes(), &printed); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{name: "db.connection", got: printed.DB["connection"], want: redactedValue},
+		{name: "auth.api_keys", got: printed.Auth["api_keys"], want: redactedValue},
+		{name: "server.addr", got: printed.Server["addr"], want: ":5500"},
+		{name: "server.read_timeout", got: printed.Server["read_timeout"], want: "10s"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+// Unset secrets are printed empty, so a missing one is visible.
+func TestPrintEmptySecret(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Default().Print(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), redactedValue) {
+		t.Errorf("unset secrets are redacted:\n%s", buf.String())
+	}
+}
+
+// The server refuses to start open unless it is asked to.
+func TestValidateServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    Auth
+		wantErr bool
+	}{
+		{name: "no keys", wantErr: true},
+		{name: "keys", auth: Auth{APIKeys: []string{"00000000-0000-0000-0000-00000000000a:key-one"}}},
+		{name: "allow open", auth: Auth{AllowOpen: true}},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.Auth = tt.auth
+		if err := cfg.ValidateServer(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidateServer() error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
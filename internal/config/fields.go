@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const redactedValue = "[REDACTED]"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// field is a leaf of Config addressed by its YAML path.
+type field struct {
+	path   string
+	env    string
+	secret bool
+	value  reflect.Value
+}
+
+func walk(cfg *Config) []field {
+	var fields []field
+	walkStruct(reflect.ValueOf(cfg).Elem(), "", &fields)
+	return fields
+}
+
+func walkStruct(v reflect.Value, prefix string, fields *[]field) {
+	t := v.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		path := prefix + name
+
+		if sf.Type.Kind() == reflect.Struct && sf.Type != durationType {
+			walkStruct(v.Field(i), path+".", fields)
+			continue
+		}
+
+		*fields = append(*fields, field{
+			path:   path,
+			env:    sf.Tag.Get("env"),
+			secret: sf.Tag.Get("secret") == "true",
+			value:  v.Field(i),
+		})
+	}
+}
+
+// set parses a flag or environment value into the field. Lists are comma
+// separated.
+func (f field) set(s string) error {
+	v := f.value
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// redacted turns the config into nested maps for printing, with durations
+// as strings and secrets hidden.
+func redacted(cfg *Config) map[string]any {
+	out := map[string]any{}
+	for _, f := range walk(cfg) {
+		var value any = f.value.Interface()
+		if d, ok := value.(time.Duration); ok {
+			value = d.String()
+		}
+		if f.secret && !f.value.IsZero() {
+			value = redactedValue
+		}
+
+		section, key, _ := strings.Cut(f.path, ".")
+		if _, ok := out[section]; !ok {
+			out[section] = map[string]any{}
+		}
+		out[section].(map[string]any)[key] = value
+	}
+	return out
+}
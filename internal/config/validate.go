@@ -0,0 +1,69 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"usersubs/internal/auth"
+	"usersubs/internal/clock"
+)
+
+// Validate reports every invalid field at once.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr is empty")
+	check(!c.Features.GRPC || c.Server.GRPCAddr != "", "server.grpc_addr is empty")
+	check((c.Server.TLSCertFile == "") == (c.Server.TLSKeyFile == ""), "server.tls_cert_file and server.tls_key_file must be set together")
+	check(c.Server.ReadTimeout > 0, "server.read_timeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout must be positive")
+	check(c.Server.DrainTimeout > 0, "server.drain_timeout must be positive")
+	check(c.Server.DrainDelay >= 0, "server.drain_delay must not be negative")
+
+	check(c.DB.Connection != "", "db.connection is empty")
+	check(c.DB.MaxOpenConns >= 0, "db.max_open_conns must not be negative")
+	check(c.DB.MaxIdleConns >= 0, "db.max_idle_conns must not be negative")
+	check(c.DB.MaxOpenConns == 0 || c.DB.MaxIdleConns <= c.DB.MaxOpenConns, "db.max_idle_conns must not exceed db.max_open_conns")
+	check(c.DB.ConnMaxLifetime >= 0, "db.conn_max_lifetime must not be negative")
+	check(c.DB.QueryTimeout > 0, "db.query_timeout must be positive")
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level %q is not one of debug, info, warn, error", c.Log.Level)
+
+	check(slices.Contains([]string{"", "otlp", "stdout", "file"}, c.Tracing.Exporter), "tracing.exporter %q is not one of otlp, stdout, file", c.Tracing.Exporter)
+	check(c.Tracing.Exporter != "file" || c.Tracing.File != "", "tracing.file is required for the file exporter")
+
+	if c.Debug.FakeNow != "" {
+		_, err := clock.Parse(c.Debug.FakeNow)
+		check(err == nil, "debug.fake_now - %v", err)
+	}
+
+	check(slices.Contains([]string{"memory", "postgres"}, c.RateLimit.Store), "rate_limit.store %q is not one of memory, postgres", c.RateLimit.Store)
+	if _, err := auth.ParseKeys(c.Auth.APIKeys); err != nil {
+		check(false, "auth.api_keys - %v", err)
+	}
+
+	check(c.RateLimit.Rate > 0, "rate_limit.rate must be positive")
+	check(c.RateLimit.Burst >= 1, "rate_limit.burst must be at least 1")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("CONFIG - invalid config - %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// ValidateServer reports the settings only the server needs, migrations and
+// subsctl run without them.
+func (c Config) ValidateServer() error {
+	if len(c.Auth.APIKeys) == 0 && !c.Auth.AllowOpen {
+		return errors.New("CONFIG - invalid config - auth.api_keys is empty, set auth.allow_open to run without authorization")
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		origins     []string
+		method      string
+		origin      string
+		preflight   bool
+		wantCode    int
+		wantAllowed string
+	}{
+		{name: "no origins configured", method: http.MethodGet, origin: "https://app.example", wantCode: http.StatusOK},
+		{name: "allowed origin", origins: []string{"https://app.example"}, method: http.MethodGet, origin: "https://app.example", wantCode: http.StatusOK, wantAllowed: "https://app.example"},
+		{name: "other origin", origins: []string{"https://app.example"}, method: http.MethodGet, origin: "https://evil.example", wantCode: http.StatusOK},
+		{name: "any origin", origins: []string{"*"}, method: http.MethodGet, origin: "https://evil.example", wantCode: http.StatusOK, wantAllowed: "https://evil.example"},
+		{name: "preflight", origins: []string{"https://app.example"}, method: http.MethodOptions, origin: "https://app.example", preflight: true, wantCode: http.StatusNoContent, wantAllowed: "https://app.example"},
+		{name: "options without preflight", origins: []string{"https://app.example"}, method: http.MethodOptions, origin: "https://app.example", wantCode: http.StatusOK, wantAllowed: "https://app.example"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Policy{AllowedOrigins: tt.origins, AllowedMethods: []string{"GET", "POST"}, AllowedHeaders: []string{"X-API-Key"}}
+			h := p.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			r := httptest.NewRequest(tt.method, "/api/subs", nil)
+			r.Header.Set("Origin", tt.origin)
+			if tt.preflight {
+				r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllowed {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantAllowed)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Methods"); tt.preflight != (got == "GET, POST") {
+				t.Errorf("Access-Control-Allow-Methods = %q", got)
+			}
+		})
+	}
+}
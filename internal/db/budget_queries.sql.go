@@ -0,0 +1,133 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: budget_queries.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const addBudget = `-- name: AddBudget :one
+INSERT INTO budgets (
+    tenant_id,
+    user_id,
+    scope,
+    target,
+    amount,
+    currency,
+    period,
+    enforce
+) VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+) RETURNING id, tenant_id, user_id, scope, target, amount, currency, period, enforce, created_at
+`
+
+type AddBudgetParams struct {
+	TenantID uuid.UUID
+	UserID   uuid.UUID
+	Scope    string
+	Target   string
+	Amount   int32
+	Currency string
+	Period   string
+	Enforce  bool
+}
+
+func (q *Queries) AddBudget(ctx context.Context, arg AddBudgetParams) (Budget, error) {
+	row := q.db.QueryRowContext(ctx, addBudget,
+		arg.TenantID,
+		arg.UserID,
+		arg.Scope,
+		arg.Target,
+		arg.Amount,
+		arg.Currency,
+		arg.Period,
+		arg.Enforce,
+	)
+	var i Budget
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.UserID,
+		&i.Scope,
+		&i.Target,
+		&i.Amount,
+		&i.Currency,
+		&i.Period,
+		&i.Enforce,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteBudget = `-- name: DeleteBudget :one
+DELETE FROM budgets WHERE id = $1 AND user_id = $2 AND tenant_id = $3 RETURNING id
+`
+
+type DeleteBudgetParams struct {
+	ID       int32
+	UserID   uuid.UUID
+	TenantID uuid.UUID
+}
+
+func (q *Queries) DeleteBudget(ctx context.Context, arg DeleteBudgetParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, deleteBudget, arg.ID, arg.UserID, arg.TenantID)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getBudgets = `-- name: GetBudgets :many
+SELECT id, tenant_id, user_id, scope, target, amount, currency, period, enforce, created_at FROM budgets WHERE user_id = $1 AND tenant_id = $2
+ORDER BY id
+`
+
+type GetBudgetsParams struct {
+	UserID   uuid.UUID
+	TenantID uuid.UUID
+}
+
+func (q *Queries) GetBudgets(ctx context.Context, arg GetBudgetsParams) ([]Budget, error) {
+	rows, err := q.db.QueryContext(ctx, getBudgets, arg.UserID, arg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Budget
+	for rows.Next() {
+		var i Budget
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.UserID,
+			&i.Scope,
+			&i.Target,
+			&i.Amount,
+			&i.Currency,
+			&i.Period,
+			&i.Enforce,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
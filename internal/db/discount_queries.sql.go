@@ -0,0 +1,192 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: discount_queries.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addDiscount = `-- name: AddDiscount :one
+INSERT INTO subscription_discounts (
+    subscription_id,
+    tenant_id,
+    kind,
+    amount,
+    started_at,
+    cycles,
+    until,
+    ends_at
+) VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+) RETURNING id, subscription_id, tenant_id, kind, amount, started_at, cycles, until, ends_at, created_at
+`
+
+type AddDiscountParams struct {
+	SubscriptionID int32
+	TenantID       uuid.UUID
+	Kind           string
+	Amount         int32
+	StartedAt      time.Time
+	Cycles         sql.NullInt32
+	Until          sql.NullTime
+	EndsAt         time.Time
+}
+
+func (q *Queries) AddDiscount(ctx context.Context, arg AddDiscountParams) (SubscriptionDiscount, error) {
+	row := q.db.QueryRowContext(ctx, addDiscount,
+		arg.SubscriptionID,
+		arg.TenantID,
+		arg.Kind,
+		arg.Amount,
+		arg.StartedAt,
+		arg.Cycles,
+		arg.Until,
+		arg.EndsAt,
+	)
+	var i SubscriptionDiscount
+	err := row.Scan(
+		&i.ID,
+		&i.SubscriptionID,
+		&i.TenantID,
+		&i.Kind,
+		&i.Amount,
+		&i.StartedAt,
+		&i.Cycles,
+		&i.Until,
+		&i.EndsAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteDiscount = `-- name: DeleteDiscount :one
+DELETE FROM subscription_discounts WHERE id = $1 AND subscription_id = $2 AND tenant_id = $3 RETURNING id
+`
+
+type DeleteDiscountParams struct {
+	ID             int32
+	SubscriptionID int32
+	TenantID       uuid.UUID
+}
+
+func (q *Queries) DeleteDiscount(ctx context.Context, arg DeleteDiscountParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, deleteDiscount, arg.ID, arg.SubscriptionID, arg.TenantID)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getDiscountsBySubs = `-- name: GetDiscountsBySubs :many
+SELECT id, subscription_id, tenant_id, kind, amount, started_at, cycles, until, ends_at, created_at FROM subscription_discounts WHERE subscription_id = ANY($1::int[]) AND tenant_id = $2
+ORDER BY subscription_id, started_at
+`
+
+type GetDiscountsBySubsParams struct {
+	SubscriptionIds []int32
+	TenantID        uuid.UUID
+}
+
+func (q *Queries) GetDiscountsBySubs(ctx context.Context, arg GetDiscountsBySubsParams) ([]SubscriptionDiscount, error) {
+	rows, err := q.db.QueryContext(ctx, getDiscountsBySubs, pq.Array(arg.SubscriptionIds), arg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SubscriptionDiscount
+	for rows.Next() {
+		var i SubscriptionDiscount
+		if err := rows.Scan(
+			&i.ID,
+			&i.SubscriptionID,
+			&i.TenantID,
+			&i.Kind,
+			&i.Amount,
+			&i.StartedAt,
+			&i.Cycles,
+			&i.Until,
+			&i.EndsAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExpiringDiscounts = `-- name: GetExpiringDiscounts :many
+SELECT subscription_discounts.id, subscription_discounts.subscription_id, subscription_discounts.tenant_id, subscription_discounts.kind, subscription_discounts.amount, subscription_discounts.started_at, subscription_discounts.cycles, subscription_discounts.until, subscription_discounts.ends_at, subscription_discounts.created_at FROM subscription_discounts
+JOIN subscriptions ON subscriptions.id = subscription_discounts.subscription_id
+WHERE subscription_discounts.tenant_id = $1
+    AND subscription_discounts.ends_at > $2::timestamp
+    AND subscription_discounts.ends_at <= $3::timestamp
+    AND ($4::uuid IS NULL OR subscriptions.user_id = $4)
+ORDER BY subscription_discounts.ends_at, subscription_discounts.id
+`
+
+type GetExpiringDiscountsParams struct {
+	TenantID uuid.UUID
+	Now      time.Time
+	Until    time.Time
+	UserID   uuid.NullUUID
+}
+
+func (q *Queries) GetExpiringDiscounts(ctx context.Context, arg GetExpiringDiscountsParams) ([]SubscriptionDiscount, error) {
+	rows, err := q.db.QueryContext(ctx, getExpiringDiscounts,
+		arg.TenantID,
+		arg.Now,
+		arg.Until,
+		arg.UserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SubscriptionDiscount
+	for rows.Next() {
+		var i SubscriptionDiscount
+		if err := rows.Scan(
+			&i.ID,
+			&i.SubscriptionID,
+			&i.TenantID,
+			&i.Kind,
+			&i.Amount,
+			&i.StartedAt,
+			&i.Cycles,
+			&i.Until,
+			&i.EndsAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
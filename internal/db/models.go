@@ -6,18 +6,130 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type Budget struct {
+	ID        int32
+	TenantID  uuid.UUID
+	UserID    uuid.UUID
+	Scope     string
+	Target    string
+	Amount    int32
+	Currency  string
+	Period    string
+	Enforce   bool
+	CreatedAt time.Time
+}
+
+type Category struct {
+	ID       int32
+	TenantID uuid.UUID
+	Name     string
+}
+
+type Organization struct {
+	ID        uuid.UUID
+	Name      string
+	CreatedAt time.Time
+}
+
+type RateLimit struct {
+	Key       string
+	Tokens    float64
+	Allowed   bool
+	UpdatedAt time.Time
+}
+
 type Subscription struct {
-	ID          int32
-	ServiceName string
-	Price       int32
-	UserID      uuid.UUID
-	StartedAt   time.Time
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	EndedAt     sql.NullTime
+	ID                int32
+	ServiceName       string
+	Price             int32
+	UserID            uuid.UUID
+	StartedAt         time.Time
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	EndedAt           sql.NullTime
+	TenantID          uuid.UUID
+	Status            string
+	StatusChangedAt   time.Time
+	CancelAtPeriodEnd bool
+	TrialEndsAt       sql.NullTime
+	TrialPrice        int32
+	Metadata          json.RawMessage
+}
+
+type SubscriptionCategory struct {
+	SubscriptionID int32
+	CategoryID     int32
+	TenantID       uuid.UUID
+}
+
+type SubscriptionDiscount struct {
+	ID             int32
+	SubscriptionID int32
+	TenantID       uuid.UUID
+	Kind           string
+	Amount         int32
+	StartedAt      time.Time
+	Cycles         sql.NullInt32
+	Until          sql.NullTime
+	EndsAt         time.Time
+	CreatedAt      time.Time
+}
+
+type SubscriptionMember struct {
+	ID             int32
+	SubscriptionID int32
+	TenantID       uuid.UUID
+	UserID         uuid.UUID
+	Status         string
+	Split          string
+	Amount         int32
+	CreatedAt      time.Time
+	JoinedAt       sql.NullTime
+	RemovedAt      sql.NullTime
+}
+
+type SubscriptionPause struct {
+	ID             int32
+	SubscriptionID int32
+	TenantID       uuid.UUID
+	StartedAt      time.Time
+	EndedAt        sql.NullTime
+	CreatedAt      time.Time
+}
+
+type SubscriptionPrice struct {
+	ID             int32
+	SubscriptionID int32
+	TenantID       uuid.UUID
+	EffectiveFrom  time.Time
+	Price          int32
+	Currency       string
+	CreatedAt      time.Time
+}
+
+type SubscriptionStatusChange struct {
+	ID             int32
+	SubscriptionID int32
+	TenantID       uuid.UUID
+	FromStatus     string
+	ToStatus       string
+	ChangedAt      time.Time
+}
+
+type SubscriptionTag struct {
+	SubscriptionID int32
+	TagID          int32
+	TenantID       uuid.UUID
+}
+
+type Tag struct {
+	ID       int32
+	TenantID uuid.UUID
+	Name     string
 }
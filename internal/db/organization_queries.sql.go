@@ -0,0 +1,71 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: organization_queries.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const bypassesRowSecurity = `-- name: BypassesRowSecurity :one
+SELECT (rolsuper OR rolbypassrls) AS bypass FROM pg_catalog.pg_roles WHERE rolname = current_user
+`
+
+func (q *Queries) BypassesRowSecurity(ctx context.Context) (sql.NullBool, error) {
+	row := q.db.QueryRowContext(ctx, bypassesRowSecurity)
+	var bypass sql.NullBool
+	err := row.Scan(&bypass)
+	return bypass, err
+}
+
+const getOrganization = `-- name: GetOrganization :one
+SELECT id, name, created_at FROM organizations WHERE id = $1
+`
+
+func (q *Queries) GetOrganization(ctx context.Context, id uuid.UUID) (Organization, error) {
+	row := q.db.QueryRowContext(ctx, getOrganization, id)
+	var i Organization
+	err := row.Scan(&i.ID, &i.Name, &i.CreatedAt)
+	return i, err
+}
+
+const getOrganizationIDs = `-- name: GetOrganizationIDs :many
+SELECT id FROM organizations ORDER BY id
+`
+
+func (q *Queries) GetOrganizationIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getOrganizationIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setTenant = `-- name: SetTenant :exec
+SELECT set_config('app.tenant_id', $1::text, true)
+`
+
+func (q *Queries) SetTenant(ctx context.Context, tenantID string) error {
+	_, err := q.db.ExecContext(ctx, setTenant, tenantID)
+	return err
+}
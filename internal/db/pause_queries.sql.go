@@ -0,0 +1,184 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: pause_queries.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addPause = `-- name: AddPause :one
+INSERT INTO subscription_pauses (
+    subscription_id,
+    tenant_id,
+    started_at,
+    ended_at
+) VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+) RETURNING id, subscription_id, tenant_id, started_at, ended_at, created_at
+`
+
+type AddPauseParams struct {
+	SubscriptionID int32
+	TenantID       uuid.UUID
+	StartedAt      time.Time
+	EndedAt        sql.NullTime
+}
+
+func (q *Queries) AddPause(ctx context.Context, arg AddPauseParams) (SubscriptionPause, error) {
+	row := q.db.QueryRowContext(ctx, addPause,
+		arg.SubscriptionID,
+		arg.TenantID,
+		arg.StartedAt,
+		arg.EndedAt,
+	)
+	var i SubscriptionPause
+	err := row.Scan(
+		&i.ID,
+		&i.SubscriptionID,
+		&i.TenantID,
+		&i.StartedAt,
+		&i.EndedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deletePause = `-- name: DeletePause :one
+DELETE FROM subscription_pauses WHERE id = $1 AND subscription_id = $2 AND tenant_id = $3 RETURNING id
+`
+
+type DeletePauseParams struct {
+	ID             int32
+	SubscriptionID int32
+	TenantID       uuid.UUID
+}
+
+func (q *Queries) DeletePause(ctx context.Context, arg DeletePauseParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, deletePause, arg.ID, arg.SubscriptionID, arg.TenantID)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
+const endPause = `-- name: EndPause :one
+UPDATE subscription_pauses SET ended_at = $1
+WHERE id = $2 AND subscription_id = $3 AND tenant_id = $4
+RETURNING id, subscription_id, tenant_id, started_at, ended_at, created_at
+`
+
+type EndPauseParams struct {
+	EndedAt        sql.NullTime
+	ID             int32
+	SubscriptionID int32
+	TenantID       uuid.UUID
+}
+
+func (q *Queries) EndPause(ctx context.Context, arg EndPauseParams) (SubscriptionPause, error) {
+	row := q.db.QueryRowContext(ctx, endPause,
+		arg.EndedAt,
+		arg.ID,
+		arg.SubscriptionID,
+		arg.TenantID,
+	)
+	var i SubscriptionPause
+	err := row.Scan(
+		&i.ID,
+		&i.SubscriptionID,
+		&i.TenantID,
+		&i.StartedAt,
+		&i.EndedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPauses = `-- name: GetPauses :many
+SELECT id, subscription_id, tenant_id, started_at, ended_at, created_at FROM subscription_pauses WHERE subscription_id = $1 AND tenant_id = $2
+ORDER BY started_at
+`
+
+type GetPausesParams struct {
+	SubscriptionID int32
+	TenantID       uuid.UUID
+}
+
+func (q *Queries) GetPauses(ctx context.Context, arg GetPausesParams) ([]SubscriptionPause, error) {
+	rows, err := q.db.QueryContext(ctx, getPauses, arg.SubscriptionID, arg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SubscriptionPause
+	for rows.Next() {
+		var i SubscriptionPause
+		if err := rows.Scan(
+			&i.ID,
+			&i.SubscriptionID,
+			&i.TenantID,
+			&i.StartedAt,
+			&i.EndedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPausesBySubs = `-- name: GetPausesBySubs :many
+SELECT id, subscription_id, tenant_id, started_at, ended_at, created_at FROM subscription_pauses WHERE subscription_id = ANY($1::int[]) AND tenant_id = $2
+ORDER BY subscription_id, started_at
+`
+
+type GetPausesBySubsParams struct {
+	SubscriptionIds []int32
+	TenantID        uuid.UUID
+}
+
+func (q *Queries) GetPausesBySubs(ctx context.Context, arg GetPausesBySubsParams) ([]SubscriptionPause, error) {
+	rows, err := q.db.QueryContext(ctx, getPausesBySubs, pq.Array(arg.SubscriptionIds), arg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SubscriptionPause
+	for rows.Next() {
+		var i SubscriptionPause
+		if err := rows.Scan(
+			&i.ID,
+			&i.SubscriptionID,
+			&i.TenantID,
+			&i.StartedAt,
+			&i.EndedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
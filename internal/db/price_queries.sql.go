@@ -0,0 +1,154 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: price_queries.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const getPricesBySubs = `-- name: GetPricesBySubs :many
+SELECT id, subscription_id, tenant_id, effective_from, price, currency, created_at FROM subscription_prices WHERE subscription_id = ANY($1::int[]) AND tenant_id = $2
+ORDER BY subscription_id, effective_from
+`
+
+type GetPricesBySubsParams struct {
+	SubscriptionIds []int32
+	TenantID        uuid.UUID
+}
+
+func (q *Queries) GetPricesBySubs(ctx context.Context, arg GetPricesBySubsParams) ([]SubscriptionPrice, error) {
+	rows, err := q.db.QueryContext(ctx, getPricesBySubs, pq.Array(arg.SubscriptionIds), arg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SubscriptionPrice
+	for rows.Next() {
+		var i SubscriptionPrice
+		if err := rows.Scan(
+			&i.ID,
+			&i.SubscriptionID,
+			&i.TenantID,
+			&i.EffectiveFrom,
+			&i.Price,
+			&i.Currency,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getServiceSubsForUpdate = `-- name: GetServiceSubsForUpdate :many
+SELECT id, service_name, price, user_id, started_at, created_at, updated_at, ended_at, tenant_id, status, status_changed_at, cancel_at_period_end, trial_ends_at, trial_price, metadata FROM subscriptions
+WHERE tenant_id = $1 AND service_name = $2
+    AND started_at < $3 AND (ended_at IS NULL OR ended_at >= $3)
+ORDER BY id FOR UPDATE
+`
+
+type GetServiceSubsForUpdateParams struct {
+	TenantID      uuid.UUID
+	ServiceName   string
+	EffectiveFrom time.Time
+}
+
+func (q *Queries) GetServiceSubsForUpdate(ctx context.Context, arg GetServiceSubsForUpdateParams) ([]Subscription, error) {
+	rows, err := q.db.QueryContext(ctx, getServiceSubsForUpdate, arg.TenantID, arg.ServiceName, arg.EffectiveFrom)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Subscription
+	for rows.Next() {
+		var i Subscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServiceName,
+			&i.Price,
+			&i.UserID,
+			&i.StartedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.EndedAt,
+			&i.TenantID,
+			&i.Status,
+			&i.StatusChangedAt,
+			&i.CancelAtPeriodEnd,
+			&i.TrialEndsAt,
+			&i.TrialPrice,
+			&i.Metadata,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setPrice = `-- name: SetPrice :one
+INSERT INTO subscription_prices (
+    subscription_id,
+    tenant_id,
+    effective_from,
+    price,
+    currency
+) VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (subscription_id, effective_from) DO UPDATE SET price = EXCLUDED.price, currency = EXCLUDED.currency
+RETURNING id, subscription_id, tenant_id, effective_from, price, currency, created_at
+`
+
+type SetPriceParams struct {
+	SubscriptionID int32
+	TenantID       uuid.UUID
+	EffectiveFrom  time.Time
+	Price          int32
+	Currency       string
+}
+
+func (q *Queries) SetPrice(ctx context.Context, arg SetPriceParams) (SubscriptionPrice, error) {
+	row := q.db.QueryRowContext(ctx, setPrice,
+		arg.SubscriptionID,
+		arg.TenantID,
+		arg.EffectiveFrom,
+		arg.Price,
+		arg.Currency,
+	)
+	var i SubscriptionPrice
+	err := row.Scan(
+		&i.ID,
+		&i.SubscriptionID,
+		&i.TenantID,
+		&i.EffectiveFrom,
+		&i.Price,
+		&i.Currency,
+		&i.CreatedAt,
+	)
+	return i, err
+}
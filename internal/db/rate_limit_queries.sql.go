@@ -0,0 +1,59 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: rate_limit_queries.sql
+
+package db
+
+import (
+	"context"
+)
+
+const deleteIdleRateLimits = `-- name: DeleteIdleRateLimits :exec
+DELETE FROM rate_limits WHERE updated_at < now() - INTERVAL '1 hour'
+`
+
+func (q *Queries) DeleteIdleRateLimits(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteIdleRateLimits)
+	return err
+}
+
+const takeRateLimitToken = `-- name: TakeRateLimitToken :one
+INSERT INTO rate_limits AS rl (
+    key,
+    tokens,
+    allowed,
+    updated_at
+) VALUES (
+    $1,
+    ($2::float8) - 1,
+    TRUE,
+    now()
+) ON CONFLICT (key) DO UPDATE SET
+    tokens = CASE
+        WHEN LEAST($2::float8, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at)::float8 * $3::float8) >= 1
+        THEN LEAST($2::float8, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at)::float8 * $3::float8) - 1
+        ELSE LEAST($2::float8, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at)::float8 * $3::float8)
+    END,
+    allowed = LEAST($2::float8, rl.tokens + EXTRACT(EPOCH FROM now() - rl.updated_at)::float8 * $3::float8) >= 1,
+    updated_at = now()
+RETURNING tokens, allowed
+`
+
+type TakeRateLimitTokenParams struct {
+	Key   string
+	Burst float64
+	Rate  float64
+}
+
+type TakeRateLimitTokenRow struct {
+	Tokens  float64
+	Allowed bool
+}
+
+func (q *Queries) TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (TakeRateLimitTokenRow, error) {
+	row := q.db.QueryRowContext(ctx, takeRateLimitToken, arg.Key, arg.Burst, arg.Rate)
+	var i TakeRateLimitTokenRow
+	err := row.Scan(&i.Tokens, &i.Allowed)
+	return i, err
+}
@@ -0,0 +1,51 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: stats_queries.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const getActiveSubsStats = `-- name: GetActiveSubsStats :many
+SELECT
+    tenant_id,
+    count(*) AS active,
+    COALESCE(sum(price), 0)::bigint AS monthly_spend
+FROM subscriptions
+WHERE started_at <= now() AND (ended_at IS NULL OR ended_at > now())
+GROUP BY tenant_id
+`
+
+type GetActiveSubsStatsRow struct {
+	TenantID     uuid.UUID
+	Active       int64
+	MonthlySpend int64
+}
+
+func (q *Queries) GetActiveSubsStats(ctx context.Context) ([]GetActiveSubsStatsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveSubsStats)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetActiveSubsStatsRow
+	for rows.Next() {
+		var i GetActiveSubsStatsRow
+		if err := rows.Scan(&i.TenantID, &i.Active, &i.MonthlySpend); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
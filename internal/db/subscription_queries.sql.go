@@ -8,9 +8,11 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const addSub = `-- name: AddSub :one
@@ -19,13 +21,23 @@ INSERT INTO subscriptions (
     price,
     user_id,
     started_at,
-    ended_at
+    ended_at,
+    tenant_id,
+    trial_ends_at,
+    trial_price,
+    status,
+    metadata
 ) VALUES (
     $1,
     $2,
     $3,
     $4,
-    $5
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10
 ) RETURNING id
 `
 
@@ -35,6 +47,11 @@ type AddSubParams struct {
 	UserID      uuid.UUID
 	StartedAt   time.Time
 	EndedAt     sql.NullTime
+	TenantID    uuid.UUID
+	TrialEndsAt sql.NullTime
+	TrialPrice  int32
+	Status      string
+	Metadata    json.RawMessage
 }
 
 func (q *Queries) AddSub(ctx context.Context, arg AddSubParams) (int32, error) {
@@ -44,6 +61,11 @@ func (q *Queries) AddSub(ctx context.Context, arg AddSubParams) (int32, error) {
 		arg.UserID,
 		arg.StartedAt,
 		arg.EndedAt,
+		arg.TenantID,
+		arg.TrialEndsAt,
+		arg.TrialPrice,
+		arg.Status,
+		arg.Metadata,
 	)
 	var id int32
 	err := row.Scan(&id)
@@ -51,21 +73,32 @@ func (q *Queries) AddSub(ctx context.Context, arg AddSubParams) (int32, error) {
 }
 
 const deleteSub = `-- name: DeleteSub :one
-DELETE FROM subscriptions WHERE id = $1 RETURNING id
+DELETE FROM subscriptions WHERE id = $1 AND tenant_id = $2 RETURNING id
 `
 
-func (q *Queries) DeleteSub(ctx context.Context, id int32) (int32, error) {
-	row := q.db.QueryRowContext(ctx, deleteSub, id)
+type DeleteSubParams struct {
+	ID       int32
+	TenantID uuid.UUID
+}
+
+func (q *Queries) DeleteSub(ctx context.Context, arg DeleteSubParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, deleteSub, arg.ID, arg.TenantID)
+	var id int32
 	err := row.Scan(&id)
 	return id, err
 }
 
 const deleteUserSubs = `-- name: DeleteUserSubs :many
-DELETE FROM subscriptions WHERE user_id = $1 RETURNING id
+DELETE FROM subscriptions WHERE user_id = $1 AND tenant_id = $2 RETURNING id
 `
 
-func (q *Queries) DeleteUserSubs(ctx context.Context, userID uuid.UUID) ([]int32, error) {
-	rows, err := q.db.QueryContext(ctx, deleteUserSubs, userID)
+type DeleteUserSubsParams struct {
+	UserID   uuid.UUID
+	TenantID uuid.UUID
+}
+
+func (q *Queries) DeleteUserSubs(ctx context.Context, arg DeleteUserSubsParams) ([]int32, error) {
+	rows, err := q.db.QueryContext(ctx, deleteUserSubs, arg.UserID, arg.TenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -87,12 +120,199 @@ func (q *Queries) DeleteUserSubs(ctx context.Context, userID uuid.UUID) ([]int32
 	return items, nil
 }
 
+const filterSubs = `-- name: FilterSubs :many
+SELECT subscriptions.id, subscriptions.service_name, subscriptions.price, subscriptions.user_id, subscriptions.started_at, subscriptions.created_at, subscriptions.updated_at, subscriptions.ended_at, subscriptions.tenant_id, subscriptions.status, subscriptions.status_changed_at, subscriptions.cancel_at_period_end, subscriptions.trial_ends_at, subscriptions.trial_price, subscriptions.metadata FROM subscriptions
+LEFT JOIN LATERAL (
+    -- The price in effect in the current month, prices are filtered by it.
+    SELECT p.price FROM subscription_prices p
+    WHERE p.subscription_id = subscriptions.id AND p.effective_from <= $1::timestamp
+    ORDER BY p.effective_from DESC LIMIT 1
+) current_price ON true
+WHERE subscriptions.tenant_id = $2
+    AND ($3::uuid IS NULL OR subscriptions.user_id = $3
+        OR ($4::boolean AND EXISTS (
+            SELECT 1 FROM subscription_members m
+            WHERE m.subscription_id = subscriptions.id AND m.user_id = $3 AND m.joined_at IS NOT NULL
+        )))
+    AND ($5::text IS NULL OR subscriptions.service_name = $5)
+    AND ($6::text IS NULL OR EXISTS (
+        SELECT 1 FROM subscription_categories sc JOIN categories c ON c.id = sc.category_id
+        WHERE sc.subscription_id = subscriptions.id AND c.name = $6
+    ))
+    -- A jsonpath of equality checks, which the jsonb_path_ops index serves.
+    AND ($7::text IS NULL OR subscriptions.metadata @@ $7::text::jsonpath)
+    AND ($8::text IS NULL OR EXISTS (
+        SELECT 1 FROM subscription_tags st JOIN tags t ON t.id = st.tag_id
+        WHERE st.subscription_id = subscriptions.id AND t.name = $8
+    ))
+    AND ($9::int IS NULL OR COALESCE(current_price.price, subscriptions.price) >= $9)
+    AND ($10::int IS NULL OR COALESCE(current_price.price, subscriptions.price) <= $10)
+    AND ($11::timestamp IS NULL OR subscriptions.started_at <= $11)
+    AND ($12::timestamp IS NULL OR subscriptions.ended_at IS NULL OR subscriptions.ended_at >= $12)
+    -- The status once ended_at or trial_ends_at has passed, like
+    -- service.Subscription.StatusAt.
+    AND ($13::text IS NULL OR $13 = CASE
+        WHEN subscriptions.status IN ('trial', 'active', 'paused') AND subscriptions.ended_at < $1::timestamp
+            THEN CASE WHEN subscriptions.cancel_at_period_end THEN 'cancelled' ELSE 'expired' END
+        WHEN subscriptions.status = 'trial' AND subscriptions.trial_ends_at <= $14::timestamp THEN 'active'
+        ELSE subscriptions.status
+    END)
+    -- Keyset paging, rows inserted or deleted meanwhile do not shift pages.
+    AND ($15::int IS NULL OR subscriptions.id > $15)
+ORDER BY subscriptions.id LIMIT $17 OFFSET $16
+`
+
+type FilterSubsParams struct {
+	CurrentMonth  time.Time
+	TenantID      uuid.UUID
+	UserID        uuid.NullUUID
+	IncludeShared bool
+	ServiceName   sql.NullString
+	Category      sql.NullString
+	Metadata      sql.NullString
+	Tag           sql.NullString
+	MinPrice      sql.NullInt32
+	MaxPrice      sql.NullInt32
+	ActiveTo      sql.NullTime
+	ActiveFrom    sql.NullTime
+	Status        sql.NullString
+	Now           time.Time
+	AfterID       sql.NullInt32
+	PageOffset    int32
+	PageLimit     sql.NullInt32
+}
+
+func (q *Queries) FilterSubs(ctx context.Context, arg FilterSubsParams) ([]Subscription, error) {
+	rows, err := q.db.QueryContext(ctx, filterSubs,
+		arg.CurrentMonth,
+		arg.TenantID,
+		arg.UserID,
+		arg.IncludeShared,
+		arg.ServiceName,
+		arg.Category,
+		arg.Metadata,
+		arg.Tag,
+		arg.MinPrice,
+		arg.MaxPrice,
+		arg.ActiveTo,
+		arg.ActiveFrom,
+		arg.Status,
+		arg.Now,
+		arg.AfterID,
+		arg.PageOffset,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Subscription
+	for rows.Next() {
+		var i Subscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServiceName,
+			&i.Price,
+			&i.UserID,
+			&i.StartedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.EndedAt,
+			&i.TenantID,
+			&i.Status,
+			&i.StatusChangedAt,
+			&i.CancelAtPeriodEnd,
+			&i.TrialEndsAt,
+			&i.TrialPrice,
+			&i.Metadata,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEndingTrials = `-- name: GetEndingTrials :many
+SELECT id, service_name, price, user_id, started_at, created_at, updated_at, ended_at, tenant_id, status, status_changed_at, cancel_at_period_end, trial_ends_at, trial_price, metadata FROM subscriptions
+WHERE tenant_id = $1
+    AND status = 'trial'
+    AND trial_ends_at > $2::timestamp AND trial_ends_at <= $3::timestamp
+    AND (ended_at IS NULL OR ended_at >= $4::timestamp)
+    AND ($5::uuid IS NULL OR user_id = $5)
+ORDER BY trial_ends_at, id
+`
+
+type GetEndingTrialsParams struct {
+	TenantID     uuid.UUID
+	Now          time.Time
+	Until        time.Time
+	CurrentMonth time.Time
+	UserID       uuid.NullUUID
+}
+
+func (q *Queries) GetEndingTrials(ctx context.Context, arg GetEndingTrialsParams) ([]Subscription, error) {
+	rows, err := q.db.QueryContext(ctx, getEndingTrials,
+		arg.TenantID,
+		arg.Now,
+		arg.Until,
+		arg.CurrentMonth,
+		arg.UserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Subscription
+	for rows.Next() {
+		var i Subscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServiceName,
+			&i.Price,
+			&i.UserID,
+			&i.StartedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.EndedAt,
+			&i.TenantID,
+			&i.Status,
+			&i.StatusChangedAt,
+			&i.CancelAtPeriodEnd,
+			&i.TrialEndsAt,
+			&i.TrialPrice,
+			&i.Metadata,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getSub = `-- name: GetSub :one
-SELECT id, service_name, price, user_id, started_at, created_at, updated_at, ended_at FROM subscriptions WHERE id = $1
+SELECT id, service_name, price, user_id, started_at, created_at, updated_at, ended_at, tenant_id, status, status_changed_at, cancel_at_period_end, trial_ends_at, trial_price, metadata FROM subscriptions WHERE id = $1 AND tenant_id = $2
 `
 
-func (q *Queries) GetSub(ctx context.Context, id int32) (Subscription, error) {
-	row := q.db.QueryRowContext(ctx, getSub, id)
+type GetSubParams struct {
+	ID       int32
+	TenantID uuid.UUID
+}
+
+func (q *Queries) GetSub(ctx context.Context, arg GetSubParams) (Subscription, error) {
+	row := q.db.QueryRowContext(ctx, getSub, arg.ID, arg.TenantID)
 	var i Subscription
 	err := row.Scan(
 		&i.ID,
@@ -103,16 +323,29 @@ func (q *Queries) GetSub(ctx context.Context, id int32) (Subscription, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.EndedAt,
+		&i.TenantID,
+		&i.Status,
+		&i.StatusChangedAt,
+		&i.CancelAtPeriodEnd,
+		&i.TrialEndsAt,
+		&i.TrialPrice,
+		&i.Metadata,
 	)
 	return i, err
 }
 
-const getSubs = `-- name: GetSubs :many
-SELECT id, service_name, price, user_id, started_at, created_at, updated_at, ended_at FROM subscriptions
+const getSubsByIDs = `-- name: GetSubsByIDs :many
+SELECT id, service_name, price, user_id, started_at, created_at, updated_at, ended_at, tenant_id, status, status_changed_at, cancel_at_period_end, trial_ends_at, trial_price, metadata FROM subscriptions WHERE id = ANY($1::int[]) AND tenant_id = $2
+ORDER BY id
 `
 
-func (q *Queries) GetSubs(ctx context.Context) ([]Subscription, error) {
-	rows, err := q.db.QueryContext(ctx, getSubs)
+type GetSubsByIDsParams struct {
+	Ids      []int32
+	TenantID uuid.UUID
+}
+
+func (q *Queries) GetSubsByIDs(ctx context.Context, arg GetSubsByIDsParams) ([]Subscription, error) {
+	rows, err := q.db.QueryContext(ctx, getSubsByIDs, pq.Array(arg.Ids), arg.TenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -129,6 +362,13 @@ func (q *Queries) GetSubs(ctx context.Context) ([]Subscription, error) {
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.EndedAt,
+			&i.TenantID,
+			&i.Status,
+			&i.StatusChangedAt,
+			&i.CancelAtPeriodEnd,
+			&i.TrialEndsAt,
+			&i.TrialPrice,
+			&i.Metadata,
 		); err != nil {
 			return nil, err
 		}
@@ -143,12 +383,72 @@ func (q *Queries) GetSubs(ctx context.Context) ([]Subscription, error) {
 	return items, nil
 }
 
-const getUserSubs = `-- name: GetUserSubs :many
-SELECT id, service_name, price, user_id, started_at, created_at, updated_at, ended_at FROM subscriptions WHERE user_id = $1
+const getSubsByServices = `-- name: GetSubsByServices :many
+SELECT id, service_name, price, user_id, started_at, created_at, updated_at, ended_at, tenant_id, status, status_changed_at, cancel_at_period_end, trial_ends_at, trial_price, metadata FROM subscriptions WHERE service_name = ANY($1::text[]) AND tenant_id = $2
+ORDER BY id
 `
 
-func (q *Queries) GetUserSubs(ctx context.Context, userID uuid.UUID) ([]Subscription, error) {
-	rows, err := q.db.QueryContext(ctx, getUserSubs, userID)
+type GetSubsByServicesParams struct {
+	ServiceNames []string
+	TenantID     uuid.UUID
+}
+
+func (q *Queries) GetSubsByServices(ctx context.Context, arg GetSubsByServicesParams) ([]Subscription, error) {
+	rows, err := q.db.QueryContext(ctx, getSubsByServices, pq.Array(arg.ServiceNames), arg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Subscription
+	for rows.Next() {
+		var i Subscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServiceName,
+			&i.Price,
+			&i.UserID,
+			&i.StartedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.EndedAt,
+			&i.TenantID,
+			&i.Status,
+			&i.StatusChangedAt,
+			&i.CancelAtPeriodEnd,
+			&i.TrialEndsAt,
+			&i.TrialPrice,
+			&i.Metadata,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSubsByUsers = `-- name: GetSubsByUsers :many
+SELECT id, service_name, price, user_id, started_at, created_at, updated_at, ended_at, tenant_id, status, status_changed_at, cancel_at_period_end, trial_ends_at, trial_price, metadata FROM subscriptions
+WHERE subscriptions.tenant_id = $1 AND (subscriptions.user_id = ANY($2::uuid[]) OR EXISTS (
+    SELECT 1 FROM subscription_members m
+    WHERE m.subscription_id = subscriptions.id AND m.user_id = ANY($2::uuid[]) AND m.joined_at IS NOT NULL
+))
+ORDER BY subscriptions.id
+`
+
+type GetSubsByUsersParams struct {
+	TenantID uuid.UUID
+	UserIds  []uuid.UUID
+}
+
+// Members removed since are included, they paid a share before.
+func (q *Queries) GetSubsByUsers(ctx context.Context, arg GetSubsByUsersParams) ([]Subscription, error) {
+	rows, err := q.db.QueryContext(ctx, getSubsByUsers, arg.TenantID, pq.Array(arg.UserIds))
 	if err != nil {
 		return nil, err
 	}
@@ -165,6 +465,13 @@ func (q *Queries) GetUserSubs(ctx context.Context, userID uuid.UUID) ([]Subscrip
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.EndedAt,
+			&i.TenantID,
+			&i.Status,
+			&i.StatusChangedAt,
+			&i.CancelAtPeriodEnd,
+			&i.TrialEndsAt,
+			&i.TrialPrice,
+			&i.Metadata,
 		); err != nil {
 			return nil, err
 		}
@@ -186,8 +493,11 @@ UPDATE subscriptions SET
     user_id = $3,
     started_at = $4,
     ended_at = $5,
-    updated_at = $6
-WHERE id = $7 RETURNING id
+    updated_at = $6,
+    trial_ends_at = $9,
+    trial_price = $10,
+    metadata = $11
+WHERE id = $7 AND tenant_id = $8 RETURNING id
 `
 
 type UpdateSubParams struct {
@@ -198,6 +508,10 @@ type UpdateSubParams struct {
 	EndedAt     sql.NullTime
 	UpdatedAt   time.Time
 	ID          int32
+	TenantID    uuid.UUID
+	TrialEndsAt sql.NullTime
+	TrialPrice  int32
+	Metadata    json.RawMessage
 }
 
 func (q *Queries) UpdateSub(ctx context.Context, arg UpdateSubParams) (int32, error) {
@@ -209,6 +523,10 @@ func (q *Queries) UpdateSub(ctx context.Context, arg UpdateSubParams) (int32, er
 		arg.EndedAt,
 		arg.UpdatedAt,
 		arg.ID,
+		arg.TenantID,
+		arg.TrialEndsAt,
+		arg.TrialPrice,
+		arg.Metadata,
 	)
 	var id int32
 	err := row.Scan(&id)
@@ -0,0 +1,67 @@
+// Package gql serves the subscriptions as a GraphQL API on /graphql.
+package gql
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"usersubs/internal/subs/service"
+	"usersubs/internal/tenant"
+	"usersubs/internal/utils"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schema string
+
+// maxDepth stops queries nesting user and subscriptions without end.
+const maxDepth = 8
+
+type Handler struct {
+	Service *service.SubscriptionService
+	schema  *graphql.Schema
+}
+
+func NewHandler(svc *service.SubscriptionService) *Handler {
+	return &Handler{
+		Service: svc,
+		schema:  graphql.MustParseSchema(schema, &resolver{svc: svc}, graphql.MaxDepth(maxDepth)),
+	}
+}
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// @Summary GraphQL
+// @Description Query and change subscriptions with GraphQL, the schema is in internal/gql/schema.graphql
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param request body request true "GraphQL query with operation name and variables"
+// @Router /graphql [POST]
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendError(w, "Error: something went wrong on decoding json", http.StatusBadRequest, err)
+		return
+	}
+
+	tenantID, _ := tenant.FromContext(r.Context())
+	ctx := withLoaders(r.Context(), newLoaders(h.Service, tenantID))
+
+	res := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+
+	body, err := json.Marshal(res)
+	if err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+
+	// Errors are part of a GraphQL response, so the status is always 200.
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(body, '\n'))
+}
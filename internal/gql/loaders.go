@@ -0,0 +1,78 @@
+package gql
+
+import (
+	"context"
+	"usersubs/internal/subs/service"
+
+	"github.com/google/uuid"
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// loaders batch the subscriptions of users and services requested by
+// sibling fields into one query each. They are made per request, so the
+// cache never outlives it.
+type loaders struct {
+	byUser    *dataloader.Loader[uuid.UUID, []service.Subscription]
+	byService *dataloader.Loader[string, []service.Subscription]
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, l loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) loaders {
+	l, _ := ctx.Value(loadersKey{}).(loaders)
+	return l
+}
+
+// clear drops the cached rows after a mutation changed them.
+func (l loaders) clear() {
+	l.byUser.ClearAll()
+	l.byService.ClearAll()
+}
+
+func newLoaders(svc *service.SubscriptionService, tenantID uuid.UUID) loaders {
+	return loaders{
+		byUser: dataloader.NewBatchedLoader(func(ctx context.Context, ids []uuid.UUID) []*dataloader.Result[[]service.Subscription] {
+			subs, err := svc.ListByUsers(ctx, tenantID, ids)
+			return group(ids, subs, err, func(sub service.Subscription) []uuid.UUID {
+				users := []uuid.UUID{sub.UserID}
+				for _, m := range sub.Members {
+					if !m.JoinedAt.IsZero() {
+						users = append(users, m.UserID)
+					}
+				}
+				return users
+			})
+		}),
+		byService: dataloader.NewBatchedLoader(func(ctx context.Context, names []string) []*dataloader.Result[[]service.Subscription] {
+			subs, err := svc.ListByServices(ctx, tenantID, names)
+			return group(names, subs, err, func(sub service.Subscription) []string { return []string{sub.ServiceName} })
+		}),
+	}
+}
+
+// group splits subs by key in the order of keys, as the loader expects. A
+// subscription is in the group of every key it has.
+func group[K comparable](keys []K, subs []service.Subscription, err error, key func(service.Subscription) []K) []*dataloader.Result[[]service.Subscription] {
+	results := make([]*dataloader.Result[[]service.Subscription], len(keys))
+	if err != nil {
+		for i := range results {
+			results[i] = &dataloader.Result[[]service.Subscription]{Error: err}
+		}
+		return results
+	}
+
+	byKey := map[K][]service.Subscription{}
+	for _, sub := range subs {
+		for _, k := range key(sub) {
+			byKey[k] = append(byKey[k], sub)
+		}
+	}
+	for i, k := range keys {
+		results[i] = &dataloader.Result[[]service.Subscription]{Data: byKey[k]}
+	}
+	return results
+}
@@ -0,0 +1,474 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+	"usersubs/internal/subs/service"
+	"usersubs/internal/tenant"
+
+	"github.com/google/uuid"
+	"github.com/graph-gophers/graphql-go"
+)
+
+const dateFormat = "01-2006"
+
+// resolver is the root, it splits queries and mutations because graphql-go
+// takes a `Subscription` method of a single root for subscription operations.
+type resolver struct {
+	svc *service.SubscriptionService
+}
+
+func (r *resolver) Query() *queryResolver {
+	return &queryResolver{r}
+}
+
+func (r *resolver) Mutation() *mutationResolver {
+	return &mutationResolver{r}
+}
+
+type queryResolver struct {
+	*resolver
+}
+
+type mutationResolver struct {
+	*resolver
+}
+
+type filterInput struct {
+	UserID      *graphql.ID
+	ServiceName *string
+	MinPrice    *int32
+	MaxPrice    *int32
+	ActiveFrom  *string
+	ActiveTo    *string
+	Status      *string
+	Category    *string
+	Tag         *string
+}
+
+// filter turns the input into a service filter, a nil input matches all.
+func (f *filterInput) filter() (service.ListFilter, error) {
+	var filter service.ListFilter
+	if f == nil {
+		return filter, nil
+	}
+
+	if f.UserID != nil {
+		id, err := uuid.Parse(string(*f.UserID))
+		if err != nil {
+			return filter, fmt.Errorf("could not parse userId - %v", err)
+		}
+		filter.UserID = id
+	}
+	if f.ServiceName != nil {
+		filter.ServiceName = *f.ServiceName
+	}
+	filter.MinPrice = f.MinPrice
+	filter.MaxPrice = f.MaxPrice
+	if f.ActiveFrom != nil {
+		t, err := parseMonth(*f.ActiveFrom)
+		if err != nil {
+			return filter, err
+		}
+		filter.ActiveFrom = t
+	}
+	if f.ActiveTo != nil {
+		t, err := parseMonth(*f.ActiveTo)
+		if err != nil {
+			return filter, err
+		}
+		filter.ActiveTo = t
+	}
+	if f.Status != nil {
+		status, err := service.ParseStatus(*f.Status)
+		if err != nil {
+			return filter, err
+		}
+		filter.Status = status
+	}
+	if f.Category != nil {
+		filter.Category = *f.Category
+	}
+	if f.Tag != nil {
+		filter.Tag = *f.Tag
+	}
+	return filter, nil
+}
+
+type pageArgs struct {
+	Limit  *int32
+	Offset *int32
+}
+
+type periodArgs struct {
+	From string
+	To   string
+}
+
+func (a periodArgs) parse() (time.Time, time.Time, error) {
+	from, err := parseMonth(a.From)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to, err := parseMonth(a.To)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, errors.New("`to` is before `from`")
+	}
+	return from, to, nil
+}
+
+func (r *queryResolver) Subscription(ctx context.Context, args struct{ ID int32 }) (*subResolver, error) {
+	tenantID, _ := tenant.FromContext(ctx)
+	sub, err := r.svc.Get(ctx, tenantID, args.ID)
+	if errors.Is(err, service.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return &subResolver{sub: sub}, nil
+}
+
+func (r *queryResolver) Subscriptions(ctx context.Context, args struct {
+	Filter *filterInput
+	pageArgs
+}) ([]*subResolver, error) {
+	filter, err := args.Filter.filter()
+	if err != nil {
+		return nil, err
+	}
+	if args.Limit != nil {
+		filter.Limit = *args.Limit
+	}
+	if args.Offset != nil {
+		filter.Offset = *args.Offset
+	}
+
+	tenantID, _ := tenant.FromContext(ctx)
+	subs, err := r.svc.List(ctx, tenantID, filter)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return subResolvers(subs), nil
+}
+
+func (r *queryResolver) User(args struct{ ID graphql.ID }) (*userResolver, error) {
+	id, err := uuid.Parse(string(args.ID))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse id - %v", err)
+	}
+	return &userResolver{id: id}, nil
+}
+
+func (r *queryResolver) Service(args struct{ Name string }) *serviceResolver {
+	return &serviceResolver{name: args.Name}
+}
+
+func (r *queryResolver) TotalCost(ctx context.Context, args struct {
+	periodArgs
+	Filter *filterInput
+}) (int32, error) {
+	from, to, err := args.parse()
+	if err != nil {
+		return 0, err
+	}
+
+	filter, err := args.Filter.filter()
+	if err != nil {
+		return 0, err
+	}
+	if filter.ActiveFrom.IsZero() {
+		filter.ActiveFrom = from
+	}
+	if filter.ActiveTo.IsZero() {
+		filter.ActiveTo = to
+	}
+
+	tenantID, _ := tenant.FromContext(ctx)
+	subs, err := r.svc.List(ctx, tenantID, filter)
+	if err != nil {
+		return 0, serviceError(err)
+	}
+	return toInt(service.TotalCost(subs, from, to))
+}
+
+type subInput struct {
+	ServiceName string
+	Price       int32
+	UserID      graphql.ID
+	StartDate   string
+	EndDate     *string
+	TrialEndsAt *string
+	TrialPrice  *int32
+}
+
+func (in subInput) parse() (service.Subscription, error) {
+	userID, err := uuid.Parse(string(in.UserID))
+	if err != nil {
+		return service.Subscription{}, fmt.Errorf("could not parse userId - %v", err)
+	}
+	start, err := parseMonth(in.StartDate)
+	if err != nil {
+		return service.Subscription{}, err
+	}
+	var end time.Time
+	if in.EndDate != nil && *in.EndDate != "" {
+		end, err = parseMonth(*in.EndDate)
+		if err != nil {
+			return service.Subscription{}, err
+		}
+	}
+	var trialEnd time.Time
+	if in.TrialEndsAt != nil && *in.TrialEndsAt != "" {
+		trialEnd, err = time.Parse(time.RFC3339, *in.TrialEndsAt)
+		if err != nil {
+			return service.Subscription{}, fmt.Errorf("could not parse trialEndsAt %q, expected RFC 3339", *in.TrialEndsAt)
+		}
+	}
+	var trialPrice int32
+	if in.TrialPrice != nil {
+		trialPrice = *in.TrialPrice
+	}
+
+	return service.Subscription{
+		ServiceName: in.ServiceName,
+		Price:       in.Price,
+		UserID:      userID,
+		StartDate:   start,
+		EndDate:     end,
+		TrialEndsAt: trialEnd,
+		TrialPrice:  trialPrice,
+	}, nil
+}
+
+func (r *mutationResolver) CreateSubscription(ctx context.Context, args struct{ Input subInput }) (*subResolver, error) {
+	sub, err := args.Input.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	tenantID, _ := tenant.FromContext(ctx)
+	sub, err = r.svc.Create(ctx, tenantID, sub)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	loadersFrom(ctx).clear()
+	return &subResolver{sub: sub}, nil
+}
+
+func (r *mutationResolver) UpdateSubscription(ctx context.Context, args struct {
+	ID    int32
+	Input subInput
+}) (*subResolver, error) {
+	sub, err := args.Input.parse()
+	if err != nil {
+		return nil, err
+	}
+	sub.ID = args.ID
+
+	tenantID, _ := tenant.FromContext(ctx)
+	sub, err = r.svc.Update(ctx, tenantID, sub)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	loadersFrom(ctx).clear()
+	return &subResolver{sub: sub}, nil
+}
+
+func (r *mutationResolver) DeleteSubscription(ctx context.Context, args struct{ ID int32 }) (int32, error) {
+	tenantID, _ := tenant.FromContext(ctx)
+	id, err := r.svc.Delete(ctx, tenantID, args.ID)
+	if err != nil {
+		return 0, serviceError(err)
+	}
+	loadersFrom(ctx).clear()
+	return id, nil
+}
+
+type subResolver struct {
+	sub service.Subscription
+}
+
+func subResolvers(subs []service.Subscription) []*subResolver {
+	res := make([]*subResolver, 0, len(subs))
+	for _, sub := range subs {
+		res = append(res, &subResolver{sub: sub})
+	}
+	return res
+}
+
+func (s *subResolver) ID() int32           { return s.sub.ID }
+func (s *subResolver) ServiceName() string { return s.sub.ServiceName }
+func (s *subResolver) Price() int32        { return s.sub.Price }
+func (s *subResolver) UserID() graphql.ID  { return graphql.ID(s.sub.UserID.String()) }
+func (s *subResolver) StartDate() string   { return s.sub.StartDate.Format(dateFormat) }
+
+func (s *subResolver) EndDate() *string {
+	if s.sub.EndDate.IsZero() {
+		return nil
+	}
+	end := s.sub.EndDate.Format(dateFormat)
+	return &end
+}
+
+func (s *subResolver) TrialEndsAt() *string {
+	if s.sub.TrialEndsAt.IsZero() {
+		return nil
+	}
+	end := s.sub.TrialEndsAt.Format(time.RFC3339)
+	return &end
+}
+
+func (s *subResolver) FirstChargeAt() string {
+	return s.sub.FirstChargeAt().Format(time.RFC3339)
+}
+
+func (s *subResolver) TrialPrice() int32 { return s.sub.TrialPrice }
+func (s *subResolver) Charge() int32     { return s.sub.Charge }
+
+func (s *subResolver) Status() string {
+	return string(s.sub.Status)
+}
+
+func (s *subResolver) Categories() []string {
+	return append([]string{}, s.sub.Categories...)
+}
+
+func (s *subResolver) Tags() []string {
+	return append([]string{}, s.sub.Tags...)
+}
+
+func (s *subResolver) User() *userResolver {
+	return &userResolver{id: s.sub.UserID}
+}
+
+func (s *subResolver) Service() *serviceResolver {
+	return &serviceResolver{name: s.sub.ServiceName}
+}
+
+func (s *subResolver) TotalCost(args periodArgs) (int32, error) {
+	from, to, err := args.parse()
+	if err != nil {
+		return 0, err
+	}
+	return toInt(s.sub.Cost(from, to))
+}
+
+type userResolver struct {
+	id uuid.UUID
+}
+
+func (u *userResolver) ID() graphql.ID {
+	return graphql.ID(u.id.String())
+}
+
+func (u *userResolver) Subscriptions(ctx context.Context, args struct {
+	Limit         *int32
+	Offset        *int32
+	IncludeShared bool
+}) ([]*subResolver, error) {
+	subs, err := loadersFrom(ctx).byUser.Load(ctx, u.id)()
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	if !args.IncludeShared {
+		// The loader also returns the subscriptions shared with the user.
+		var owned []service.Subscription
+		for _, sub := range subs {
+			if sub.UserID == u.id {
+				owned = append(owned, sub)
+			}
+		}
+		subs = owned
+	}
+	return subResolvers(page(subs, pageArgs{Limit: args.Limit, Offset: args.Offset})), nil
+}
+
+func (u *userResolver) TotalCost(ctx context.Context, args periodArgs) (int32, error) {
+	from, to, err := args.parse()
+	if err != nil {
+		return 0, err
+	}
+	subs, err := loadersFrom(ctx).byUser.Load(ctx, u.id)()
+	if err != nil {
+		return 0, serviceError(err)
+	}
+	return toInt(service.UserCost(subs, u.id, from, to))
+}
+
+type serviceResolver struct {
+	name string
+}
+
+func (s *serviceResolver) Name() string {
+	return s.name
+}
+
+func (s *serviceResolver) Subscriptions(ctx context.Context, args pageArgs) ([]*subResolver, error) {
+	subs, err := loadersFrom(ctx).byService.Load(ctx, s.name)()
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return subResolvers(page(subs, args)), nil
+}
+
+func (s *serviceResolver) TotalCost(ctx context.Context, args periodArgs) (int32, error) {
+	from, to, err := args.parse()
+	if err != nil {
+		return 0, err
+	}
+	subs, err := loadersFrom(ctx).byService.Load(ctx, s.name)()
+	if err != nil {
+		return 0, serviceError(err)
+	}
+	return toInt(service.TotalCost(subs, from, to))
+}
+
+// toInt fits a total into the 32 bit Int of GraphQL, larger totals are an
+// error instead of wrapping around.
+func toInt(total int64) (int32, error) {
+	if total > math.MaxInt32 || total < math.MinInt32 {
+		return 0, fmt.Errorf("total cost %d does not fit in Int, use a shorter period or a filter", total)
+	}
+	return int32(total), nil
+}
+
+// page applies limit and offset to subscriptions already loaded in batch.
+func page(subs []service.Subscription, args pageArgs) []service.Subscription {
+	if args.Offset != nil && *args.Offset > 0 {
+		subs = subs[min(int(*args.Offset), len(subs)):]
+	}
+	if args.Limit != nil && *args.Limit >= 0 {
+		subs = subs[:min(int(*args.Limit), len(subs))]
+	}
+	return subs
+}
+
+func parseMonth(s string) (time.Time, error) {
+	t, err := time.Parse(dateFormat, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse month %q, expected MM-YYYY", s)
+	}
+	return t, nil
+}
+
+// serviceError keeps the messages of the REST API and hides the details of
+// unexpected errors, which are logged instead.
+func serviceError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalid), errors.Is(err, service.ErrNotFound), errors.Is(err, service.ErrConflict):
+		return err
+	case errors.Is(err, context.DeadlineExceeded):
+		return errors.New("sql query timed out")
+	default:
+		slog.Error("Error: something went wrong on sql query", "error", err)
+		return errors.New("something went wrong on sql query")
+	}
+}
@@ -0,0 +1,139 @@
+package gql
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+	"usersubs/internal/subs/service"
+
+	"github.com/google/uuid"
+)
+
+func date(year int, month time.Month) time.Time {
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPeriodArgs(t *testing.T) {
+	if _, _, err := (periodArgs{From: "03-2025", To: "01-2025"}).parse(); err == nil {
+		t.Error("parse accepted to before from")
+	}
+	if _, _, err := (periodArgs{From: "2025-01", To: "03-2025"}).parse(); err == nil {
+		t.Error("parse accepted 2025-01")
+	}
+	from, to, err := periodArgs{From: "01-2025", To: "03-2025"}.parse()
+	if err != nil || !from.Equal(date(2025, time.January)) || !to.Equal(date(2025, time.March)) {
+		t.Errorf("parse = %v, %v, %v", from, to, err)
+	}
+}
+
+func TestPage(t *testing.T) {
+	subs := []service.Subscription{{ID: 1}, {ID: 2}, {ID: 3}}
+	n := func(v int32) *int32 { return &v }
+
+	tests := []struct {
+		args pageArgs
+		want []int32
+	}{
+		{args: pageArgs{}, want: []int32{1, 2, 3}},
+		{args: pageArgs{Limit: n(2)}, want: []int32{1, 2}},
+		{args: pageArgs{Offset: n(1)}, want: []int32{2, 3}},
+		{args: pageArgs{Limit: n(1), Offset: n(1)}, want: []int32{2}},
+		{args: pageArgs{Offset: n(5)}, want: []int32{}},
+		{args: pageArgs{Limit: n(0)}, want: []int32{}},
+	}
+	for _, tt := range tests {
+		ids := []int32{}
+		for _, sub := range page(subs, tt.args) {
+			ids = append(ids, sub.ID)
+		}
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("page(%+v) = %v, want %v", tt.args, ids, tt.want)
+		}
+	}
+}
+
+// group answers every key in order, with nil for keys without subscriptions,
+// and puts a subscription in the group of each of its keys.
+func TestGroup(t *testing.T) {
+	alice, bob, carol, dave := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	subs := []service.Subscription{
+		{ID: 1, UserID: bob},
+		{ID: 2, UserID: alice, Members: []service.Member{{UserID: bob}}},
+		{ID: 3, UserID: bob, Members: []service.Member{{UserID: carol}}},
+	}
+	key := func(sub service.Subscription) []uuid.UUID {
+		keys := []uuid.UUID{sub.UserID}
+		for _, m := range sub.Members {
+			keys = append(keys, m.UserID)
+		}
+		return keys
+	}
+
+	results := group([]uuid.UUID{alice, bob, carol, dave}, subs, nil, key)
+	want := [][]int32{{2}, {1, 2, 3}, {3}, nil}
+	for i, res := range results {
+		var ids []int32
+		for _, sub := range res.Data {
+			ids = append(ids, sub.ID)
+		}
+		if res.Error != nil || !slices.Equal(ids, want[i]) {
+			t.Errorf("result %d = %v, %v, want %v", i, ids, res.Error, want[i])
+		}
+	}
+
+	failed := errors.New("connection reset")
+	for i, res := range group([]uuid.UUID{alice, bob}, nil, failed, key) {
+		if res.Error != failed {
+			t.Errorf("result %d error = %v, want %v", i, res.Error, failed)
+		}
+	}
+}
+
+func TestToInt(t *testing.T) {
+	tests := []struct {
+		total   int64
+		want    int32
+		wantErr bool
+	}{
+		{total: 0, want: 0},
+		{total: math.MaxInt32, want: math.MaxInt32},
+		{total: math.MaxInt32 + 1, wantErr: true},
+		{total: math.MinInt32 - 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := toInt(tt.total)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("toInt(%d) error = %v, wantErr %v", tt.total, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("toInt(%d) = %d, want %d", tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestSubTotalCostOverflow(t *testing.T) {
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	s := &subResolver{sub: service.Subscription{
+		ServiceName: "Enterprise",
+		Price:       math.MaxInt32 / 2,
+		UserID:      uuid.New(),
+		StartDate:   start,
+		Status:      service.StatusActive,
+	}}
+
+	if _, err := s.TotalCost(periodArgs{From: "01-2025", To: "02-2025"}); err != nil {
+		t.Errorf("two months error = %v, want none", err)
+	}
+	if got, err := s.TotalCost(periodArgs{From: "01-2025", To: "03-2025"}); err == nil {
+		t.Errorf("three months = %d, want an overflow error", got)
+	}
+}
+
+// NewHandler panics when the schema does not match the resolvers.
+func TestSchemaMatchesResolvers(t *testing.T) {
+	NewHandler(nil)
+}
@@ -0,0 +1,154 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+	"usersubs/internal/utils"
+)
+
+const checkTimeout = 2 * time.Second
+
+type checkJSON struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Detail any    `json:"detail,omitempty"`
+}
+
+type reportJSON struct {
+	Status string               `json:"status"`
+	Checks map[string]checkJSON `json:"checks,omitempty"`
+}
+
+// Checker serves the probes. SchemaVersion is the goose migration version
+// the binary expects the database to be at.
+type Checker struct {
+	DB            *sql.DB
+	SchemaVersion int64
+
+	started  time.Time
+	draining atomic.Bool
+}
+
+func NewChecker(sqlDB *sql.DB, schemaVersion int64) *Checker {
+	return &Checker{DB: sqlDB, SchemaVersion: schemaVersion, started: time.Now()}
+}
+
+// SetDraining makes readiness fail, so no new traffic is routed to the
+// instance while it shuts down.
+func (c *Checker) SetDraining(draining bool) {
+	c.draining.Store(draining)
+}
+
+// @Summary Healthz
+// @Description Process is alive
+// @Produce json
+// @Router /healthz [GET]
+func (c *Checker) Healthz(w http.ResponseWriter, r *http.Request) {
+	sendReport(w, reportJSON{Status: "ok"})
+}
+
+// @Summary Livez
+// @Description Liveness probe with process details
+// @Produce json
+// @Router /livez [GET]
+func (c *Checker) Livez(w http.ResponseWriter, r *http.Request) {
+	sendReport(w, reportJSON{
+		Status: "ok",
+		Checks: map[string]checkJSON{
+			"process": {
+				Status: "ok",
+				Detail: map[string]any{
+					"uptime_seconds": int64(time.Since(c.started).Seconds()),
+					"goroutines":     runtime.NumGoroutine(),
+				},
+			},
+		},
+	})
+}
+
+// @Summary Readyz
+// @Description Readiness probe: database is reachable and migrated, instance is not draining
+// @Produce json
+// @Router /readyz [GET]
+func (c *Checker) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	report := reportJSON{Status: "ok", Checks: map[string]checkJSON{}}
+	add := func(name string, detail any, err error) {
+		check := checkJSON{Status: "ok", Detail: detail}
+		if err != nil {
+			check.Status = "fail"
+			check.Error = err.Error()
+			report.Status = "fail"
+		}
+		report.Checks[name] = check
+	}
+
+	var drainErr error
+	if c.draining.Load() {
+		drainErr = errors.New("instance is shutting down")
+	}
+	add("draining", nil, drainErr)
+
+	add("database", nil, c.DB.PingContext(ctx))
+
+	version, err := c.migrationVersion(ctx)
+	add("migrations", map[string]int64{"current": version, "expected": c.SchemaVersion}, err)
+
+	sendReport(w, report)
+}
+
+// migrationVersion reads the version the way goose does: a rolled back
+// migration stays in the log with a newer row that is not applied, the
+// version is the newest one whose latest row is applied.
+func (c *Checker) migrationVersion(ctx context.Context) (int64, error) {
+	rows, err := c.DB.QueryContext(ctx, "SELECT version_id, is_applied FROM goose_db_version ORDER BY id DESC")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var version int64
+	rolledBack := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		var applied bool
+		if err := rows.Scan(&id, &applied); err != nil {
+			return 0, err
+		}
+		if rolledBack[id] {
+			continue
+		}
+		if applied {
+			version = id
+			break
+		}
+		rolledBack[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if version != c.SchemaVersion {
+		return version, fmt.Errorf("database is at version %d, expected %d", version, c.SchemaVersion)
+	}
+	return version, nil
+}
+
+func sendReport(w http.ResponseWriter, report reportJSON) {
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+
+	if err := utils.SendData(w, report, status); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+	}
+}
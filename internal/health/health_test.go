@@ -0,0 +1,159 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeDB answers the version query with Log, newest row first, and fails
+// pings with PingErr.
+type fakeDB struct {
+	Log     []logRow
+	PingErr error
+}
+
+// logRow is a row of goose_db_version.
+type logRow struct {
+	version int64
+	applied bool
+}
+
+var fakeDBs = map[string]*fakeDB{}
+
+func init() {
+	sql.Register("healthfake", fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return fakeConn{fakeDBs[name]}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c fakeConn) Close() error { return nil }
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c fakeConn) Ping(ctx context.Context) error {
+	return c.db.PingErr
+}
+
+func (c fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.db.PingErr != nil {
+		return nil, c.db.PingErr
+	}
+	return &logRows{rows: c.db.Log}, nil
+}
+
+type logRows struct {
+	rows []logRow
+}
+
+func (r *logRows) Columns() []string { return []string{"version_id", "is_applied"} }
+
+func (r *logRows) Close() error { return nil }
+
+func (r *logRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	dest[0], dest[1] = r.rows[0].version, r.rows[0].applied
+	r.rows = r.rows[1:]
+	return nil
+}
+
+var fakeDBCount atomic.Int64
+
+func openFake(t *testing.T, db *fakeDB) *sql.DB {
+	t.Helper()
+	name := strconv.FormatInt(fakeDBCount.Add(1), 10)
+	fakeDBs[name] = db
+	sqlDB, err := sql.Open("healthfake", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return sqlDB
+}
+
+func serve(t *testing.T, h http.HandlerFunc) (int, reportJSON) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var res struct {
+		Data reportJSON `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("body %q - %v", w.Body, err)
+	}
+	return w.Code, res.Data
+}
+
+func TestHealthzLivez(t *testing.T) {
+	c := NewChecker(nil, 3)
+	if code, report := serve(t, c.Healthz); code != http.StatusOK || report.Status != "ok" {
+		t.Errorf("Healthz() = %d %+v", code, report)
+	}
+	if code, report := serve(t, c.Livez); code != http.StatusOK || report.Checks["process"].Status != "ok" {
+		t.Errorf("Livez() = %d %+v", code, report)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name       string
+		db         fakeDB
+		draining   bool
+		wantCode   int
+		wantFailed []string
+	}{
+		{name: "ready", db: fakeDB{Log: []logRow{{3, true}, {2, true}, {1, true}}}, wantCode: http.StatusOK},
+		{name: "draining", db: fakeDB{Log: []logRow{{3, true}}}, draining: true, wantCode: http.StatusServiceUnavailable, wantFailed: []string{"draining"}},
+		{name: "behind", db: fakeDB{Log: []logRow{{2, true}, {1, true}}}, wantCode: http.StatusServiceUnavailable, wantFailed: []string{"migrations"}},
+		{name: "rolled back", db: fakeDB{Log: []logRow{{3, false}, {3, true}, {2, true}}}, wantCode: http.StatusServiceUnavailable, wantFailed: []string{"migrations"}},
+		{name: "applied again", db: fakeDB{Log: []logRow{{3, true}, {3, false}, {3, true}, {2, true}}}, wantCode: http.StatusOK},
+		{name: "empty", wantCode: http.StatusServiceUnavailable, wantFailed: []string{"migrations"}},
+		{name: "unreachable", db: fakeDB{PingErr: errors.New("connection refused")}, wantCode: http.StatusServiceUnavailable, wantFailed: []string{"database", "migrations"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(openFake(t, &tt.db), 3)
+			c.SetDraining(tt.draining)
+
+			code, report := serve(t, c.Readyz)
+			if code != tt.wantCode {
+				t.Errorf("code = %d, want %d", code, tt.wantCode)
+			}
+			failed := map[string]bool{}
+			for _, name := range tt.wantFailed {
+				failed[name] = true
+			}
+			for name, check := range report.Checks {
+				if (check.Status == "fail") != failed[name] {
+					t.Errorf("check %s = %+v", name, check)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+	"usersubs/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader is accepted from clients and echoed in every response.
+const RequestIDHeader = "X-Request-ID"
+
+// LogBodies enables logging of response bodies on debug level. Bodies may
+// contain personal data, so it is off by default.
+var LogBodies bool
+
+// Setup makes a JSON logger the default one. Level is one of `debug`,
+// `info`, `warn` or `error`.
+func Setup(level string) error {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return fmt.Errorf("LOGGING - unknown log level %q - %w", level, err)
+		}
+	}
+
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: lvl})))
+	return nil
+}
+
+// HashUser hides a user ID in logs while keeping it comparable between
+// records.
+func HashUser(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:8])
+}
+
+type ctxKey struct{}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+type userKey struct{}
+
+// user is filled in by SetUser. Middlewares calling r.WithContext pass a
+// copy of the request to the mux, so the path values are not read here.
+type user struct {
+	id string
+}
+
+// SetUser records the user a request acts on for its log record, handlers
+// call it with the user of a path value or of the subscription they serve.
+// The `user_id` query parameter is logged when it is not called.
+func SetUser(ctx context.Context, id string) {
+	if u, ok := ctx.Value(userKey{}).(*user); ok {
+		u.id = id
+	}
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Middleware assigns a request ID and logs every request after it is
+// served. It should be wrapped by tracing.Middleware, which keeps the route
+// pattern.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		rw := &responseWriter{ResponseWriter: w}
+		u := &user{id: r.URL.Query().Get("user_id")}
+		ctx := context.WithValue(r.Context(), ctxKey{}, id)
+		r = r.WithContext(context.WithValue(ctx, userKey{}, u))
+		next.ServeHTTP(rw, r)
+
+		attrs := []any{
+			"request_id", id,
+			"method", r.Method,
+			"route", tracing.Route(r.Context()),
+			"status", rw.status,
+			"latency_ms", time.Since(start).Milliseconds(),
+			"bytes", rw.bytes,
+		}
+		if traceID := tracing.TraceID(r.Context()); traceID != "" {
+			attrs = append(attrs, "trace_id", traceID)
+		}
+		if u.id != "" {
+			attrs = append(attrs, "user", HashUser(u.id))
+		}
+
+		slog.Info("request", attrs...)
+	})
+}
@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"usersubs/internal/tracing"
+)
+
+// captureLogs sends the default logger to a buffer for the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func TestMiddleware(t *testing.T) {
+	long := strings.Repeat("x", 129)
+	tests := []struct {
+		name       string
+		target     string
+		requestID  string
+		wantID     string
+		wantStatus int
+		wantUser   string
+	}{
+		{name: "client id", target: "/api/subs/7", requestID: "abc", wantID: "abc", wantStatus: http.StatusNoContent},
+		{name: "generated id", target: "/api/subs/7", wantStatus: http.StatusNoContent},
+		{name: "id too long", target: "/api/subs/7", requestID: long, wantStatus: http.StatusNoContent},
+		{name: "user from query", target: "/api/subs?user_id=u1", wantStatus: http.StatusOK, wantUser: HashUser("u1")},
+		{name: "user from handler", target: "/api/subs/7/members/u2?user_id=u1", wantStatus: http.StatusNoContent, wantUser: HashUser("u2")},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/subs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if RequestID(r.Context()) == "" {
+			t.Error("no request ID on the context")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /api/subs/{id}/members/{user_id}", func(w http.ResponseWriter, r *http.Request) {
+		SetUser(r.Context(), r.PathValue("user_id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /api/subs", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t)
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.requestID != "" {
+				r.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			tracing.Middleware(Middleware(tracing.RecordRoute(mux))).ServeHTTP(w, r)
+
+			id := w.Header().Get(RequestIDHeader)
+			if id == "" || id == long || (tt.wantID != "" && id != tt.wantID) {
+				t.Errorf("%s = %q, want %q or a new one", RequestIDHeader, id, tt.wantID)
+			}
+
+			var record struct {
+				RequestID string `json:"request_id"`
+				Route     string `json:"route"`
+				Status    int    `json:"status"`
+				User      string `json:"user"`
+			}
+			if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+				t.Fatalf("log %q - %v", logs, err)
+			}
+			if record.RequestID != id || record.Status != tt.wantStatus || record.User != tt.wantUser || !strings.HasPrefix(record.Route, "GET /api/subs") {
+				t.Errorf("logged %+v", record)
+			}
+		})
+	}
+}
+
+func TestHashUser(t *testing.T) {
+	a, b := HashUser("60601fee-2bf1-4721-ae6f-7636e79a0cba"), HashUser("60601fee-2bf1-4721-ae6f-7636e79a0cbb")
+	if a == b || len(a) != 16 || strings.Contains(a, "60601fee") {
+		t.Errorf("HashUser() = %q and %q", a, b)
+	}
+	if HashUser("u1") != HashUser("u1") {
+		t.Error("HashUser() is not stable")
+	}
+}
+
+func TestSetup(t *testing.T) {
+	prev := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	if err := Setup("debug"); err != nil {
+		t.Errorf("Setup(debug) error = %v", err)
+	}
+	if err := Setup("verbose"); err == nil {
+		t.Error("Setup(verbose) accepted an unknown level")
+	}
+}
@@ -1,34 +1,103 @@
 package internal
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	"usersubs/internal/auth"
+	"usersubs/internal/clock"
+	"usersubs/internal/config"
+	"usersubs/internal/cors"
 	"usersubs/internal/db"
+	"usersubs/internal/gql"
+	"usersubs/internal/health"
+	"usersubs/internal/logging"
+	"usersubs/internal/metrics"
+	"usersubs/internal/migrate"
+	"usersubs/internal/ratelimit"
+	"usersubs/internal/rpc"
+	subsv1 "usersubs/internal/rpc/subs/v1"
 	"usersubs/internal/subs"
+	"usersubs/internal/subs/service"
+	"usersubs/internal/tenant"
+	"usersubs/internal/tracing"
 
-	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 	"github.com/swaggo/http-swagger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	grpchealth "google.golang.org/grpc/health"
 	_ "usersubs/docs"
 )
 
-func Start() error {
-	log.SetPrefix("[INFO]: ")
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Error: something wrong with getting .env - %v\n", err)
-		log.Printf("Trying to get environment variables not from .env\n")
+// Start runs the server with the command line args (without the program
+// name), or the `migrate` subcommand when args start with it.
+func Start(args []string) error {
+	if len(args) > 0 && args[0] == "migrate" {
+		return runMigrate(args[1:])
 	}
 
-	query, err := startDB()
+	cfg, printOnly, err := config.Load(args)
+	if printOnly {
+		if err := cfg.Print(os.Stdout); err != nil {
+			return err
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if err := cfg.ValidateServer(); err != nil {
+		return err
+	}
+
+	if err := logging.Setup(cfg.Log.Level); err != nil {
+		return err
+	}
+	logging.LogBodies = cfg.Log.Bodies
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter, cfg.Tracing.File)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("Could not flush traces", "error", err)
+		}
+	}()
+
+	sqlDB, err := startDB(cfg.DB, cfg.DB.Connection)
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.DB.AutoMigrate {
+		if err := autoMigrate(ctx, cfg.DB, sqlDB); err != nil {
+			return err
+		}
+	}
+
+	bypass, err := tenant.BypassesRLS(ctx, sqlDB)
 	if err != nil {
 		return err
 	}
+	if bypass {
+		slog.Warn("DB role bypasses row level security, connect as a member of usersubs_app")
+	}
 
-	err = startServer(query)
+	err = startServer(ctx, cfg, sqlDB)
 	if err != nil {
 		return err
 	}
@@ -36,45 +105,311 @@ func Start() error {
 	return nil
 }
 
-func startDB() (*db.Queries, error) {
-	connection, exist := os.LookupEnv("DB_CONNECTION")
-	if !exist {
-		return nil, errors.New("Error: cant get field DB_CONNECTION from .env")
+// runMigrate handles `migrate up|down|status|to N [flags]`, the flags are
+// the same as for the server.
+func runMigrate(args []string) error {
+	command, flags := args, []string(nil)
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			command, flags = args[:i], args[i:]
+			break
+		}
 	}
 
+	cfg, _, err := config.Load(flags)
+	if err != nil {
+		return err
+	}
+
+	sqlDB, err := startDB(cfg.DB, migrateConnection(cfg.DB))
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	return migrate.Run(context.Background(), sqlDB, command, os.Stdout)
+}
+
+// autoMigrate applies pending migrations with db.migrate_connection, or with
+// the server connection when it is not set.
+func autoMigrate(ctx context.Context, cfg config.DB, sqlDB *sql.DB) error {
+	if cfg.MigrateConnection != "" {
+		ownerDB, err := startDB(cfg, cfg.MigrateConnection)
+		if err != nil {
+			return err
+		}
+		defer ownerDB.Close()
+		sqlDB = ownerDB
+	}
+
+	results, err := migrate.Up(ctx, sqlDB)
+	if err != nil {
+		return err
+	}
+	slog.Info("Migrations applied", "count", len(results))
+	return nil
+}
+
+func migrateConnection(cfg config.DB) string {
+	if cfg.MigrateConnection != "" {
+		return cfg.MigrateConnection
+	}
+	return cfg.Connection
+}
+
+func startDB(cfg config.DB, connection string) (*sql.DB, error) {
 	sqlDB, err := sql.Open("postgres", connection)
+	if err != nil {
+		return nil, fmt.Errorf("CONNECT DB - something went wrong - %v", err)
+	}
+
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 
 	err = sqlDB.Ping()
 	if err != nil {
 		return nil, fmt.Errorf("CONNECT DB - something went wrong - %v", err)
 	}
 
-	return db.New(sqlDB), nil
+	return sqlDB, nil
+}
+
+// routeLimits are stricter for destructive and bulk routes, gRPC methods
+// get the limits of the matching routes.
+var routeLimits = map[string]ratelimit.Limit{
+	"DELETE /api/subs":                       {Rate: 1.0 / 6, Burst: 5},
+	"DELETE /api/sub/{id}":                   {Rate: 1, Burst: 10},
+	"POST /api/services/{name}/price-change": {Rate: 1.0 / 6, Burst: 5},
+
+	subsv1.SubscriptionService_DeleteUserSubscriptions_FullMethodName: {Rate: 1.0 / 6, Burst: 5},
+	subsv1.SubscriptionService_DeleteSubscription_FullMethodName:      {Rate: 1, Burst: 10},
 }
 
-func startServer(query *db.Queries) error {
-	port, exist := os.LookupEnv("SERVER_PORT")
-	if !exist {
-		return errors.New("Error: cant get field PORT from .env")
+// rateLimitStore picks the store, `memory` keeps limits per instance and
+// `postgres` shares them between instances.
+func rateLimitStore(ctx context.Context, store string, sqlDB *sql.DB) ratelimit.Store {
+	if store == "postgres" {
+		store := ratelimit.PostgresStore{Repo: db.New(tracing.WrapDB(metrics.WrapDB(sqlDB)))}
+		go store.Sweep(ctx, 10*time.Minute)
+		return store
 	}
+	return ratelimit.NewMemoryStore()
+}
 
+func startServer(ctx context.Context, cfg config.Config, sqlDB *sql.DB) error {
 	mux := http.NewServeMux()
+	policy := cors.Policy{
+		AllowedOrigins: cfg.CORS.AllowedOrigins,
+		AllowedMethods: cfg.CORS.AllowedMethods,
+		AllowedHeaders: cfg.CORS.AllowedHeaders,
+	}
 	server := http.Server{
-		Addr:    ":" + port,
-		Handler: mux,
+		Addr:         cfg.Server.Addr,
+		Handler:      tracing.Middleware(logging.Middleware(metrics.Middleware(policy.Middleware(tracing.RecordRoute(mux))))),
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+
+	var clk clock.Clock = clock.System{}
+	if cfg.Debug.FakeNow != "" {
+		now, err := clock.Parse(cfg.Debug.FakeNow)
+		if err != nil {
+			return err
+		}
+		clk = clock.NewFake(now)
+		slog.Warn("Clock is fixed by debug.fake_now", "now", now)
+	}
+	if cfg.Debug.FakeNowHeader {
+		slog.Warn("Requests can set the clock with X-Fake-Now, debug only")
+	}
+
+	subsService := &service.SubscriptionService{
+		Repo:         service.PostgresRepository{DB: sqlDB},
+		Events:       service.LogPublisher{},
+		QueryTimeout: cfg.DB.QueryTimeout,
+		Clock:        clk,
+	}
+	handler := subs.SubsHandler{Service: subsService}
+	tenants := tenant.Resolver{Repo: db.New(tracing.WrapDB(metrics.WrapDB(sqlDB))), Timeout: cfg.DB.QueryTimeout}
+	apiKeys, err := auth.ParseKeys(cfg.Auth.APIKeys)
+	if err != nil {
+		return err
+	}
+	keys := auth.APIKeys{Keys: apiKeys}
+	if len(apiKeys) == 0 {
+		slog.Warn("API is open, any caller can pick an organization with X-Tenant-ID")
+	}
+
+	limiter := ratelimit.Limiter{
+		Store:       rateLimitStore(ctx, cfg.RateLimit.Store, sqlDB),
+		Default:     ratelimit.Limit{Rate: cfg.RateLimit.Rate, Burst: cfg.RateLimit.Burst},
+		RouteLimits: routeLimits,
+		TrustProxy:  cfg.Server.TrustProxy,
+		Identify:    keys.Identify,
+	}
+
+	api := func(h http.HandlerFunc) http.Handler {
+		handler := keys.Middleware(tenants.Middleware(h))
+		if cfg.Debug.FakeNowHeader {
+			handler = clock.Middleware(handler)
+		}
+		if cfg.Features.RateLimit {
+			handler = limiter.Middleware(handler)
+		}
+		return handler
+	}
+
+	mux.Handle("GET /api/subs", api(handler.GetSubs))
+	mux.Handle("GET /api/subs/ending-trials", api(handler.GetEndingTrials))
+	mux.Handle("GET /api/subs/expiring-discounts", api(handler.GetExpiringDiscounts))
+	mux.Handle("GET /api/subs/spend-by-category", api(handler.GetSpendByCategory))
+	mux.Handle("GET /api/sub/{id}", api(handler.GetSub))
+	mux.Handle("POST /api/sub", api(handler.PostSub))
+	mux.Handle("PUT /api/sub/{id}", api(handler.PutSub))
+	mux.Handle("PATCH /api/sub/{id}", api(handler.PatchSub))
+	mux.Handle("DELETE /api/sub/{id}", api(handler.DeleteSub))
+	mux.Handle("DELETE /api/subs", api(handler.DeleteUserSubs))
+	mux.Handle("POST /api/sub/{id}/pause", api(handler.PauseSub))
+	mux.Handle("POST /api/sub/{id}/resume", api(handler.ResumeSub))
+	mux.Handle("POST /api/sub/{id}/cancel", api(handler.CancelSub))
+	mux.Handle("POST /api/sub/{id}/reactivate", api(handler.ReactivateSub))
+	mux.Handle("GET /api/sub/{id}/history", api(handler.GetSubHistory))
+	mux.Handle("POST /api/sub/{id}/pauses", api(handler.PostPause))
+	mux.Handle("GET /api/sub/{id}/pauses", api(handler.GetPauses))
+	mux.Handle("DELETE /api/sub/{id}/pauses/{pause_id}", api(handler.DeletePause))
+	mux.Handle("POST /api/sub/{id}/prices", api(handler.PostPrice))
+	mux.Handle("GET /api/sub/{id}/prices", api(handler.GetPrices))
+	mux.Handle("POST /api/sub/{id}/discounts", api(handler.PostDiscount))
+	mux.Handle("GET /api/sub/{id}/discounts", api(handler.GetDiscounts))
+	mux.Handle("DELETE /api/sub/{id}/discounts/{discount_id}", api(handler.DeleteDiscount))
+	mux.Handle("POST /api/sub/{id}/members", api(handler.PostMember))
+	mux.Handle("POST /api/sub/{id}/invites", api(handler.PostInvite))
+	mux.Handle("POST /api/sub/{id}/invites/{user_id}/accept", api(handler.AcceptInvite))
+	mux.Handle("GET /api/sub/{id}/members", api(handler.GetMembers))
+	mux.Handle("DELETE /api/sub/{id}/members/{user_id}", api(handler.DeleteMember))
+	mux.Handle("POST /api/users/{id}/budgets", api(handler.PostBudget))
+	mux.Handle("GET /api/users/{id}/budgets", api(handler.GetBudgets))
+	mux.Handle("DELETE /api/users/{id}/budgets/{budget_id}", api(handler.DeleteBudget))
+	mux.Handle("GET /api/users/{id}/budget-status", api(handler.GetBudgetStatus))
+	mux.Handle("POST /api/categories", api(handler.PostCategory))
+	mux.Handle("GET /api/categories", api(handler.GetCategories))
+	mux.Handle("DELETE /api/categories/{category_id}", api(handler.DeleteCategory))
+	mux.Handle("GET /api/tags", api(handler.GetTags))
+	mux.Handle("POST /api/sub/{id}/categories", api(handler.PostSubCategory))
+	mux.Handle("DELETE /api/sub/{id}/categories/{name}", api(handler.DeleteSubCategory))
+	mux.Handle("POST /api/sub/{id}/tags", api(handler.PostSubTag))
+	mux.Handle("DELETE /api/sub/{id}/tags/{name}", api(handler.DeleteSubTag))
+	mux.Handle("POST /api/services/{name}/price-change", api(handler.PostServicePriceChange))
+
+	if cfg.Features.GraphQL {
+		mux.Handle("POST /graphql", api(gql.NewHandler(subsService).ServeHTTP))
+	}
+
+	schemaVersion, err := migrate.LatestVersion()
+	if err != nil {
+		return err
+	}
+	checker := health.NewChecker(sqlDB, schemaVersion)
+	mux.HandleFunc("GET /healthz", checker.Healthz)
+	mux.HandleFunc("GET /livez", checker.Livez)
+	mux.HandleFunc("GET /readyz", checker.Readyz)
+
+	if cfg.Features.Metrics {
+		metrics.RegisterDB(sqlDB)
+		mux.Handle("GET /metrics", metrics.Handler())
+	}
+	if cfg.Features.Swagger {
+		mux.HandleFunc("GET /swagger/", httpSwagger.Handler(httpSwagger.URL("/swagger/doc.json")))
 	}
 
-	handler := subs.SubsHandler{SubsRepo: query}
+	serverErr := make(chan error, 2)
 
-	mux.HandleFunc("GET /api/subs", handler.GetSubs)
-	mux.HandleFunc("GET /api/sub/{id}", handler.GetSub)
-	mux.HandleFunc("POST /api/sub", handler.PostSub)
-	mux.HandleFunc("PUT /api/sub/{id}", handler.PutSub)
-	mux.HandleFunc("DELETE /api/sub/{id}", handler.DeleteSub)
-	mux.HandleFunc("DELETE /api/subs", handler.DeleteUserSubs)
-	mux.HandleFunc("GET /swagger/", httpSwagger.Handler(httpSwagger.URL(fmt.Sprintf("http://localhost:%s/swagger/doc.json", port))))
+	var (
+		grpcServer *grpc.Server
+		grpcHealth *grpchealth.Server
+	)
+	if cfg.Features.GRPC {
+		opts := rpc.Options{Keys: keys, Tenants: tenants, FakeNow: cfg.Debug.FakeNowHeader}
+		if cfg.Features.RateLimit {
+			opts.Limiter = &limiter
+		}
+		grpcServer, grpcHealth, err = startGRPC(cfg, rpc.SubsServer{Service: subsService}, opts, serverErr)
+		if err != nil {
+			return err
+		}
+	}
+
+	go func() {
+		slog.Info("Server starts", "addr", cfg.Server.Addr, "tls", cfg.Server.TLSCertFile != "")
+		if cfg.Server.TLSCertFile != "" {
+			serverErr <- server.ListenAndServeTLS(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+			return
+		}
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
 
-	log.Printf("Server starts at port: %v\n", port)
-	log.Fatal(server.ListenAndServe())
+	// Readiness fails first, so the load balancer stops sending new requests
+	// before the listener is closed.
+	slog.Info("Server is draining", "timeout", cfg.Server.DrainTimeout.String())
+	checker.SetDraining(true)
+	if grpcHealth != nil {
+		grpcHealth.Shutdown()
+	}
+	time.Sleep(cfg.Server.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.DrainTimeout)
+	defer cancel()
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		defer func() {
+			select {
+			case <-stopped:
+			case <-shutdownCtx.Done():
+				grpcServer.Stop()
+			}
+		}()
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("SHUTDOWN - requests did not finish in time - %v", err)
+	}
+
+	slog.Info("Server stopped")
 	return nil
 }
+
+// startGRPC serves the gRPC API on its own port, with the TLS certificate
+// of the HTTP server when one is set. Serve errors are sent to serverErr.
+func startGRPC(cfg config.Config, subsServer rpc.SubsServer, o rpc.Options, serverErr chan<- error) (*grpc.Server, *grpchealth.Server, error) {
+	var opts []grpc.ServerOption
+	if cfg.Server.TLSCertFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("GRPC - could not load tls certificate - %v", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	listener, err := net.Listen("tcp", cfg.Server.GRPCAddr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("GRPC - could not listen on %s - %v", cfg.Server.GRPCAddr, err)
+	}
+
+	server, health := rpc.NewServer(subsServer, o, opts...)
+	go func() {
+		slog.Info("gRPC server starts", "addr", cfg.Server.GRPCAddr, "tls", cfg.Server.TLSCertFile != "")
+		serverErr <- server.Serve(listener)
+	}()
+	return server, health, nil
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+	"usersubs/internal/db"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+var (
+	activeSubsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "active_subscriptions"),
+		"Number of subscriptions active now.",
+		[]string{"tenant_id"}, nil,
+	)
+	monthlySpendDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "monthly_spend"),
+		"Sum of monthly prices of active subscriptions.",
+		[]string{"tenant_id"}, nil,
+	)
+)
+
+// businessCollector queries the stats on every scrape, in a transaction per
+// tenant since row level security only exposes the rows of one.
+type businessCollector struct {
+	sqlDB *sql.DB
+}
+
+func (c businessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeSubsDesc
+	ch <- monthlySpendDesc
+}
+
+func (c businessCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tenants, err := db.New(WrapDB(c.sqlDB)).GetOrganizationIDs(ctx)
+	if err != nil {
+		slog.Error("METRICS - could not get organizations", "error", err)
+		return
+	}
+
+	for _, tenantID := range tenants {
+		stats, err := c.tenantStats(ctx, tenantID)
+		if err != nil {
+			slog.Error("METRICS - could not get subscription stats", "tenant_id", tenantID, "error", err)
+			return
+		}
+		for _, s := range stats {
+			ch <- prometheus.MustNewConstMetric(activeSubsDesc, prometheus.GaugeValue, float64(s.Active), s.TenantID.String())
+			ch <- prometheus.MustNewConstMetric(monthlySpendDesc, prometheus.GaugeValue, float64(s.MonthlySpend), s.TenantID.String())
+		}
+	}
+}
+
+func (c businessCollector) tenantStats(ctx context.Context, tenantID uuid.UUID) ([]db.GetActiveSubsStatsRow, error) {
+	tx, err := c.sqlDB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	q := db.New(WrapDB(tx))
+	if err := q.SetTenant(ctx, tenantID.String()); err != nil {
+		return nil, err
+	}
+	return q.GetActiveSubsStats(ctx)
+}
+
+// RegisterDB adds the connection pool and the business gauges.
+func RegisterDB(sqlDB *sql.DB) {
+	Registry.MustRegister(
+		collectors.NewDBStatsCollector(sqlDB, "usersubs"),
+		businessCollector{sqlDB: sqlDB},
+	)
+}
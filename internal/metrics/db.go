@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+	"usersubs/internal/db"
+)
+
+// DB measures every query of db.Queries. The query name is taken from the
+// `-- name:` header sqlc puts in front of each query.
+type DB struct {
+	db.DBTX
+}
+
+func WrapDB(dbtx db.DBTX) DB {
+	return DB{DBTX: dbtx}
+}
+
+func (d DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := d.DBTX.ExecContext(ctx, query, args...)
+	observeQuery(query, start, err)
+	return res, err
+}
+
+func (d DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := d.DBTX.QueryContext(ctx, query, args...)
+	observeQuery(query, start, err)
+	return rows, err
+}
+
+func (d DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := d.DBTX.QueryRowContext(ctx, query, args...)
+	observeQuery(query, start, row.Err())
+	return row
+}
+
+func observeQuery(query string, start time.Time, err error) {
+	dbDuration.WithLabelValues(QueryName(query), strconv.FormatBool(err != nil)).Observe(time.Since(start).Seconds())
+}
+
+// QueryName returns the sqlc name of a query or `unknown`.
+func QueryName(query string) string {
+	name, ok := strings.CutPrefix(query, "-- name: ")
+	if !ok {
+		return "unknown"
+	}
+
+	name, _, _ = strings.Cut(name, " ")
+	return name
+}
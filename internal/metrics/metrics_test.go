@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestQueryName(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "-- name: GetSub :one\nSELECT 1", want: "GetSub"},
+		{query: "-- name: ListSubs :many\nSELECT 1", want: "ListSubs"},
+		{query: "SELECT 1", want: "unknown"},
+		{query: "", want: "unknown"},
+	}
+	for _, tt := range tests {
+		if got := QueryName(tt.query); got != tt.want {
+			t.Errorf("QueryName(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func count(t *testing.T, c prometheus.Counter) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := c.Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestMiddleware(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics-test/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	mux.HandleFunc("GET /metrics-test", func(w http.ResponseWriter, r *http.Request) {})
+	h := Middleware(mux)
+
+	tests := []struct {
+		target string
+		route  string
+		status string
+	}{
+		{target: "/metrics-test/1", route: "GET /metrics-test/{id}", status: "418"},
+		{target: "/metrics-test", route: "GET /metrics-test", status: "200"},
+		{target: "/nowhere", route: "unmatched", status: "404"},
+	}
+	for _, tt := range tests {
+		before := count(t, httpRequests.WithLabelValues(tt.route, tt.status))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if got := count(t, httpRequests.WithLabelValues(tt.route, tt.status)) - before; got != 1 {
+			t.Errorf("%s counted %v times as %s %s, want once", tt.target, got, tt.route, tt.status)
+		}
+	}
+}
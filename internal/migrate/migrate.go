@@ -0,0 +1,144 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"text/tabwriter"
+	"time"
+	"usersubs/migrations"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// NewProvider applies the embedded migrations. Goose keeps the version in
+// `goose_db_version`, the same table the goose CLI used, and a Postgres
+// advisory lock keeps replicas starting together from racing.
+func NewProvider(sqlDB *sql.DB) (*goose.Provider, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, fmt.Errorf("MIGRATE - could not create lock - %w", err)
+	}
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, sqlDB, migrations.FS, goose.WithSessionLocker(locker))
+	if err != nil {
+		return nil, fmt.Errorf("MIGRATE - could not load migrations - %w", err)
+	}
+	return provider, nil
+}
+
+// LatestVersion is the version of the newest embedded migration, the one
+// this binary expects the database to be at.
+func LatestVersion() (int64, error) {
+	names, err := fs.Glob(migrations.FS, "*.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, name := range names {
+		version, err := goose.NumericComponent(name)
+		if err != nil {
+			return 0, fmt.Errorf("MIGRATE - bad migration name %s - %w", name, err)
+		}
+		latest = max(latest, version)
+	}
+	return latest, nil
+}
+
+// Run executes a `migrate` subcommand: up, down, status or to N.
+func Run(ctx context.Context, sqlDB *sql.DB, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("MIGRATE - expected one of: up, down, status, to N")
+	}
+
+	provider, err := NewProvider(sqlDB)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		results, err := provider.Up(ctx)
+		printResults(out, results...)
+		return err
+	case "down":
+		result, err := provider.Down(ctx)
+		if result != nil {
+			printResults(out, result)
+		}
+		return err
+	case "status":
+		return printStatus(ctx, provider, out)
+	case "to":
+		if len(args) < 2 {
+			return errors.New("MIGRATE - `to` expects a version")
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("MIGRATE - could not parse version - %w", err)
+		}
+		return migrateTo(ctx, provider, version, out)
+	default:
+		return fmt.Errorf("MIGRATE - unknown command %q", args[0])
+	}
+}
+
+func migrateTo(ctx context.Context, provider *goose.Provider, version int64, out io.Writer) error {
+	current, err := provider.GetDBVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	var results []*goose.MigrationResult
+	if version >= current {
+		results, err = provider.UpTo(ctx, version)
+	} else {
+		results, err = provider.DownTo(ctx, version)
+	}
+	printResults(out, results...)
+	return err
+}
+
+// Up applies all pending migrations, it is used for auto-migration on
+// startup.
+func Up(ctx context.Context, sqlDB *sql.DB) ([]*goose.MigrationResult, error) {
+	provider, err := NewProvider(sqlDB)
+	if err != nil {
+		return nil, err
+	}
+	return provider.Up(ctx)
+}
+
+func printResults(out io.Writer, results ...*goose.MigrationResult) {
+	if len(results) == 0 {
+		fmt.Fprintln(out, "no migrations to apply")
+		return
+	}
+	for _, r := range results {
+		fmt.Fprintln(out, r)
+	}
+}
+
+func printStatus(ctx context.Context, provider *goose.Provider, out io.Writer) error {
+	statuses, err := provider.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tSTATE\tAPPLIED AT\tFILE")
+	for _, s := range statuses {
+		applied := "-"
+		if !s.AppliedAt.IsZero() {
+			applied = s.AppliedAt.Format(time.DateTime)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", s.Source.Version, s.State, applied, s.Source.Path)
+	}
+	return tw.Flush()
+}
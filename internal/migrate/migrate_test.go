@@ -0,0 +1,104 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"usersubs/migrations"
+
+	_ "github.com/lib/pq"
+	"github.com/pressly/goose/v3"
+)
+
+// The embedded migrations are numbered 1..N without gaps, so LatestVersion
+// is N.
+func TestLatestVersion(t *testing.T) {
+	names, err := fs.Glob(migrations.FS, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[int64]string{}
+	for _, name := range names {
+		version, err := goose.NumericComponent(name)
+		if err != nil {
+			t.Fatalf("%s - %v", name, err)
+		}
+		if other, ok := seen[version]; ok {
+			t.Errorf("%s and %s share version %d", name, other, version)
+		}
+		seen[version] = name
+	}
+	for version := int64(1); version <= int64(len(names)); version++ {
+		if _, ok := seen[version]; !ok {
+			t.Errorf("no migration %d", version)
+		}
+	}
+
+	latest, err := LatestVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest != int64(len(names)) {
+		t.Errorf("LatestVersion() = %d, want %d", latest, len(names))
+	}
+}
+
+// Commands are checked before the database is used.
+func TestRunUsage(t *testing.T) {
+	sqlDB, err := sql.Open("postgres", "postgres://localhost:1/none?sslmode=disable&connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: nil, wantErr: "expected one of"},
+		{args: []string{"sideways"}, wantErr: "unknown command"},
+		{args: []string{"to"}, wantErr: "expects a version"},
+		{args: []string{"to", "latest"}, wantErr: "could not parse version"},
+	}
+	for _, tt := range tests {
+		err := Run(context.Background(), sqlDB, tt.args, &bytes.Buffer{})
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Run(%q) error = %v, want one containing %q", tt.args, err, tt.wantErr)
+		}
+	}
+}
+
+// TestUp applies every migration on DATABASE_URL, it is skipped without it.
+func TestUp(t *testing.T) {
+	url := os.Getenv("DATABASE_URL")
+	if url == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	sqlDB, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	if err := Run(ctx, sqlDB, []string{"up"}, &out); err != nil {
+		t.Fatalf("up - %v\n%s", err, out.String())
+	}
+
+	provider, err := NewProvider(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := provider.GetDBVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest, _ := LatestVersion(); current != latest {
+		t.Errorf("database at %d after up, want %d", current, latest)
+	}
+}
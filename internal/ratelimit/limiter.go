@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"usersubs/internal/utils"
+)
+
+// Limit describes a token bucket: Burst tokens at most, refilled with Rate
+// tokens per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is the state of a bucket after taking a token from it.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is the time until the next token is available.
+	RetryAfter time.Duration
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+}
+
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Limiter applies per-route limits to requests, keyed by client.
+type Limiter struct {
+	Store Store
+	// Default is used for routes missing in RouteLimits.
+	Default     Limit
+	RouteLimits map[string]Limit
+	// TrustProxy makes the client IP to be taken from `X-Forwarded-For`, as
+	// the address the proxy in front of the API appended last.
+	TrustProxy bool
+	// Identify names the caller from verified credentials. Requests it does
+	// not know, including those with invalid credentials, are limited by IP.
+	Identify func(r *http.Request) (string, bool)
+}
+
+// Middleware should wrap single routes, the route pattern of the request
+// selects the limit.
+func (l Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pattern := r.Pattern
+		limit, res, err := l.Take(r.Context(), pattern, l.clientKey(r))
+		if err != nil {
+			// The limiter should not take the API down with it.
+			slog.Error("RATE LIMIT - could not take token", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		setHeaders(w, limit, res)
+		if !res.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			utils.SendError(w, "Error: too many requests", http.StatusTooManyRequests, fmt.Errorf("rate limit exceeded on %q", pattern))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Take takes a token of route for client, with the limit of route or
+// Default. The gRPC server calls it with the full method name as route.
+func (l Limiter) Take(ctx context.Context, route, client string) (Limit, Result, error) {
+	limit, ok := l.RouteLimits[route]
+	if !ok {
+		limit = l.Default
+	}
+	res, err := l.Store.Take(ctx, route+"|"+client, limit)
+	return limit, res, err
+}
+
+// clientKey identifies the caller by Identify or by IP. Unverified
+// credentials never pick the bucket, or a client could get a fresh one on
+// every request by sending a random key.
+func (l Limiter) clientKey(r *http.Request) string {
+	if l.Identify != nil {
+		if id, ok := l.Identify(r); ok {
+			return id
+		}
+	}
+	return "ip:" + l.clientIP(r)
+}
+
+// clientIP takes the rightmost `X-Forwarded-For` entry behind a proxy, the
+// entries before it are sent by the client and can be anything.
+func (l Limiter) clientIP(r *http.Request) string {
+	if l.TrustProxy {
+		if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			forwarded := values[len(values)-1]
+			if ip := strings.TrimSpace(forwarded[strings.LastIndex(forwarded, ",")+1:]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func setHeaders(w http.ResponseWriter, limit Limit, res Result) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// result builds a Result from the tokens left in a bucket.
+func result(allowed bool, tokens float64, limit Limit) Result {
+	res := Result{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+	}
+
+	if limit.Rate > 0 {
+		res.Reset = time.Duration((float64(limit.Burst) - tokens) / limit.Rate * float64(time.Second))
+		if tokens < 1 {
+			res.RetryAfter = time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,204 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Limit{Rate: 0.001, Burst: 3}
+
+	for i, want := range []Result{
+		{Allowed: true, Remaining: 2},
+		{Allowed: true, Remaining: 1},
+		{Allowed: true, Remaining: 0},
+		{Allowed: false, Remaining: 0},
+	} {
+		got, err := s.Take(context.Background(), "a", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Allowed != want.Allowed || got.Remaining != want.Remaining {
+			t.Errorf("take %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	if got, _ := s.Take(context.Background(), "b", limit); !got.Allowed {
+		t.Error("another key shares the bucket")
+	}
+}
+
+func TestResult(t *testing.T) {
+	tests := []struct {
+		name           string
+		allowed        bool
+		tokens         float64
+		limit          Limit
+		wantRemaining  int
+		wantRetryAfter time.Duration
+		wantReset      time.Duration
+	}{
+		{name: "full", allowed: true, tokens: 10, limit: Limit{Rate: 2, Burst: 10}, wantRemaining: 10},
+		{name: "partial", allowed: true, tokens: 4.5, limit: Limit{Rate: 2, Burst: 10}, wantRemaining: 4, wantReset: 2750 * time.Millisecond},
+		{name: "empty", tokens: 0.5, limit: Limit{Rate: 2, Burst: 10}, wantRetryAfter: 250 * time.Millisecond, wantReset: 4750 * time.Millisecond},
+		{name: "no refill", tokens: 0, limit: Limit{Burst: 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := result(tt.allowed, tt.tokens, tt.limit)
+			want := Result{Allowed: tt.allowed, Remaining: tt.wantRemaining, RetryAfter: tt.wantRetryAfter, Reset: tt.wantReset}
+			if got != want {
+				t.Errorf("result() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestRouteLimits(t *testing.T) {
+	l := Limiter{
+		Store:       NewMemoryStore(),
+		Default:     Limit{Rate: 0.001, Burst: 3},
+		RouteLimits: map[string]Limit{"DELETE /api/subs": {Rate: 0.001, Burst: 1}},
+	}
+	h := http.NewServeMux()
+	h.Handle("GET /api/subs", l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	h.Handle("DELETE /api/subs", l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	tests := []struct {
+		method     string
+		remoteAddr string
+		wantCode   int
+	}{
+		{http.MethodDelete, "10.0.0.1:1234", http.StatusOK},
+		{http.MethodDelete, "10.0.0.1:1234", http.StatusTooManyRequests},
+		// The strict route has a bucket of its own.
+		{http.MethodGet, "10.0.0.1:1234", http.StatusOK},
+		{http.MethodDelete, "10.0.0.2:1234", http.StatusOK},
+	}
+	for i, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/api/subs", nil)
+		r.RemoteAddr = tt.remoteAddr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != tt.wantCode {
+			t.Errorf("request %d: code = %d, want %d", i, w.Code, tt.wantCode)
+		}
+		if w.Header().Get("RateLimit-Limit") == "" {
+			t.Errorf("request %d: no RateLimit-Limit header", i)
+		}
+		if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+			t.Errorf("request %d: 429 without Retry-After", i)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	identify := func(r *http.Request) (string, bool) {
+		if r.Header.Get("X-API-Key") == "valid" {
+			return "key:valid", true
+		}
+		return "", false
+	}
+
+	tests := []struct {
+		name string
+		// key returns the X-API-Key of the i-th request.
+		key         func(i int) string
+		remoteAddr  func(i int) string
+		wantAllowed int
+	}{
+		{
+			name:        "random keys share the bucket of the ip",
+			key:         func(i int) string { return fmt.Sprintf("random-%d", i) },
+			remoteAddr:  func(int) string { return "10.0.0.1:1234" },
+			wantAllowed: 2,
+		},
+		{
+			name:        "valid key has one bucket from any ip",
+			key:         func(int) string { return "valid" },
+			remoteAddr:  func(i int) string { return fmt.Sprintf("10.0.0.%d:1234", i+1) },
+			wantAllowed: 2,
+		},
+		{
+			name:        "ips have their own buckets",
+			key:         func(int) string { return "" },
+			remoteAddr:  func(i int) string { return fmt.Sprintf("10.0.0.%d:1234", i+1) },
+			wantAllowed: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := Limiter{
+				Store:    NewMemoryStore(),
+				Default:  Limit{Rate: 0.001, Burst: 2},
+				Identify: identify,
+			}
+			h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			allowed := 0
+			for i := range 5 {
+				r := httptest.NewRequest(http.MethodGet, "/api/subs", nil)
+				r.RemoteAddr = tt.remoteAddr(i)
+				if key := tt.key(i); key != "" {
+					r.Header.Set("X-API-Key", key)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+
+				switch w.Code {
+				case http.StatusOK:
+					allowed++
+				case http.StatusTooManyRequests:
+					if w.Header().Get("Retry-After") == "" {
+						t.Error("429 without Retry-After")
+					}
+				default:
+					t.Fatalf("code = %d", w.Code)
+				}
+			}
+
+			if allowed != tt.wantAllowed {
+				t.Errorf("allowed %d of 5 requests, want %d", allowed, tt.wantAllowed)
+			}
+		})
+	}
+}
+
+// Behind a proxy the client can only prepend entries to `X-Forwarded-For`,
+// they must not pick the bucket.
+func TestClientIPBehindProxy(t *testing.T) {
+	l := Limiter{TrustProxy: true}
+	tests := []struct {
+		name      string
+		forwarded []string
+		want      string
+	}{
+		{name: "proxy only", forwarded: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "spoofed leftmost", forwarded: []string{"10.9.9.9, 203.0.113.7"}, want: "203.0.113.7"},
+		{name: "spoofed header", forwarded: []string{"10.9.9.9", "203.0.113.7"}, want: "203.0.113.7"},
+		{name: "spaces", forwarded: []string{"10.9.9.9 ,  203.0.113.7 "}, want: "203.0.113.7"},
+		{name: "none", want: "192.0.2.1"},
+		{name: "empty entry", forwarded: []string{"10.9.9.9,"}, want: "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/subs", nil)
+			r.RemoteAddr = "192.0.2.1:1234"
+			for _, v := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if got := l.clientKey(r); got != "ip:"+tt.want {
+				t.Errorf("clientKey() = %q, want %q", got, "ip:"+tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"usersubs/internal/db"
+)
+
+// PostgresStore keeps buckets in the `rate_limits` table, so limits hold
+// across instances sharing the database.
+type PostgresStore struct {
+	Repo *db.Queries
+}
+
+func (s PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	row, err := s.Repo.TakeRateLimitToken(ctx, db.TakeRateLimitTokenParams{
+		Key:   key,
+		Burst: float64(limit.Burst),
+		Rate:  limit.Rate,
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result(row.Allowed, row.Tokens, limit), nil
+}
+
+// Sweep removes buckets untouched for an hour every interval until ctx is
+// done.
+func (s PostgresStore) Sweep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Repo.DeleteIdleRateLimits(ctx); err != nil {
+				slog.Error("RATE LIMIT - could not delete idle buckets", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"usersubs/internal/auth"
+	"usersubs/internal/clock"
+	"usersubs/internal/logging"
+	"usersubs/internal/metrics"
+	"usersubs/internal/ratelimit"
+	subsv1 "usersubs/internal/rpc/subs/v1"
+	"usersubs/internal/tenant"
+	"usersubs/internal/tracing"
+
+	"github.com/google/uuid"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// Options are the checks of the REST API applied to the subscription
+// service.
+type Options struct {
+	Keys    auth.APIKeys
+	Tenants tenant.Resolver
+	// Limiter is applied when set, RouteLimits are keyed by the full method
+	// name.
+	Limiter *ratelimit.Limiter
+	// FakeNow lets a call set the clock with `x-fake-now`, see
+	// clock.Middleware. Debug only.
+	FakeNow bool
+}
+
+// NewServer registers the subscription service together with the standard
+// health and reflection services. The returned health server is used to
+// report NOT_SERVING while draining.
+func NewServer(subs SubsServer, o Options, opts ...grpc.ServerOption) (*grpc.Server, *health.Server) {
+	unary := []grpc.UnaryServerInterceptor{traceUnary, observeUnary}
+	stream := []grpc.StreamServerInterceptor{traceStream, observeStream}
+	if o.Limiter != nil {
+		l := limiter{limiter: *o.Limiter, keys: o.Keys}
+		unary, stream = append(unary, l.unary), append(stream, l.stream)
+	}
+	if o.FakeNow {
+		unary, stream = append(unary, fakeNowUnary), append(stream, fakeNowStream)
+	}
+	g := guard{keys: o.Keys, tenants: o.Tenants}
+	unary, stream = append(unary, g.unary), append(stream, g.stream)
+
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	)
+	server := grpc.NewServer(opts...)
+
+	subsv1.RegisterSubscriptionServiceServer(server, subs)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(subsv1.SubscriptionService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+
+	return server, healthServer
+}
+
+// guard applies the API key and tenant checks of the REST API to the
+// subscription service, health and reflection stay open.
+type guard struct {
+	keys    auth.APIKeys
+	tenants tenant.Resolver
+}
+
+func (g guard) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !guarded(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	ctx, err := g.check(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (g guard) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !guarded(info.FullMethod) {
+		return handler(srv, ss)
+	}
+	ctx, err := g.check(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, contextStream{ServerStream: ss, ctx: ctx})
+}
+
+func guarded(method string) bool {
+	return strings.HasPrefix(method, "/"+subsv1.SubscriptionService_ServiceDesc.ServiceName+"/")
+}
+
+// check validates `x-api-key` and resolves `x-tenant-id` from the metadata,
+// see tenant.Resolver.Resolve.
+func (g guard) check(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if len(g.keys.Keys) > 0 {
+		secret := first(md, "x-api-key")
+		if secret == "" {
+			return ctx, status.Error(codes.Unauthenticated, "metadata `x-api-key` is not provided")
+		}
+		key, ok := g.keys.Lookup(secret)
+		if !ok {
+			return ctx, status.Error(codes.Unauthenticated, "invalid api key")
+		}
+		ctx = tenant.Bind(ctx, key.TenantID)
+	}
+
+	id, err := g.tenants.Resolve(ctx, first(md, strings.ToLower(tenant.Header)))
+	switch {
+	case errors.Is(err, tenant.ErrMissing):
+		return ctx, status.Error(codes.InvalidArgument, "metadata `x-tenant-id` is not provided")
+	case errors.Is(err, tenant.ErrInvalid):
+		return ctx, status.Errorf(codes.InvalidArgument, "could not parse metadata `x-tenant-id` - %v", err)
+	case errors.Is(err, tenant.ErrForbidden):
+		return ctx, status.Error(codes.PermissionDenied, "api key does not belong to the organization")
+	case errors.Is(err, sql.ErrNoRows):
+		return ctx, status.Error(codes.NotFound, "organization not found")
+	case err != nil:
+		slog.Error("Error: something went wrong on sql query", "error", err)
+		return ctx, status.Error(codes.Internal, "something went wrong on sql query")
+	}
+
+	return tenant.WithID(ctx, id), nil
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// contextStream replaces the context of a server stream.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// limiter applies the rate limits of the REST API to the subscription
+// service. Callers are named by a valid `x-api-key`, else by address.
+type limiter struct {
+	limiter ratelimit.Limiter
+	keys    auth.APIKeys
+}
+
+func (l limiter) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if guarded(info.FullMethod) {
+		if err := l.take(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+func (l limiter) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if guarded(info.FullMethod) {
+		if err := l.take(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+	}
+	return handler(srv, ss)
+}
+
+// take refuses the call with ResourceExhausted and `retry-after` when the
+// bucket of the caller is empty.
+func (l limiter) take(ctx context.Context, method string) error {
+	_, res, err := l.limiter.Take(ctx, method, l.clientKey(ctx))
+	if err != nil {
+		// The limiter should not take the API down with it.
+		slog.Error("RATE LIMIT - could not take token", "error", err)
+		return nil
+	}
+	if !res.Allowed {
+		retry := int(math.Ceil(res.RetryAfter.Seconds()))
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retry)))
+		return status.Errorf(codes.ResourceExhausted, "too many requests, retry in %ds", retry)
+	}
+	return nil
+}
+
+func (l limiter) clientKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if name, ok := l.keys.Name(first(md, "x-api-key")); ok {
+		return name
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return "ip:" + host
+		}
+		return "ip:" + p.Addr.String()
+	}
+	return "ip:"
+}
+
+func fakeNowUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := fakeNow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func fakeNowStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := fakeNow(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// fakeNow fixes "now" from the `x-fake-now` metadata when given.
+func fakeNow(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	value := first(md, strings.ToLower(clock.Header))
+	if value == "" {
+		return ctx, nil
+	}
+	now, err := clock.Parse(value)
+	if err != nil {
+		return ctx, status.Errorf(codes.InvalidArgument, "could not parse metadata `x-fake-now` - %v", err)
+	}
+	return clock.WithNow(ctx, now), nil
+}
+
+// metadataCarrier reads the trace propagated in the metadata of a call.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	return first(metadata.MD(c), key)
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func traceUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, span := startSpan(ctx, info.FullMethod)
+	defer span.End()
+	res, err := handler(ctx, req)
+	endSpan(span, err)
+	return res, err
+}
+
+func traceStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, span := startSpan(ss.Context(), info.FullMethod)
+	defer span.End()
+	err := handler(srv, contextStream{ServerStream: ss, ctx: ctx})
+	endSpan(span, err)
+	return err
+}
+
+// startSpan names the span after the method, like the route of an HTTP
+// request.
+func startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx, span := tracing.StartServer(ctx, method, metadataCarrier(md.Copy()))
+	service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	span.SetAttributes(semconv.RPCSystemGRPC, semconv.RPCService(service), semconv.RPCMethod(name))
+	return ctx, span
+}
+
+// endSpan marks the span failed on the codes of server errors.
+func endSpan(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		span.SetStatus(otelcodes.Error, code.String())
+	}
+}
+
+func observeUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	res, err := handler(ctx, req)
+	observe(ctx, info.FullMethod, start, err)
+	return res, err
+}
+
+func observeStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	observe(ss.Context(), info.FullMethod, start, err)
+	return err
+}
+
+// observe counts the call and writes the same record as the HTTP logging
+// middleware, with the request ID taken from the `x-request-id` metadata
+// when given.
+func observe(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err).String()
+	metrics.ObserveGRPC(method, code, time.Since(start))
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := first(md, strings.ToLower(logging.RequestIDHeader))
+	if id == "" || len(id) > 128 {
+		id = uuid.NewString()
+	}
+
+	attrs := []any{
+		"request_id", id,
+		"method", "gRPC",
+		"route", method,
+		"status", code,
+		"latency_ms", time.Since(start).Milliseconds(),
+	}
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		attrs = append(attrs, "trace_id", traceID)
+	}
+	slog.Info("request", attrs...)
+}
@@ -0,0 +1,167 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+	"usersubs/internal/auth"
+	"usersubs/internal/clock"
+	"usersubs/internal/metrics"
+	"usersubs/internal/ratelimit"
+	subsv1 "usersubs/internal/rpc/subs/v1"
+	"usersubs/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// call runs a unary interceptor and returns the context it passed on.
+func call(interceptor grpc.UnaryServerInterceptor, ctx context.Context, method string) (context.Context, error) {
+	var got context.Context
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req any) (any, error) {
+		got = ctx
+		return nil, nil
+	})
+	return got, err
+}
+
+// The gRPC methods share the limits of the REST routes, the delete of all
+// subscriptions of a user has the strict one.
+func TestLimiter(t *testing.T) {
+	deleteUser := subsv1.SubscriptionService_DeleteUserSubscriptions_FullMethodName
+	list := subsv1.SubscriptionService_ListSubscriptions_FullMethodName
+	l := limiter{
+		limiter: ratelimit.Limiter{
+			Store:       ratelimit.NewMemoryStore(),
+			Default:     ratelimit.Limit{Rate: 0.001, Burst: 3},
+			RouteLimits: map[string]ratelimit.Limit{deleteUser: {Rate: 0.001, Burst: 1}},
+		},
+		keys: auth.APIKeys{Keys: []auth.Key{{TenantID: uuid.New(), Secret: "k1"}, {TenantID: uuid.New(), Secret: "k2"}}},
+	}
+	from := func(addr string, pairs ...string) context.Context {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(addr), Port: 5501}})
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(pairs...))
+	}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		want   codes.Code
+	}{
+		{name: "delete", ctx: from("10.0.0.1", "x-api-key", "k1"), method: deleteUser, want: codes.OK},
+		{name: "delete again", ctx: from("10.0.0.2", "x-api-key", "k1"), method: deleteUser, want: codes.ResourceExhausted},
+		{name: "other method", ctx: from("10.0.0.1", "x-api-key", "k1"), method: list, want: codes.OK},
+		{name: "other key", ctx: from("10.0.0.1", "x-api-key", "k2"), method: deleteUser, want: codes.OK},
+		{name: "invalid key", ctx: from("10.0.0.3", "x-api-key", "bad1"), method: deleteUser, want: codes.OK},
+		{name: "another invalid key", ctx: from("10.0.0.3", "x-api-key", "bad2"), method: deleteUser, want: codes.ResourceExhausted},
+		{name: "health", ctx: from("10.0.0.3"), method: "/grpc.health.v1.Health/Check", want: codes.OK},
+		{name: "health again", ctx: from("10.0.0.3"), method: "/grpc.health.v1.Health/Check", want: codes.OK},
+	}
+	for _, tt := range tests {
+		if _, err := call(l.unary, tt.ctx, tt.method); status.Code(err) != tt.want {
+			t.Errorf("%s: code = %v, want %v", tt.name, status.Code(err), tt.want)
+		}
+	}
+}
+
+func TestFakeNow(t *testing.T) {
+	now := time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-fake-now", "2025-02-28"))
+	got, err := call(fakeNowUnary, ctx, subsv1.SubscriptionService_ListSubscriptions_FullMethodName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !clock.Now(got, nil).Equal(now) {
+		t.Errorf("now = %v, want %v", clock.Now(got, nil), now)
+	}
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-fake-now", "tomorrow"))
+	if _, err := call(fakeNowUnary, ctx, subsv1.SubscriptionService_ListSubscriptions_FullMethodName); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("code = %v, want InvalidArgument", status.Code(err))
+	}
+}
+
+// Spans are named after the method and continue the trace of the caller.
+func TestTraceUnary(t *testing.T) {
+	// Setup without an exporter installs the propagation only.
+	if _, err := tracing.Setup(context.Background(), "", ""); err != nil {
+		t.Fatal(err)
+	}
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01"))
+	got, err := call(traceUnary, ctx, subsv1.SubscriptionService_GetSubscription_FullMethodName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := tracing.TraceID(got); id != traceID {
+		t.Errorf("trace = %q, want %q", id, traceID)
+	}
+
+	_, err = traceUnary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: subsv1.SubscriptionService_ListSubscriptions_FullMethodName}, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.Internal, "boom")
+	})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("code = %v, want Internal", status.Code(err))
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	if spans[0].Name() != subsv1.SubscriptionService_GetSubscription_FullMethodName || spans[0].Status().Code == otelcodes.Error {
+		t.Errorf("span = %s %v, want the method without error", spans[0].Name(), spans[0].Status())
+	}
+	if spans[1].Status().Code != otelcodes.Error {
+		t.Errorf("span of an internal error has status %v", spans[1].Status())
+	}
+}
+
+func TestObserveUnary(t *testing.T) {
+	method := subsv1.SubscriptionService_GetSubscription_FullMethodName
+	count := func() float64 {
+		t.Helper()
+		families, err := metrics.Registry.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range families {
+			if f.GetName() != "usersubs_grpc_requests_total" {
+				continue
+			}
+			for _, m := range f.GetMetric() {
+				labels := map[string]string{}
+				for _, l := range m.GetLabel() {
+					labels[l.GetName()] = l.GetValue()
+				}
+				if labels["method"] == method && labels["code"] == codes.NotFound.String() {
+					return m.GetCounter().GetValue()
+				}
+			}
+		}
+		return 0
+	}
+
+	before := count()
+	_, err := observeUnary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.NotFound, "subscription not found")
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("code = %v, want NotFound", status.Code(err))
+	}
+	if got := count() - before; got != 1 {
+		t.Errorf("counted %v calls, want 1", got)
+	}
+}
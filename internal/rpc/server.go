@@ -0,0 +1,223 @@
+// Package rpc serves the subscriptions API over gRPC, next to the REST one.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+	subsv1 "usersubs/internal/rpc/subs/v1"
+	"usersubs/internal/subs/service"
+	"usersubs/internal/tenant"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const dateFormat = "01-2006"
+
+// streamPage is the number of rows read per query by StreamSubscriptions.
+const streamPage = 100
+
+type SubsServer struct {
+	subsv1.UnimplementedSubscriptionServiceServer
+
+	Service *service.SubscriptionService
+}
+
+func (s SubsServer) ListSubscriptions(ctx context.Context, req *subsv1.ListSubscriptionsRequest) (*subsv1.ListSubscriptionsResponse, error) {
+	if req.GetLimit() < 0 || req.GetOffset() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit and offset must not be negative")
+	}
+
+	subs, err := s.list(ctx, req.GetUserId(), service.ListFilter{Limit: req.GetLimit(), Offset: req.GetOffset()})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &subsv1.ListSubscriptionsResponse{Subscriptions: make([]*subsv1.Subscription, 0, len(subs))}
+	for _, sub := range subs {
+		res.Subscriptions = append(res.Subscriptions, toProto(sub))
+	}
+	return res, nil
+}
+
+// StreamSubscriptions pages by ID, so rows added or deleted while streaming
+// do not make it skip or repeat others.
+func (s SubsServer) StreamSubscriptions(req *subsv1.StreamSubscriptionsRequest, stream subsv1.SubscriptionService_StreamSubscriptionsServer) error {
+	filter := service.ListFilter{Limit: streamPage}
+	for {
+		subs, err := s.list(stream.Context(), req.GetUserId(), filter)
+		if err != nil {
+			return err
+		}
+
+		for _, sub := range subs {
+			if err := stream.Send(&subsv1.StreamSubscriptionsResponse{Subscription: toProto(sub)}); err != nil {
+				return err
+			}
+		}
+		if len(subs) < streamPage {
+			return nil
+		}
+		filter.AfterID = subs[len(subs)-1].ID
+	}
+}
+
+// list reads one page of subscriptions, of a single user when userID is set.
+func (s SubsServer) list(ctx context.Context, userID string, filter service.ListFilter) ([]service.Subscription, error) {
+	tenantID, _ := tenant.FromContext(ctx)
+	if userID != "" {
+		var err error
+		filter.UserID, err = uuid.Parse(userID)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "could not parse user_id - %v", err)
+		}
+	}
+
+	subs, err := s.Service.List(ctx, tenantID, filter)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return subs, nil
+}
+
+func (s SubsServer) GetSubscription(ctx context.Context, req *subsv1.GetSubscriptionRequest) (*subsv1.GetSubscriptionResponse, error) {
+	tenantID, _ := tenant.FromContext(ctx)
+	sub, err := s.Service.Get(ctx, tenantID, req.GetId())
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return &subsv1.GetSubscriptionResponse{Subscription: toProto(sub)}, nil
+}
+
+func (s SubsServer) CreateSubscription(ctx context.Context, req *subsv1.CreateSubscriptionRequest) (*subsv1.CreateSubscriptionResponse, error) {
+	tenantID, _ := tenant.FromContext(ctx)
+	sub, err := fromProto(req.GetSubscription())
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err = s.Service.Create(ctx, tenantID, sub)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return &subsv1.CreateSubscriptionResponse{Subscription: toProto(sub)}, nil
+}
+
+func (s SubsServer) UpdateSubscription(ctx context.Context, req *subsv1.UpdateSubscriptionRequest) (*subsv1.UpdateSubscriptionResponse, error) {
+	tenantID, _ := tenant.FromContext(ctx)
+	sub, err := fromProto(req.GetSubscription())
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err = s.Service.Update(ctx, tenantID, sub)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return &subsv1.UpdateSubscriptionResponse{Subscription: toProto(sub)}, nil
+}
+
+func (s SubsServer) DeleteSubscription(ctx context.Context, req *subsv1.DeleteSubscriptionRequest) (*subsv1.DeleteSubscriptionResponse, error) {
+	tenantID, _ := tenant.FromContext(ctx)
+	id, err := s.Service.Delete(ctx, tenantID, req.GetId())
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return &subsv1.DeleteSubscriptionResponse{Id: id}, nil
+}
+
+func (s SubsServer) DeleteUserSubscriptions(ctx context.Context, req *subsv1.DeleteUserSubscriptionsRequest) (*subsv1.DeleteUserSubscriptionsResponse, error) {
+	tenantID, _ := tenant.FromContext(ctx)
+	userID, err := uuid.Parse(req.GetUserId())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "could not parse user_id - %v", err)
+	}
+
+	ids, err := s.Service.DeleteUser(ctx, tenantID, userID)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return &subsv1.DeleteUserSubscriptionsResponse{Ids: ids}, nil
+}
+
+func toProto(sub service.Subscription) *subsv1.Subscription {
+	res := &subsv1.Subscription{
+		Id:          sub.ID,
+		ServiceName: sub.ServiceName,
+		Price:       sub.Price,
+		UserId:      sub.UserID.String(),
+		StartDate:   sub.StartDate.Format(dateFormat),
+		Status:      string(sub.Status),
+		TrialPrice:  sub.TrialPrice,
+	}
+	if !sub.EndDate.IsZero() {
+		res.EndDate = sub.EndDate.Format(dateFormat)
+	}
+	if !sub.TrialEndsAt.IsZero() {
+		res.TrialEndsAt = sub.TrialEndsAt.Format(time.RFC3339)
+	}
+	return res
+}
+
+func fromProto(sub *subsv1.Subscription) (service.Subscription, error) {
+	if sub == nil {
+		return service.Subscription{}, status.Error(codes.InvalidArgument, "subscription is required")
+	}
+
+	userID, err := uuid.Parse(sub.GetUserId())
+	if err != nil {
+		return service.Subscription{}, status.Errorf(codes.InvalidArgument, "could not parse user_id - %v", err)
+	}
+	start, err := time.Parse(dateFormat, sub.GetStartDate())
+	if err != nil {
+		return service.Subscription{}, status.Errorf(codes.InvalidArgument, "could not parse start_date, expected MM-YYYY - %v", err)
+	}
+	var end time.Time
+	if sub.GetEndDate() != "" {
+		end, err = time.Parse(dateFormat, sub.GetEndDate())
+		if err != nil {
+			return service.Subscription{}, status.Errorf(codes.InvalidArgument, "could not parse end_date, expected MM-YYYY - %v", err)
+		}
+	}
+	var trialEnd time.Time
+	if sub.GetTrialEndsAt() != "" {
+		trialEnd, err = time.Parse(time.RFC3339, sub.GetTrialEndsAt())
+		if err != nil {
+			return service.Subscription{}, status.Errorf(codes.InvalidArgument, "could not parse trial_ends_at, expected RFC 3339 - %v", err)
+		}
+	}
+
+	return service.Subscription{
+		ID:          sub.GetId(),
+		ServiceName: sub.GetServiceName(),
+		Price:       sub.GetPrice(),
+		UserID:      userID,
+		StartDate:   start,
+		EndDate:     end,
+		TrialEndsAt: trialEnd,
+		TrialPrice:  sub.GetTrialPrice(),
+	}, nil
+}
+
+// serviceError maps service errors to status codes the same way the REST
+// handlers map them to HTTP statuses.
+func serviceError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalid):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrConflict):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "sql query timed out")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "call was cancelled")
+	default:
+		slog.Error("Error: something went wrong on sql query", "error", err)
+		return status.Error(codes.Internal, "something went wrong on sql query")
+	}
+}
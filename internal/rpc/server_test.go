@@ -0,0 +1,114 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+	"usersubs/internal/auth"
+	subsv1 "usersubs/internal/rpc/subs/v1"
+	"usersubs/internal/subs/service"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestProtoRoundTrip(t *testing.T) {
+	subs := []service.Subscription{
+		{ID: 1, ServiceName: "Netflix", Price: 400, UserID: uuid.New(), StartDate: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, ServiceName: "Spotify", Price: 200, UserID: uuid.New(), StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			EndDate: time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, sub := range subs {
+		got, err := fromProto(toProto(sub))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, sub) {
+			t.Errorf("round trip = %+v, want %+v", got, sub)
+		}
+	}
+}
+
+func TestFromProtoInvalid(t *testing.T) {
+	user := uuid.NewString()
+	tests := []struct {
+		name string
+		sub  *subsv1.Subscription
+	}{
+		{name: "missing"},
+		{name: "user", sub: &subsv1.Subscription{UserId: "bob", StartDate: "07-2025"}},
+		{name: "start", sub: &subsv1.Subscription{UserId: user, StartDate: "2025-07"}},
+		{name: "end", sub: &subsv1.Subscription{UserId: user, StartDate: "07-2025", EndDate: "soon"}},
+	}
+	for _, tt := range tests {
+		if _, err := fromProto(tt.sub); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: code = %v, want InvalidArgument", tt.name, status.Code(err))
+		}
+	}
+}
+
+func TestServiceError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{err: &service.ValidationError{Field: "price", Message: "must not be negative"}, want: codes.InvalidArgument},
+		{err: service.ErrNotFound, want: codes.NotFound},
+		{err: &service.TransitionError{From: service.StatusCancelled, Allowed: []service.Status{service.StatusActive}}, want: codes.FailedPrecondition},
+		{err: fmt.Errorf("TENANT TX - %w", context.DeadlineExceeded), want: codes.DeadlineExceeded},
+		{err: context.Canceled, want: codes.Canceled},
+		{err: errors.New("connection reset"), want: codes.Internal},
+	}
+	for _, tt := range tests {
+		if got := status.Code(serviceError(tt.err)); got != tt.want {
+			t.Errorf("serviceError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// The checks that fail before the organization lookup.
+func TestGuard(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		md     metadata.MD
+		// open runs the guard without keys.
+		open bool
+		want codes.Code
+	}{
+		{name: "health stays open", method: "/grpc.health.v1.Health/Check", want: codes.OK},
+		{name: "missing key", method: subsv1.SubscriptionService_ListSubscriptions_FullMethodName, want: codes.Unauthenticated},
+		{name: "invalid key", method: subsv1.SubscriptionService_ListSubscriptions_FullMethodName, md: metadata.Pairs("x-api-key", "k2"), want: codes.Unauthenticated},
+		{name: "missing tenant", method: subsv1.SubscriptionService_ListSubscriptions_FullMethodName, open: true, want: codes.InvalidArgument},
+		{name: "bad tenant", method: subsv1.SubscriptionService_ListSubscriptions_FullMethodName, md: metadata.Pairs("x-api-key", "k1", "x-tenant-id", "acme"), want: codes.InvalidArgument},
+		{name: "foreign tenant", method: subsv1.SubscriptionService_ListSubscriptions_FullMethodName, md: metadata.Pairs("x-api-key", "k1", "x-tenant-id", uuid.NewString()), want: codes.PermissionDenied},
+	}
+
+	keyed := guard{keys: auth.APIKeys{Keys: []auth.Key{{TenantID: uuid.New(), Secret: "k1"}}}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := keyed
+			if tt.open {
+				g = guard{}
+			}
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			reached := false
+			_, err := g.unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, func(ctx context.Context, req any) (any, error) {
+				reached = true
+				return nil, nil
+			})
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("code = %v, want %v", got, tt.want)
+			}
+			if reached != (tt.want == codes.OK) {
+				t.Errorf("handler reached = %v", reached)
+			}
+		})
+	}
+}
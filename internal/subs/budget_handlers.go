@@ -0,0 +1,198 @@
+package subs
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"usersubs/internal/subs/service"
+	"usersubs/internal/tenant"
+	"usersubs/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+type budgetJSON struct {
+	ID    int32               `json:"id,omitempty"`
+	Scope service.BudgetScope `json:"scope" enums:"overall,service,category"`
+	// Target is the service or category name of a service or category
+	// budget.
+	Target   string               `json:"target,omitempty"`
+	Amount   int32                `json:"amount"`
+	Currency string               `json:"currency"`
+	Period   service.BudgetPeriod `json:"period" enums:"month,year"`
+	// Enforce rejects changes taking the user over the budget with 409.
+	Enforce bool `json:"enforce,omitempty"`
+}
+
+func toBudgetJSON(b service.Budget) budgetJSON {
+	return budgetJSON{
+		ID:       b.ID,
+		Scope:    b.Scope,
+		Target:   b.Target,
+		Amount:   b.Amount,
+		Currency: b.Currency,
+		Period:   b.Period,
+		Enforce:  b.Enforce,
+	}
+}
+
+type budgetStatusJSON struct {
+	Budget budgetJSON     `json:"budget"`
+	From   utils.JSONDate `json:"from"`
+	To     utils.JSONDate `json:"to"`
+	// Spent is projected for the whole period from the active
+	// subscriptions, Remaining is negative when over.
+	Spent     int64 `json:"spent"`
+	Remaining int64 `json:"remaining"`
+	Over      bool  `json:"over"`
+}
+
+func toBudgetStatusesJSON(statuses []service.BudgetStatus) []budgetStatusJSON {
+	res := []budgetStatusJSON{}
+	for _, s := range statuses {
+		res = append(res, budgetStatusJSON{
+			Budget:    toBudgetJSON(s.Budget),
+			From:      utils.JSONDate(s.From),
+			To:        utils.JSONDate(s.To),
+			Spent:     s.Spent,
+			Remaining: s.Remaining,
+			Over:      s.Over,
+		})
+	}
+	return res
+}
+
+// parseUserID reads the `id` path value of the user routes, see parseUser.
+func parseUserID(r *http.Request) (uuid.UUID, error) {
+	return parseUser(r, "id")
+}
+
+// @Summary PostBudget
+// @Description Add a monthly or yearly budget of a user over all subscriptions or the ones of a service or category
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path string true "User ID"
+// @Param request body budgetJSON true "Budget"
+// @Router /api/users/{id}/budgets [POST]
+func (h SubsHandler) PostBudget(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	userID, err := parseUserID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	var budget budgetJSON
+	if err := json.NewDecoder(r.Body).Decode(&budget); err != nil {
+		utils.SendError(w, "Error: something went wrong on decoding json", http.StatusBadRequest, err)
+		return
+	}
+
+	added, err := h.Service.AddBudget(r.Context(), tenantID, service.Budget{
+		UserID:   userID,
+		Scope:    budget.Scope,
+		Target:   budget.Target,
+		Amount:   budget.Amount,
+		Currency: budget.Currency,
+		Period:   budget.Period,
+		Enforce:  budget.Enforce,
+	})
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, toBudgetJSON(added), http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// @Summary GetBudgets
+// @Description Get the budgets of a user
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path string true "User ID"
+// @Router /api/users/{id}/budgets [GET]
+func (h SubsHandler) GetBudgets(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	userID, err := parseUserID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	budgets, err := h.Service.Budgets(r.Context(), tenantID, userID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	res := []budgetJSON{}
+	for _, b := range budgets {
+		res = append(res, toBudgetJSON(b))
+	}
+
+	if err := utils.SendData(w, res, http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// @Summary DeleteBudget
+// @Description Remove a budget
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path string true "User ID"
+// @Param budget_id path int true "ID of budget"
+// @Router /api/users/{id}/budgets/{budget_id} [DELETE]
+func (h SubsHandler) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	userID, err := parseUserID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+	budgetID, err := strconv.ParseInt(r.PathValue("budget_id"), 10, 32)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.Service.RemoveBudget(r.Context(), tenantID, userID, int32(budgetID)); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, budgetID, http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// @Summary GetBudgetStatus
+// @Description Compare every budget of a user to the projected spend of their active subscriptions
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path string true "User ID"
+// @Router /api/users/{id}/budget-status [GET]
+func (h SubsHandler) GetBudgetStatus(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	userID, err := parseUserID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	statuses, err := h.Service.BudgetStatus(r.Context(), tenantID, userID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, toBudgetStatusesJSON(statuses), http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
@@ -0,0 +1,264 @@
+package subs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"usersubs/internal/subs/service"
+	"usersubs/internal/tenant"
+	"usersubs/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+type categoryJSON struct {
+	ID   int32  `json:"id,omitempty"`
+	Name string `json:"name"`
+}
+
+// labelJSON names a category or a tag of a subscription.
+type labelJSON struct {
+	Name string `json:"name"`
+}
+
+type categorySpendJSON struct {
+	// Category is empty for the subscriptions without one.
+	Category      string `json:"category"`
+	Subscriptions int    `json:"subscriptions"`
+	Total         int64  `json:"total"`
+}
+
+// @Summary PostCategory
+// @Description Add a category of subscriptions
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param request body categoryJSON true "Category"
+// @Router /api/categories [POST]
+func (h SubsHandler) PostCategory(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	var category categoryJSON
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		utils.SendError(w, "Error: something went wrong on decoding json", http.StatusBadRequest, err)
+		return
+	}
+
+	added, err := h.Service.AddCategory(r.Context(), tenantID, category.Name)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, categoryJSON{ID: added.ID, Name: added.Name}, http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// @Summary GetCategories
+// @Description Get all categories by name
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Router /api/categories [GET]
+func (h SubsHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+
+	categories, err := h.Service.Categories(r.Context(), tenantID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	res := []categoryJSON{}
+	for _, c := range categories {
+		res = append(res, categoryJSON{ID: c.ID, Name: c.Name})
+	}
+
+	if err := utils.SendData(w, res, http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// @Summary DeleteCategory
+// @Description Delete a category and take it off every subscription
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param category_id path int true "ID of category"
+// @Router /api/categories/{category_id} [DELETE]
+func (h SubsHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	categoryID, err := strconv.ParseInt(r.PathValue("category_id"), 10, 32)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.Service.RemoveCategory(r.Context(), tenantID, int32(categoryID)); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, categoryID, http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// @Summary GetTags
+// @Description Get every tag in use by name
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Router /api/tags [GET]
+func (h SubsHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+
+	tags, err := h.Service.Tags(r.Context(), tenantID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, append([]string{}, tags...), http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// @Summary PostSubCategory
+// @Description Put a subscription in an existing category
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Param request body labelJSON true "Category"
+// @Router /api/sub/{id}/categories [POST]
+func (h SubsHandler) PostSubCategory(w http.ResponseWriter, r *http.Request) {
+	h.addLabel(w, r, h.Service.Categorize)
+}
+
+// @Summary DeleteSubCategory
+// @Description Take a subscription out of a category
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Param name path string true "Name of category"
+// @Router /api/sub/{id}/categories/{name} [DELETE]
+func (h SubsHandler) DeleteSubCategory(w http.ResponseWriter, r *http.Request) {
+	h.removeLabel(w, r, h.Service.Uncategorize)
+}
+
+// @Summary PostSubTag
+// @Description Tag a subscription, the tag is created on first use
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Param request body labelJSON true "Tag"
+// @Router /api/sub/{id}/tags [POST]
+func (h SubsHandler) PostSubTag(w http.ResponseWriter, r *http.Request) {
+	h.addLabel(w, r, h.Service.AddTag)
+}
+
+// @Summary DeleteSubTag
+// @Description Remove a tag from a subscription
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Param name path string true "Tag"
+// @Router /api/sub/{id}/tags/{name} [DELETE]
+func (h SubsHandler) DeleteSubTag(w http.ResponseWriter, r *http.Request) {
+	h.removeLabel(w, r, h.Service.RemoveTag)
+}
+
+type labelFunc func(ctx context.Context, tenantID uuid.UUID, subID int32, name string) (service.Subscription, error)
+
+// addLabel adds the category or tag named in the body and responds with
+// the subscription.
+func (h SubsHandler) addLabel(w http.ResponseWriter, r *http.Request, add labelFunc) {
+	subID, err := parseID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	var label labelJSON
+	if err := json.NewDecoder(r.Body).Decode(&label); err != nil {
+		utils.SendError(w, "Error: something went wrong on decoding json", http.StatusBadRequest, err)
+		return
+	}
+
+	h.sendLabeled(w, r, add, subID, label.Name)
+}
+
+// removeLabel removes the category or tag named in the path and responds
+// with the subscription.
+func (h SubsHandler) removeLabel(w http.ResponseWriter, r *http.Request, remove labelFunc) {
+	subID, err := parseID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	h.sendLabeled(w, r, remove, subID, r.PathValue("name"))
+}
+
+func (h SubsHandler) sendLabeled(w http.ResponseWriter, r *http.Request, fn labelFunc, subID int32, name string) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	sub, err := fn(r.Context(), tenantID, subID, name)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSub(w, r, sub)
+}
+
+// @Summary GetSpendByCategory
+// @Description Get the cost of the matching subscriptions over a period by category, the largest first. A subscription in several categories counts in each, with user_id only the share of the user counts
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param from query string true "First month, MM-YYYY"
+// @Param to query string true "Last month, MM-YYYY"
+// @Param user_id query string false "User ID, if need to get the spend of a specific user"
+// @Param include_shared query bool false "With user_id, also count the subscriptions shared with the user"
+// @Param status query string false "Status of subscriptions" Enums(trial, active, paused, cancelled, expired)
+// @Param category query string false "Name of category"
+// @Param tag query string false "Tag"
+// @Router /api/subs/spend-by-category [GET]
+func (h SubsHandler) GetSpendByCategory(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+
+	from, to, err := parsePeriod(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse `from` or `to`", http.StatusBadRequest, err)
+		return
+	}
+	filter, err := parseFilter(r)
+	if errors.Is(err, service.ErrInvalid) {
+		sendServiceError(w, err)
+		return
+	}
+	if err != nil {
+		utils.SendError(w, "Error: could not parse url query", http.StatusBadRequest, err)
+		return
+	}
+
+	spend, err := h.Service.SpendByCategory(r.Context(), tenantID, filter, from, to)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	res := []categorySpendJSON{}
+	for _, c := range spend {
+		res = append(res, categorySpendJSON{Category: c.Category, Subscriptions: c.Subscriptions, Total: c.Total})
+	}
+
+	if err := utils.SendData(w, res, http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
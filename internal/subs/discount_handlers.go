@@ -0,0 +1,192 @@
+package subs
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+	"usersubs/internal/subs/service"
+	"usersubs/internal/tenant"
+	"usersubs/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+type discountJSON struct {
+	ID        int32                `json:"id,omitempty"`
+	Kind      service.DiscountKind `json:"kind" enums:"percent,fixed"`
+	Amount    int32                `json:"amount"`
+	StartedAt utils.JSONDate       `json:"start_date"`
+	// Either Cycles, a number of months, or Until is set.
+	Cycles int32     `json:"cycles,omitempty"`
+	Until  time.Time `json:"until,omitzero"`
+	// EndsAt is the first month charged in full again, read only.
+	EndsAt utils.JSONDate `json:"ends_at,omitzero"`
+}
+
+func toDiscountJSON(d service.Discount) discountJSON {
+	return discountJSON{
+		ID:        d.ID,
+		Kind:      d.Kind,
+		Amount:    d.Amount,
+		StartedAt: utils.JSONDate(d.StartDate),
+		Cycles:    d.Cycles,
+		Until:     d.Until,
+		EndsAt:    utils.JSONDate(d.EndsAt),
+	}
+}
+
+func toDiscountsJSON(discounts []service.Discount) []discountJSON {
+	res := []discountJSON{}
+	for _, d := range discounts {
+		res = append(res, toDiscountJSON(d))
+	}
+	return res
+}
+
+type expiringDiscountJSON struct {
+	SubscriptionID int32        `json:"subscription_id"`
+	ServiceName    string       `json:"service_name"`
+	UserID         uuid.UUID    `json:"user_id"`
+	Discount       discountJSON `json:"discount"`
+	// Charge is the last discounted charge, NextCharge the one after it.
+	Charge     int32 `json:"charge"`
+	NextCharge int32 `json:"next_charge"`
+}
+
+// @Summary PostDiscount
+// @Description Add a percent or fixed discount for a number of months (cycles) or until a date
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Param request body discountJSON true "Discount"
+// @Router /api/sub/{id}/discounts [POST]
+func (h SubsHandler) PostDiscount(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	subID, err := parseID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	var discount discountJSON
+	if err := json.NewDecoder(r.Body).Decode(&discount); err != nil {
+		utils.SendError(w, "Error: something went wrong on decoding json", http.StatusBadRequest, err)
+		return
+	}
+
+	added, err := h.Service.AddDiscount(r.Context(), tenantID, subID, service.Discount{
+		Kind:      discount.Kind,
+		Amount:    discount.Amount,
+		StartDate: time.Time(discount.StartedAt),
+		Cycles:    discount.Cycles,
+		Until:     discount.Until,
+	})
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, toDiscountJSON(added), http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// @Summary GetDiscounts
+// @Description Get the discounts of a subscription, the earliest first
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Router /api/sub/{id}/discounts [GET]
+func (h SubsHandler) GetDiscounts(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	subID, err := parseID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	discounts, err := h.Service.Discounts(r.Context(), tenantID, subID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, toDiscountsJSON(discounts), http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// @Summary DeleteDiscount
+// @Description Remove a discount
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Param discount_id path int true "ID of discount"
+// @Router /api/sub/{id}/discounts/{discount_id} [DELETE]
+func (h SubsHandler) DeleteDiscount(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	subID, err := parseID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+	discountID, err := strconv.ParseInt(r.PathValue("discount_id"), 10, 32)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.Service.RemoveDiscount(r.Context(), tenantID, subID, int32(discountID)); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, discountID, http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// @Summary GetExpiringDiscounts
+// @Description Get discounts ending within `days`, the soonest first, with the charge before and after
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param days query int false "Number of days ahead, 7 if not set"
+// @Param user_id query string false "User ID, if need to get discounts of a specific user"
+// @Router /api/subs/expiring-discounts [GET]
+func (h SubsHandler) GetExpiringDiscounts(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+
+	days, userID, err := parseSoon(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse url query", http.StatusBadRequest, err)
+		return
+	}
+
+	expiring, err := h.Service.ExpiringDiscounts(r.Context(), tenantID, userID, days)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	res := []expiringDiscountJSON{}
+	for _, e := range expiring {
+		res = append(res, expiringDiscountJSON{
+			SubscriptionID: e.Subscription.ID,
+			ServiceName:    e.Subscription.ServiceName,
+			UserID:         e.Subscription.UserID,
+			Discount:       toDiscountJSON(e.Discount),
+			Charge:         e.Charge,
+			NextCharge:     e.NextCharge,
+		})
+	}
+
+	if err := utils.SendData(w, res, http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
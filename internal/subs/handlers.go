@@ -2,14 +2,16 @@ package subs
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
-	"usersubs/internal/db"
+	"usersubs/internal/logging"
+	"usersubs/internal/subs/service"
+	"usersubs/internal/tenant"
 	"usersubs/internal/utils"
 
 	"github.com/google/uuid"
@@ -22,52 +24,158 @@ type subJSON struct {
 	UserID      uuid.UUID      `json:"user_id"`
 	StartedAt   utils.JSONDate `json:"start_date"`
 	EndedAt     utils.JSONDate `json:"end_date,omitzero"`
+	// TrialEndsAt is the day of the first paid charge, months before it
+	// cost TrialPrice.
+	TrialEndsAt time.Time `json:"trial_ends_at,omitzero"`
+	TrialPrice  int32     `json:"trial_price,omitempty"`
+	// Metadata is a flat object of up to 4 KB, kept on update when not set.
+	Metadata map[string]any `json:"metadata,omitempty"`
+
+	// Read only, set through the pause, price and status endpoints.
+	Pauses    []pauseJSON    `json:"pauses,omitempty"`
+	Prices    []priceJSON    `json:"prices,omitempty"`
+	Discounts []discountJSON `json:"discounts,omitempty"`
+	Members   []memberJSON   `json:"members,omitempty"`
+	// Categories and Tags are set through their endpoints.
+	Categories []string `json:"categories,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	// FirstChargeAt is the day of the first charge at the full price, the
+	// end of the trial or the start date.
+	FirstChargeAt time.Time `json:"first_charge_at"`
+	// Charge is the amount charged for the current month.
+	Charge            int32          `json:"charge"`
+	Status            service.Status `json:"status,omitempty"`
+	StatusChangedAt   time.Time      `json:"status_changed_at,omitzero"`
+	CancelAtPeriodEnd bool           `json:"cancel_at_period_end,omitempty"`
+	// Warnings are the budgets of the user over after a create or update.
+	Warnings []budgetStatusJSON `json:"warnings,omitempty"`
 }
 
+// defaultSoonDays is how far ahead GetEndingTrials and
+// GetExpiringDiscounts look without `days`.
+const defaultSoonDays = 7
+
+const dateFormat = "01-2006"
+
 type SubsHandler struct {
-	SubsRepo *db.Queries
+	Service *service.SubscriptionService
+}
+
+func toSubJSON(sub service.Subscription) subJSON {
+	return subJSON{
+		ID:          sub.ID,
+		ServiceName: sub.ServiceName,
+		Price:       sub.Price,
+		UserID:      sub.UserID,
+		StartedAt:   utils.JSONDate(sub.StartDate),
+		EndedAt:     utils.JSONDate(sub.EndDate),
+		TrialEndsAt: sub.TrialEndsAt,
+		TrialPrice:  sub.TrialPrice,
+		Metadata:    sub.Metadata,
+
+		Pauses:            toPausesJSON(sub.Pauses),
+		Prices:            toPricesJSON(sub.Prices),
+		Discounts:         toDiscountsJSON(sub.Discounts),
+		Members:           toMembersJSON(sub.Members),
+		Categories:        sub.Categories,
+		Tags:              sub.Tags,
+		FirstChargeAt:     sub.FirstChargeAt(),
+		Charge:            sub.Charge,
+		Status:            sub.Status,
+		StatusChangedAt:   sub.StatusChangedAt,
+		CancelAtPeriodEnd: sub.CancelAtPeriodEnd,
+		Warnings:          toBudgetStatusesJSON(sub.OverBudget),
+	}
+}
+
+func (s subJSON) toService() service.Subscription {
+	return service.Subscription{
+		ID:          s.ID,
+		ServiceName: s.ServiceName,
+		Price:       s.Price,
+		UserID:      s.UserID,
+		StartDate:   time.Time(s.StartedAt),
+		EndDate:     time.Time(s.EndedAt),
+		TrialEndsAt: s.TrialEndsAt,
+		TrialPrice:  s.TrialPrice,
+		Metadata:    s.Metadata,
+	}
 }
 
 // @Summary GetSubs
 // @Description Get all subscriptions
 // @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
 // @Param user_id query string false "User ID, if need to get all subscriptions of a specific user"
+// @Param include_shared query bool false "With user_id, also get the subscriptions shared with the user, now or before"
+// @Param status query string false "Status of subscriptions" Enums(trial, active, paused, cancelled, expired)
+// @Param category query string false "Name of category"
+// @Param tag query string false "Tag"
+// @Param metadata.key query string false "Value of the metadata key, matches the string or the number or boolean it reads as, any number of keys"
+// @Param limit query int false "Max number of subscriptions, all if not set"
+// @Param offset query int false "Number of subscriptions to skip, ordered by ID"
 // @Router /api/subs [GET]
 func (h SubsHandler) GetSubs(w http.ResponseWriter, r *http.Request) {
-	log.Println("GET /api/subs - Receive request")
-	var (
-		subsDB []db.Subscription
-		err    error
-	)
+	tenantID, _ := tenant.FromContext(r.Context())
 
-	user_id := r.URL.Query().Get("user_id")
-	if user_id != "" {
-		id, err := uuid.Parse(user_id)
-		if err != nil {
-			utils.SendError(w, "Error: could not parse url query", http.StatusInternalServerError, err)
-			return
-		}
+	limit, offset, err := parsePage(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse `limit` or `offset`", http.StatusBadRequest, err)
+		return
+	}
+	filter, err := parseFilter(r)
+	if errors.Is(err, service.ErrInvalid) {
+		sendServiceError(w, err)
+		return
+	}
+	if err != nil {
+		utils.SendError(w, "Error: could not parse url query", http.StatusBadRequest, err)
+		return
+	}
+	filter.Limit, filter.Offset = limit, offset
 
-		subsDB, err = h.SubsRepo.GetUserSubs(context.Background(), id)
-	} else {
-		subsDB, err = h.SubsRepo.GetSubs(context.Background())
+	subsList, err := h.Service.List(r.Context(), tenantID, filter)
+	if err != nil {
+		sendServiceError(w, err)
+		return
 	}
 
+	subs := []subJSON{}
+	for _, sub := range subsList {
+		subs = append(subs, toSubJSON(sub))
+	}
+
+	if err := utils.SendData(w, subs, http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// @Summary GetEndingTrials
+// @Description Get trials converting to paid within `days`, the soonest first, `first_charge_at` is the day of the first paid charge
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param days query int false "Number of days ahead, 7 if not set"
+// @Param user_id query string false "User ID, if need to get trials of a specific user"
+// @Router /api/subs/ending-trials [GET]
+func (h SubsHandler) GetEndingTrials(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+
+	days, userID, err := parseSoon(r)
 	if err != nil {
-		utils.SendError(w, "Error: something went wrong on sql query", http.StatusInternalServerError, err)
+		utils.SendError(w, "Error: could not parse url query", http.StatusBadRequest, err)
+		return
+	}
+
+	trials, err := h.Service.EndingTrials(r.Context(), tenantID, userID, days)
+	if err != nil {
+		sendServiceError(w, err)
 		return
 	}
 
 	subs := []subJSON{}
-	for _, sub := range subsDB {
-		subs = append(subs, subJSON{
-			ID:          sub.ID,
-			ServiceName: sub.ServiceName,
-			Price:       sub.Price,
-			UserID:      sub.UserID,
-			StartedAt:   utils.JSONDate(sub.StartedAt),
-			EndedAt:     utils.JSONDate(sub.EndedAt.Time),
-		})
+	for _, sub := range trials {
+		subs = append(subs, toSubJSON(sub))
 	}
 
 	if err := utils.SendData(w, subs, http.StatusOK); err != nil {
@@ -79,138 +187,157 @@ func (h SubsHandler) GetSubs(w http.ResponseWriter, r *http.Request) {
 // @Summary GetSub
 // @Description Get a subscription by ID
 // @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
 // @Param id path int true "ID (int) of specific subscription"
 // @Router /api/sub/{id} [GET]
 func (h SubsHandler) GetSub(w http.ResponseWriter, r *http.Request) {
-	log.Println("GET /api/sub/{id} - Receive request")
+	tenantID, _ := tenant.FromContext(r.Context())
 
-	pathID := r.PathValue("id")
-	subID, err := strconv.ParseInt(pathID, 10, 32)
+	subID, err := parseID(r)
 	if err != nil {
-		utils.SendError(w, "Error: could not parse path value", http.StatusInternalServerError, err)
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
 		return
 	}
 
-	sub, err := h.SubsRepo.GetSub(context.Background(), int32(subID))
+	sub, err := h.Service.Get(r.Context(), tenantID, subID)
 	if err != nil {
-		utils.SendError(w, "Error: something went wrong on sql query", http.StatusInternalServerError, err)
+		sendServiceError(w, err)
 		return
 	}
 
-	s := subJSON{
-		ID:          int32(subID),
-		ServiceName: sub.ServiceName,
-		Price:       sub.Price,
-		UserID:      sub.UserID,
-		StartedAt:   utils.JSONDate(sub.StartedAt),
-		EndedAt:     utils.JSONDate(sub.EndedAt.Time),
-	}
-
-	if err := utils.SendData(w, s, http.StatusOK); err != nil {
-		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
-		return
-	}
+	sendSub(w, r, sub)
 }
 
 // @Summary PostSub
-// @Description Create a subscription
+// @Description Create a subscription, `warnings` lists the budgets of the user it takes over, 409 if one of them is enforced
 // @Accept json
 // @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
 // @Param request body subJSON true "Structure of new subscription"
 // @Router /api/sub [POST]
 func (h SubsHandler) PostSub(w http.ResponseWriter, r *http.Request) {
-	log.Println("POST /api/sub - Receive request")
+	tenantID, _ := tenant.FromContext(r.Context())
 	var sub subJSON
 	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
-		utils.SendError(w, "Error: something went wrong on decoding json", http.StatusInternalServerError, err)
+		utils.SendError(w, "Error: something went wrong on decoding json", http.StatusBadRequest, err)
 		return
 	}
 
-	params := db.AddSubParams{
-		ServiceName: sub.ServiceName,
-		Price:       sub.Price,
-		UserID:      sub.UserID,
-		StartedAt:   time.Time(sub.StartedAt),
-		EndedAt:     sql.NullTime{Time: time.Time(sub.EndedAt), Valid: true},
-	}
-
-	id, err := h.SubsRepo.AddSub(context.Background(), params)
+	created, err := h.Service.Create(r.Context(), tenantID, sub.toService())
 	if err != nil {
-		utils.SendError(w, "Error: something went wrong on sql query", http.StatusInternalServerError, err)
+		sendServiceError(w, err)
 		return
 	}
 
-	sub.ID = id
-
-	if err := utils.SendData(w, sub, http.StatusOK); err != nil {
-		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
-		return
-	}
+	sendSub(w, r, created)
 }
 
 // @Summary PutSub
-// @Description Update a subscription
+// @Description Update a subscription, budgets are checked like on create
 // @Accept json
 // @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
 // @Param id path int true "ID of subscription"
 // @Param request body subJSON true "Structure of subscription"
 // @Router /api/sub/{id} [PUT]
 func (h SubsHandler) PutSub(w http.ResponseWriter, r *http.Request) {
-	log.Println("PUT /api/sub/{id} - Receive request")
-	pathID := r.PathValue("id")
-	subID, err := strconv.ParseInt(pathID, 10, 32)
+	tenantID, _ := tenant.FromContext(r.Context())
+	subID, err := parseID(r)
 	if err != nil {
-		utils.SendError(w, "Error: could not parse path value", http.StatusInternalServerError, err)
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
 		return
 	}
 
 	var sub subJSON
 	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
-		utils.SendError(w, "Error: something went wrong on decoding json", http.StatusInternalServerError, err)
+		utils.SendError(w, "Error: something went wrong on decoding json", http.StatusBadRequest, err)
 		return
 	}
+	sub.ID = subID
 
-	params := db.UpdateSubParams{
-		ID:          int32(subID),
-		ServiceName: sub.ServiceName,
-		Price:       sub.Price,
-		UserID:      sub.UserID,
-		StartedAt:   time.Time(sub.StartedAt),
-		EndedAt:     sql.NullTime{Time: time.Time(sub.EndedAt), Valid: true},
-		UpdatedAt:   time.Now(),
+	updated, err := h.Service.Update(r.Context(), tenantID, sub.toService())
+	if err != nil {
+		sendServiceError(w, err)
+		return
 	}
 
-	id, err := h.SubsRepo.UpdateSub(context.Background(), params)
+	sendSub(w, r, updated)
+}
+
+// patchJSON holds the fields of PatchSub, the ones not set are kept.
+type patchJSON struct {
+	ServiceName *string         `json:"service_name,omitempty"`
+	Price       *int32          `json:"price,omitempty"`
+	UserID      *uuid.UUID      `json:"user_id,omitempty"`
+	StartedAt   *utils.JSONDate `json:"start_date,omitempty"`
+	EndedAt     *utils.JSONDate `json:"end_date,omitempty"`
+	// Metadata is merged, a null value removes its key.
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// @Summary PatchSub
+// @Description Update some fields of a subscription and merge its metadata, budgets are checked like on create
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Param request body patchJSON true "Fields to change"
+// @Router /api/sub/{id} [PATCH]
+func (h SubsHandler) PatchSub(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	subID, err := parseID(r)
 	if err != nil {
-		utils.SendError(w, "Error: something went wrong on sql query", http.StatusInternalServerError, err)
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
 		return
 	}
 
-	sub.ID = id
+	var patch patchJSON
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		utils.SendError(w, "Error: something went wrong on decoding json", http.StatusBadRequest, err)
+		return
+	}
 
-	if err := utils.SendData(w, sub, http.StatusOK); err != nil {
-		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+	p := service.Patch{
+		ServiceName: patch.ServiceName,
+		Price:       patch.Price,
+		UserID:      patch.UserID,
+		Metadata:    patch.Metadata,
+	}
+	if patch.StartedAt != nil {
+		start := time.Time(*patch.StartedAt)
+		p.StartDate = &start
+	}
+	if patch.EndedAt != nil {
+		end := time.Time(*patch.EndedAt)
+		p.EndDate = &end
+	}
+
+	patched, err := h.Service.Patch(r.Context(), tenantID, subID, p)
+	if err != nil {
+		sendServiceError(w, err)
 		return
 	}
+
+	sendSub(w, r, patched)
 }
 
 // @Summary DeleteSub
 // @Description Delete a subscription
 // @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
 // @Param id path int true "ID of subscription"
 // @Router /api/sub/{id} [DELETE]
 func (h SubsHandler) DeleteSub(w http.ResponseWriter, r *http.Request) {
-	log.Println("DELETE /api/sub/{id} - Receive request")
-	pathID := r.PathValue("id")
-	subID, err := strconv.ParseInt(pathID, 10, 32)
+	tenantID, _ := tenant.FromContext(r.Context())
+	subID, err := parseID(r)
 	if err != nil {
-		utils.SendError(w, "Error: could not parse path value", http.StatusInternalServerError, err)
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
 		return
 	}
 
-	id, err := h.SubsRepo.DeleteSub(context.Background(), int32(subID))
+	id, err := h.Service.Delete(r.Context(), tenantID, subID)
 	if err != nil {
-		utils.SendError(w, "Error: something went wrong on sql query", http.StatusInternalServerError, err)
+		sendServiceError(w, err)
 		return
 	}
 
@@ -223,10 +350,11 @@ func (h SubsHandler) DeleteSub(w http.ResponseWriter, r *http.Request) {
 // @Summary DeleteUserSubs
 // @Description Delete all subscriptions of specific user
 // @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
 // @Param user_id query string true "User ID"
 // @Router /api/subs [DELETE]
 func (h SubsHandler) DeleteUserSubs(w http.ResponseWriter, r *http.Request) {
-	log.Println("DELETE /api/subs - Receive request")
+	tenantID, _ := tenant.FromContext(r.Context())
 	user_id := r.URL.Query().Get("user_id")
 	if user_id == "" {
 		utils.SendError(w, "Error: query param `user_id` is not provided", http.StatusBadRequest, errors.New("no user_id"))
@@ -235,14 +363,13 @@ func (h SubsHandler) DeleteUserSubs(w http.ResponseWriter, r *http.Request) {
 
 	id, err := uuid.Parse(user_id)
 	if err != nil {
-		utils.SendError(w, "Error: could not parse url query", http.StatusInternalServerError, err)
+		utils.SendError(w, "Error: could not parse url query", http.StatusBadRequest, err)
 		return
 	}
 
-	ids, err := h.SubsRepo.DeleteUserSubs(context.Background(), id)
-
+	ids, err := h.Service.DeleteUser(r.Context(), tenantID, id)
 	if err != nil {
-		utils.SendError(w, "Error: something went wrong on sql query", http.StatusInternalServerError, err)
+		sendServiceError(w, err)
 		return
 	}
 
@@ -251,3 +378,146 @@ func (h SubsHandler) DeleteUserSubs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// parsePage reads the optional `limit` and `offset` query params, a zero
+// limit means no limit.
+func parsePage(r *http.Request) (int32, int32, error) {
+	limit, err := queryCount(r, "limit")
+	if err != nil {
+		return 0, 0, err
+	}
+	offset, err := queryCount(r, "offset")
+	if err != nil {
+		return 0, 0, err
+	}
+	return limit, offset, nil
+}
+
+// queryCount reads a non-negative query param, zero when it is not set.
+func queryCount(r *http.Request, name string) (int32, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 32)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("bad %s %q", name, s)
+	}
+	return int32(n), nil
+}
+
+// parseFilter reads the `user_id`, `include_shared`, `status`, `category`,
+// `tag` and `metadata.*` query params, an unknown status is a
+// *service.ValidationError.
+func parseFilter(r *http.Request) (service.ListFilter, error) {
+	query := r.URL.Query()
+	filter := service.ListFilter{Category: query.Get("category"), Tag: query.Get("tag")}
+	for key, values := range query {
+		if k, ok := strings.CutPrefix(key, "metadata."); ok {
+			if filter.Metadata == nil {
+				filter.Metadata = map[string]string{}
+			}
+			filter.Metadata[k] = values[0]
+		}
+	}
+
+	var err error
+	if user_id := query.Get("user_id"); user_id != "" {
+		filter.UserID, err = uuid.Parse(user_id)
+		if err != nil {
+			return filter, err
+		}
+	}
+	if s := query.Get("include_shared"); s != "" {
+		filter.IncludeShared, err = strconv.ParseBool(s)
+		if err != nil {
+			return filter, err
+		}
+	}
+	if status := query.Get("status"); status != "" {
+		filter.Status, err = service.ParseStatus(status)
+	}
+	return filter, err
+}
+
+// parsePeriod reads the `from` and `to` months.
+func parsePeriod(r *http.Request) (time.Time, time.Time, error) {
+	f, err := time.Parse(dateFormat, r.URL.Query().Get("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	t, err := time.Parse(dateFormat, r.URL.Query().Get("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if t.Before(f) {
+		return time.Time{}, time.Time{}, errors.New("to is before from")
+	}
+	return f, t, nil
+}
+
+// parseSoon reads the optional `days` and `user_id` query params of the
+// listings of what changes soon.
+func parseSoon(r *http.Request) (int, uuid.UUID, error) {
+	days := defaultSoonDays
+	if r.URL.Query().Has("days") {
+		n, err := queryCount(r, "days")
+		if err != nil {
+			return 0, uuid.Nil, err
+		}
+		days = int(n)
+	}
+
+	var userID uuid.UUID
+	if user_id := r.URL.Query().Get("user_id"); user_id != "" {
+		var err error
+		userID, err = uuid.Parse(user_id)
+		if err != nil {
+			return 0, uuid.Nil, err
+		}
+	}
+	return days, userID, nil
+}
+
+// sendSub writes sub and logs the request as one of its owner.
+func sendSub(w http.ResponseWriter, r *http.Request, sub service.Subscription) {
+	logging.SetUser(r.Context(), sub.UserID.String())
+	if err := utils.SendData(w, toSubJSON(sub), http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+	}
+}
+
+func parseID(r *http.Request) (int32, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	return int32(id), err
+}
+
+// parseUser reads the user in the path value name and logs the request as
+// one of the user.
+func parseUser(r *http.Request, name string) (uuid.UUID, error) {
+	userID, err := uuid.Parse(r.PathValue(name))
+	if err == nil {
+		logging.SetUser(r.Context(), userID.String())
+	}
+	return userID, err
+}
+
+// sendServiceError reports invalid input as a bad request, a missing row
+// (including a row of another tenant) as not found, a change the stored
+// data does not allow (a status change, an enforced budget, a taken name)
+// as a conflict, an exceeded deadline as a timeout, any other error as a
+// failed query.
+func sendServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalid):
+		utils.SendError(w, "Error: "+err.Error(), http.StatusBadRequest, err)
+	case errors.Is(err, service.ErrNotFound):
+		utils.SendError(w, "Error: "+err.Error(), http.StatusNotFound, err)
+	case errors.Is(err, service.ErrConflict):
+		utils.SendError(w, "Error: "+err.Error(), http.StatusConflict, err)
+	case errors.Is(err, context.DeadlineExceeded):
+		utils.SendError(w, "Error: sql query timed out", http.StatusGatewayTimeout, err)
+	default:
+		utils.SendError(w, "Error: something went wrong on sql query", http.StatusInternalServerError, err)
+	}
+}
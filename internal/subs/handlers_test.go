@@ -0,0 +1,33 @@
+package subs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"usersubs/internal/subs/service"
+)
+
+func TestSendServiceError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: &service.ValidationError{Field: "price", Message: "must not be negative"}, want: http.StatusBadRequest},
+		{err: service.ErrNotFound, want: http.StatusNotFound},
+		{err: &service.TransitionError{From: service.StatusCancelled, Allowed: []service.Status{service.StatusActive}}, want: http.StatusConflict},
+		{err: &service.BudgetError{Status: service.BudgetStatus{Remaining: -10}}, want: http.StatusConflict},
+		{err: service.ErrCategoryExists, want: http.StatusConflict},
+		{err: fmt.Errorf("TENANT TX - %w", context.DeadlineExceeded), want: http.StatusGatewayTimeout},
+		{err: errors.New("connection reset"), want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		sendServiceError(w, tt.err)
+		if w.Code != tt.want {
+			t.Errorf("sendServiceError(%v) code = %d, want %d", tt.err, w.Code, tt.want)
+		}
+	}
+}
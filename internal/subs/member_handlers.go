@@ -0,0 +1,192 @@
+package subs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+	"usersubs/internal/subs/service"
+	"usersubs/internal/tenant"
+	"usersubs/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+type memberJSON struct {
+	UserID uuid.UUID     `json:"user_id"`
+	Split  service.Split `json:"split" enums:"equal,percent,fixed"`
+	// Amount is a percent or a fixed sum, empty for an equal split.
+	Amount int32 `json:"amount,omitempty"`
+	// Read only.
+	Status    service.MemberStatus `json:"status,omitempty" enums:"invited,active"`
+	Share     int32                `json:"share"`
+	CreatedAt time.Time            `json:"created_at,omitzero"`
+	// The member pays from the month of joined_at up to the month before
+	// removed_at.
+	JoinedAt  time.Time `json:"joined_at,omitzero"`
+	RemovedAt time.Time `json:"removed_at,omitzero"`
+}
+
+func toMemberJSON(m service.Member) memberJSON {
+	return memberJSON{
+		UserID:    m.UserID,
+		Split:     m.Split,
+		Amount:    m.Amount,
+		Status:    m.Status,
+		Share:     m.Share,
+		CreatedAt: m.CreatedAt,
+		JoinedAt:  m.JoinedAt,
+		RemovedAt: m.RemovedAt,
+	}
+}
+
+func toMembersJSON(members []service.Member) []memberJSON {
+	res := []memberJSON{}
+	for _, m := range members {
+		res = append(res, toMemberJSON(m))
+	}
+	return res
+}
+
+// @Summary PostMember
+// @Description Share a subscription with a user, who pays their part of the charge from the current month
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Param request body memberJSON true "Member"
+// @Router /api/sub/{id}/members [POST]
+func (h SubsHandler) PostMember(w http.ResponseWriter, r *http.Request) {
+	h.addMember(w, r, h.Service.AddMember)
+}
+
+// @Summary PostInvite
+// @Description Invite a user to share a subscription, they pay nothing until the invite is accepted
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Param request body memberJSON true "Member"
+// @Router /api/sub/{id}/invites [POST]
+func (h SubsHandler) PostInvite(w http.ResponseWriter, r *http.Request) {
+	h.addMember(w, r, h.Service.InviteMember)
+}
+
+func (h SubsHandler) addMember(w http.ResponseWriter, r *http.Request, add func(ctx context.Context, tenantID uuid.UUID, subID int32, m service.Member) (service.Member, error)) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	subID, err := parseID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	var member memberJSON
+	if err := json.NewDecoder(r.Body).Decode(&member); err != nil {
+		utils.SendError(w, "Error: something went wrong on decoding json", http.StatusBadRequest, err)
+		return
+	}
+
+	added, err := add(r.Context(), tenantID, subID, service.Member{
+		UserID: member.UserID,
+		Split:  member.Split,
+		Amount: member.Amount,
+	})
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, toMemberJSON(added), http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// @Summary AcceptInvite
+// @Description Accept an invite, the user pays their part of the charge from the current month
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Param user_id path string true "ID of invited user"
+// @Router /api/sub/{id}/invites/{user_id}/accept [POST]
+func (h SubsHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	subID, err := parseID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+	userID, err := parseUser(r, "user_id")
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	member, err := h.Service.AcceptInvite(r.Context(), tenantID, subID, userID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, toMemberJSON(member), http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// @Summary GetMembers
+// @Description Get the members and invites of a subscription with their share of the current month, removed members have removed_at
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Router /api/sub/{id}/members [GET]
+func (h SubsHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	subID, err := parseID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	members, err := h.Service.Members(r.Context(), tenantID, subID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, toMembersJSON(members), http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// @Summary DeleteMember
+// @Description Remove a member or an invite, the owner pays their share from the current month, past months keep the split
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Param user_id path string true "ID of member"
+// @Router /api/sub/{id}/members/{user_id} [DELETE]
+func (h SubsHandler) DeleteMember(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	subID, err := parseID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+	userID, err := parseUser(r, "user_id")
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.Service.RemoveMember(r.Context(), tenantID, subID, userID); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, userID, http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
@@ -0,0 +1,125 @@
+package subs
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+	"usersubs/internal/subs/service"
+	"usersubs/internal/tenant"
+	"usersubs/internal/utils"
+)
+
+// pauseJSON has no end_date while the subscription is paused, the pause
+// lasts until it is resumed.
+type pauseJSON struct {
+	ID        int32          `json:"id,omitempty"`
+	StartedAt utils.JSONDate `json:"start_date"`
+	EndedAt   utils.JSONDate `json:"end_date,omitzero"`
+}
+
+func toPauseJSON(p service.Pause) pauseJSON {
+	return pauseJSON{ID: p.ID, StartedAt: utils.JSONDate(p.StartDate), EndedAt: utils.JSONDate(p.EndDate)}
+}
+
+func toPausesJSON(pauses []service.Pause) []pauseJSON {
+	res := []pauseJSON{}
+	for _, p := range pauses {
+		res = append(res, toPauseJSON(p))
+	}
+	return res
+}
+
+// @Summary PostPause
+// @Description Pause a subscription for a range of months, both included
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Param request body pauseJSON true "Months of the pause"
+// @Router /api/sub/{id}/pauses [POST]
+func (h SubsHandler) PostPause(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	subID, err := parseID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	var pause pauseJSON
+	if err := json.NewDecoder(r.Body).Decode(&pause); err != nil {
+		utils.SendError(w, "Error: something went wrong on decoding json", http.StatusBadRequest, err)
+		return
+	}
+
+	added, err := h.Service.AddPause(r.Context(), tenantID, subID, service.Pause{
+		StartDate: time.Time(pause.StartedAt),
+		EndDate:   time.Time(pause.EndedAt),
+	})
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, toPauseJSON(added), http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// @Summary GetPauses
+// @Description Get the pauses of a subscription, the earliest first. The pause of a paused subscription has no end_date
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Router /api/sub/{id}/pauses [GET]
+func (h SubsHandler) GetPauses(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	subID, err := parseID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	pauses, err := h.Service.Pauses(r.Context(), tenantID, subID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, toPausesJSON(pauses), http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// @Summary DeletePause
+// @Description Remove a pause, its months are charged again
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Param pause_id path int true "ID of pause"
+// @Router /api/sub/{id}/pauses/{pause_id} [DELETE]
+func (h SubsHandler) DeletePause(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	subID, err := parseID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+	pauseID, err := strconv.ParseInt(r.PathValue("pause_id"), 10, 32)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.Service.RemovePause(r.Context(), tenantID, subID, int32(pauseID)); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, pauseID, http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
@@ -0,0 +1,94 @@
+package subs
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+	"usersubs/internal/subs/service"
+	"usersubs/internal/tenant"
+	"usersubs/internal/utils"
+)
+
+type priceJSON struct {
+	ID            int32          `json:"id,omitempty"`
+	EffectiveFrom utils.JSONDate `json:"effective_from"`
+	Price         int32          `json:"price"`
+	Currency      string         `json:"currency,omitempty"`
+}
+
+func toPricesJSON(prices []service.Price) []priceJSON {
+	res := []priceJSON{}
+	for _, p := range prices {
+		res = append(res, priceJSON{
+			ID:            p.ID,
+			EffectiveFrom: utils.JSONDate(p.EffectiveFrom),
+			Price:         p.Price,
+			Currency:      p.Currency,
+		})
+	}
+	return res
+}
+
+// @Summary PostPrice
+// @Description Schedule a price change from a month on, earlier months keep their price
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Param request body priceJSON true "New price, currency is RUB if not set"
+// @Router /api/sub/{id}/prices [POST]
+func (h SubsHandler) PostPrice(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	subID, err := parseID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	var price priceJSON
+	if err := json.NewDecoder(r.Body).Decode(&price); err != nil {
+		utils.SendError(w, "Error: something went wrong on decoding json", http.StatusBadRequest, err)
+		return
+	}
+
+	prices, err := h.Service.SchedulePrice(r.Context(), tenantID, subID, service.Price{
+		EffectiveFrom: time.Time(price.EffectiveFrom),
+		Price:         price.Price,
+		Currency:      price.Currency,
+	})
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, toPricesJSON(prices), http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// @Summary GetPrices
+// @Description Get the price timeline of a subscription, starting with the price from its start date
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Router /api/sub/{id}/prices [GET]
+func (h SubsHandler) GetPrices(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	subID, err := parseID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	prices, err := h.Service.Prices(r.Context(), tenantID, subID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := utils.SendData(w, toPricesJSON(prices), http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
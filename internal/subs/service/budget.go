@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+	"usersubs/internal/db"
+
+	"github.com/google/uuid"
+)
+
+type BudgetScope string
+
+const (
+	// BudgetOverall covers every subscription of the user.
+	BudgetOverall BudgetScope = "overall"
+	// BudgetService covers the subscriptions of the service named by Target.
+	BudgetService BudgetScope = "service"
+	// BudgetCategory covers the subscriptions in the category named by
+	// Target.
+	BudgetCategory BudgetScope = "category"
+)
+
+type BudgetPeriod string
+
+const (
+	// BudgetMonth limits the charges of the current month.
+	BudgetMonth BudgetPeriod = "month"
+	// BudgetYear limits the charges of the current calendar year.
+	BudgetYear BudgetPeriod = "year"
+)
+
+// Budget limits what a user pays in Currency over a period, other
+// currencies are not converted and do not count.
+type Budget struct {
+	ID     int32
+	UserID uuid.UUID
+	Scope  BudgetScope
+	// Target is empty for BudgetOverall.
+	Target   string
+	Amount   int32
+	Currency string
+	Period   BudgetPeriod
+	// Enforce rejects creating or updating a subscription that takes the
+	// user over the budget, otherwise it is only reported.
+	Enforce   bool
+	CreatedAt time.Time
+}
+
+func fromBudgetRow(row db.Budget) Budget {
+	return Budget{
+		ID:        row.ID,
+		UserID:    row.UserID,
+		Scope:     BudgetScope(row.Scope),
+		Target:    row.Target,
+		Amount:    row.Amount,
+		Currency:  row.Currency,
+		Period:    BudgetPeriod(row.Period),
+		Enforce:   row.Enforce,
+		CreatedAt: row.CreatedAt,
+	}
+}
+
+// window is the first and last month of the period of b around now.
+func (b Budget) window(now time.Time) (time.Time, time.Time) {
+	month := monthOf(now)
+	if b.Period == BudgetYear {
+		from := time.Date(month.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(0, 11, 0)
+	}
+	return month, month
+}
+
+// covers tells if sub counts towards b at now: it is in the currency of b
+// and of the service or in the category of a service or category budget.
+// Paused, cancelled and expired months are left out by the cost itself,
+// see ActiveMonths.
+func (b Budget) covers(sub Subscription, now time.Time) bool {
+	if b.Scope == BudgetService && sub.ServiceName != b.Target {
+		return false
+	}
+	if b.Scope == BudgetCategory && !slices.Contains(sub.Categories, b.Target) {
+		return false
+	}
+	return sub.CurrencyAt(monthOf(now)) == b.Currency
+}
+
+// BudgetStatus compares a budget to the projected spend of its user: the
+// share of the user in the subscriptions it covers, for every month of the
+// period from From to To, as if they all keep running. Past months of a
+// yearly budget are included.
+type BudgetStatus struct {
+	Budget    Budget
+	From      time.Time
+	To        time.Time
+	Spent     int64
+	Remaining int64
+	Over      bool
+}
+
+func budgetStatusOf(b Budget, subs []Subscription, now time.Time) BudgetStatus {
+	from, to := b.window(now)
+	status := BudgetStatus{Budget: b, From: from, To: to}
+	for _, sub := range subs {
+		if b.covers(sub, now) {
+			status.Spent += sub.ShareCost(b.UserID, from, to)
+		}
+	}
+	status.Remaining = int64(b.Amount) - status.Spent
+	status.Over = status.Remaining < 0
+	return status
+}
+
+func validateBudget(b Budget, budgets []Budget) error {
+	switch {
+	case b.UserID == uuid.Nil:
+		return &ValidationError{Field: "user_id", Message: "is empty"}
+	case b.Scope != BudgetOverall && b.Scope != BudgetService && b.Scope != BudgetCategory:
+		return &ValidationError{Field: "scope", Message: "must be overall, service or category"}
+	case b.Scope == BudgetOverall && b.Target != "":
+		return &ValidationError{Field: "target", Message: "must be empty for an overall budget"}
+	case b.Scope != BudgetOverall && b.Target == "":
+		return &ValidationError{Field: "target", Message: "is empty"}
+	case b.Amount <= 0:
+		return &ValidationError{Field: "amount", Message: "must be positive"}
+	case !validCurrency(b.Currency):
+		return &ValidationError{Field: "currency", Message: "must be an ISO 4217 code like RUB"}
+	case b.Period != BudgetMonth && b.Period != BudgetYear:
+		return &ValidationError{Field: "period", Message: "must be month or year"}
+	}
+	for _, other := range budgets {
+		if other.Scope == b.Scope && other.Target == b.Target && other.Currency == b.Currency && other.Period == b.Period {
+			return &ValidationError{Field: "scope", Message: fmt.Sprintf("already has budget %d", other.ID)}
+		}
+	}
+	return nil
+}
+
+// budgetStatuses reads the budgets of userID and compares them to the
+// subscriptions of the user at now, in the order of the budgets.
+func budgetStatuses(ctx context.Context, q Queries, tenantID, userID uuid.UUID, now time.Time) ([]BudgetStatus, error) {
+	rows, err := q.GetBudgets(ctx, db.GetBudgetsParams{UserID: userID, TenantID: tenantID})
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+
+	filter := ListFilter{UserID: userID, IncludeShared: true}
+	subRows, err := q.FilterSubs(ctx, filter.params(tenantID, now))
+	if err != nil {
+		return nil, err
+	}
+	subs, err := load(ctx, q, tenantID, subRows, now)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]BudgetStatus, 0, len(rows))
+	for _, row := range rows {
+		statuses = append(statuses, budgetStatusOf(fromBudgetRow(row), subs, now))
+	}
+	return statuses, nil
+}
+
+// checkBudgets returns the budgets over after a change, before and after
+// are budgetStatuses of the same user read in the same transaction. It
+// fails with a *BudgetError when the change took the spend of an enforced
+// budget up and over it.
+func checkBudgets(before, after []BudgetStatus) ([]BudgetStatus, error) {
+	var over []BudgetStatus
+	for i, status := range after {
+		if !status.Over {
+			continue
+		}
+		if status.Budget.Enforce && status.Spent > before[i].Spent {
+			return nil, &BudgetError{Status: status}
+		}
+		over = append(over, status)
+	}
+	return over, nil
+}
+
+func (s *SubscriptionService) AddBudget(ctx context.Context, tenantID uuid.UUID, b Budget) (Budget, error) {
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		rows, err := q.GetBudgets(ctx, db.GetBudgetsParams{UserID: b.UserID, TenantID: tenantID})
+		if err != nil {
+			return err
+		}
+		budgets := make([]Budget, 0, len(rows))
+		for _, row := range rows {
+			budgets = append(budgets, fromBudgetRow(row))
+		}
+		if err := validateBudget(b, budgets); err != nil {
+			return err
+		}
+
+		added, err := q.AddBudget(ctx, db.AddBudgetParams{
+			TenantID: tenantID,
+			UserID:   b.UserID,
+			Scope:    string(b.Scope),
+			Target:   b.Target,
+			Amount:   b.Amount,
+			Currency: b.Currency,
+			Period:   string(b.Period),
+			Enforce:  b.Enforce,
+		})
+		if err != nil {
+			return err
+		}
+		b = fromBudgetRow(added)
+		return nil
+	})
+	if err != nil {
+		return Budget{}, err
+	}
+	return b, nil
+}
+
+// Budgets lists the budgets of a user in the order they were added.
+func (s *SubscriptionService) Budgets(ctx context.Context, tenantID, userID uuid.UUID) ([]Budget, error) {
+	budgets := []Budget{}
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		rows, err := q.GetBudgets(ctx, db.GetBudgetsParams{UserID: userID, TenantID: tenantID})
+		for _, row := range rows {
+			budgets = append(budgets, fromBudgetRow(row))
+		}
+		return err
+	})
+	return budgets, err
+}
+
+func (s *SubscriptionService) RemoveBudget(ctx context.Context, tenantID, userID uuid.UUID, budgetID int32) error {
+	return s.run(ctx, tenantID, func(q Queries) error {
+		_, err := q.DeleteBudget(ctx, db.DeleteBudgetParams{ID: budgetID, UserID: userID, TenantID: tenantID})
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrBudgetNotFound
+		}
+		return err
+	})
+}
+
+// BudgetStatus compares every budget of the user to their projected spend.
+func (s *SubscriptionService) BudgetStatus(ctx context.Context, tenantID, userID uuid.UUID) ([]BudgetStatus, error) {
+	now := s.now(ctx)
+	var statuses []BudgetStatus
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		var err error
+		statuses, err = budgetStatuses(ctx, q, tenantID, userID, now)
+		return err
+	})
+	return statuses, err
+}
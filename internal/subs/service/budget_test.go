@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"usersubs/internal/db"
+
+	"github.com/google/uuid"
+)
+
+func addBudget(repo *fakeRepo, b Budget) {
+	repo.data.budgets = append(repo.data.budgets, db.Budget{
+		ID:       int32(len(repo.data.budgets) + 1),
+		TenantID: testTenant,
+		UserID:   b.UserID,
+		Scope:    string(b.Scope),
+		Target:   b.Target,
+		Amount:   b.Amount,
+		Currency: b.Currency,
+		Period:   string(b.Period),
+		Enforce:  b.Enforce,
+	})
+}
+
+// Create and Update compare the budgets of the owner before and after the
+// change, only a change taking an enforced budget up and over is refused.
+func TestBudgetChecks(t *testing.T) {
+	tests := []struct {
+		name     string
+		budget   Budget
+		change   func(svc *SubscriptionService, stored Subscription) (Subscription, error)
+		wantErr  error
+		wantOver int
+		wantSubs int
+	}{
+		{
+			name:   "create within budget",
+			budget: Budget{Amount: 300},
+			change: func(svc *SubscriptionService, stored Subscription) (Subscription, error) {
+				return svc.Create(context.Background(), testTenant, validSub())
+			},
+			wantSubs: 2,
+		},
+		{
+			name:   "create over budget",
+			budget: Budget{Amount: 150},
+			change: func(svc *SubscriptionService, stored Subscription) (Subscription, error) {
+				return svc.Create(context.Background(), testTenant, validSub())
+			},
+			wantOver: 1, wantSubs: 2,
+		},
+		{
+			name:   "create over enforced budget",
+			budget: Budget{Amount: 150, Enforce: true},
+			change: func(svc *SubscriptionService, stored Subscription) (Subscription, error) {
+				return svc.Create(context.Background(), testTenant, validSub())
+			},
+			wantErr: ErrBudgetExceeded, wantSubs: 1,
+		},
+		{
+			name:   "other service",
+			budget: Budget{Scope: BudgetService, Target: "Netflix", Amount: 150, Enforce: true},
+			change: func(svc *SubscriptionService, stored Subscription) (Subscription, error) {
+				sub := validSub()
+				sub.ServiceName = "Spotify"
+				return svc.Create(context.Background(), testTenant, sub)
+			},
+			wantSubs: 2,
+		},
+		{
+			name:   "update over enforced budget",
+			budget: Budget{Amount: 150, Enforce: true},
+			change: func(svc *SubscriptionService, stored Subscription) (Subscription, error) {
+				stored.Price = 200
+				return svc.Update(context.Background(), testTenant, stored)
+			},
+			wantErr: ErrBudgetExceeded, wantSubs: 1,
+		},
+		{
+			name:   "update down while over enforced budget",
+			budget: Budget{Amount: 50, Enforce: true},
+			change: func(svc *SubscriptionService, stored Subscription) (Subscription, error) {
+				stored.Price = 80
+				return svc.Update(context.Background(), testTenant, stored)
+			},
+			wantOver: 1, wantSubs: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo := newTestService(month(time.March))
+			stored := mustCreate(t, svc, validSub())
+
+			b := tt.budget
+			b.UserID, b.Currency, b.Period = testUser, DefaultCurrency, BudgetMonth
+			if b.Scope == "" {
+				b.Scope = BudgetOverall
+			}
+			addBudget(repo, b)
+
+			got, err := tt.change(svc, stored)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			var budgetErr *BudgetError
+			if tt.wantErr != nil && (!errors.As(err, &budgetErr) || !errors.Is(err, ErrConflict)) {
+				t.Errorf("error %T is not a *BudgetError matching ErrConflict", err)
+			}
+			if len(got.OverBudget) != tt.wantOver {
+				t.Errorf("over budget = %v, want %d", got.OverBudget, tt.wantOver)
+			}
+			if len(repo.data.subs) != tt.wantSubs {
+				t.Errorf("stored %d subscriptions, want %d", len(repo.data.subs), tt.wantSubs)
+			}
+			if tt.wantErr != nil {
+				if current, _ := svc.Get(context.Background(), testTenant, stored.ID); current.Price != stored.Price {
+					t.Errorf("refused change stored the price %d", current.Price)
+				}
+			}
+		})
+	}
+}
+
+func TestBudgetStatusOf(t *testing.T) {
+	now := month(time.March).AddDate(0, 0, 14)
+	active := func(change func(sub *Subscription)) Subscription {
+		sub := Subscription{ServiceName: "Netflix", Price: 100, UserID: testUser, StartDate: month(time.January), Status: StatusActive}
+		change(&sub)
+		return sub
+	}
+	monthly := Budget{UserID: testUser, Scope: BudgetOverall, Amount: 100, Currency: DefaultCurrency, Period: BudgetMonth}
+	tests := []struct {
+		name      string
+		budget    func(b *Budget)
+		sub       Subscription
+		wantFrom  time.Time
+		wantTo    time.Time
+		wantSpent int64
+	}{
+		{name: "month", sub: active(func(sub *Subscription) {}), wantSpent: 100},
+		{name: "year", budget: func(b *Budget) { b.Period = BudgetYear }, sub: active(func(sub *Subscription) {}), wantFrom: month(time.January), wantTo: month(time.December), wantSpent: 12 * 100},
+		{name: "year from June", budget: func(b *Budget) { b.Period = BudgetYear }, sub: active(func(sub *Subscription) { sub.StartDate = month(time.June) }), wantFrom: month(time.January), wantTo: month(time.December), wantSpent: 7 * 100},
+		{name: "service", budget: func(b *Budget) { b.Scope, b.Target = BudgetService, "Netflix" }, sub: active(func(sub *Subscription) {}), wantSpent: 100},
+		{name: "other service", budget: func(b *Budget) { b.Scope, b.Target = BudgetService, "Spotify" }, sub: active(func(sub *Subscription) {})},
+		{name: "cancelled", sub: active(func(sub *Subscription) { sub.Status, sub.EndDate = StatusCancelled, month(time.February) })},
+		{name: "trial", sub: active(func(sub *Subscription) {
+			sub.Status, sub.TrialEndsAt, sub.TrialPrice = StatusTrial, month(time.May), 10
+		}), wantSpent: 10},
+		{name: "other currency", sub: active(func(sub *Subscription) {
+			sub.Prices = []Price{{EffectiveFrom: month(time.January), Price: 100, Currency: "USD"}}
+		})},
+		{
+			name: "member share",
+			sub: active(func(sub *Subscription) {
+				sub.UserID = uuid.New()
+				sub.Members = []Member{{UserID: testUser, Status: MemberActive, Split: SplitEqual, JoinedAt: month(time.January)}}
+			}),
+			wantSpent: 50,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := monthly
+			if tt.budget != nil {
+				tt.budget(&b)
+			}
+			got := budgetStatusOf(b, []Subscription{tt.sub}, now)
+			wantFrom, wantTo := tt.wantFrom, tt.wantTo
+			if wantFrom.IsZero() {
+				wantFrom, wantTo = month(time.March), month(time.March)
+			}
+			if !got.From.Equal(wantFrom) || !got.To.Equal(wantTo) {
+				t.Errorf("window = %v to %v, want %v to %v", got.From, got.To, wantFrom, wantTo)
+			}
+			if got.Spent != tt.wantSpent || got.Remaining != int64(b.Amount)-tt.wantSpent || got.Over != (tt.wantSpent > int64(b.Amount)) {
+				t.Errorf("status = %+v, want %d spent", got, tt.wantSpent)
+			}
+		})
+	}
+}
+
+func TestValidateBudget(t *testing.T) {
+	existing := []Budget{{ID: 1, Scope: BudgetOverall, Currency: DefaultCurrency, Period: BudgetMonth}}
+	tests := []struct {
+		name      string
+		change    func(b *Budget)
+		wantField string
+	}{
+		{name: "valid", change: func(b *Budget) {}},
+		{name: "other period", change: func(b *Budget) { b.Period = BudgetMonth }, wantField: "scope"},
+		{name: "other currency", change: func(b *Budget) { b.Currency = "USD" }},
+		{name: "service", change: func(b *Budget) { b.Scope, b.Target = BudgetService, "Netflix" }},
+		{name: "no user", change: func(b *Budget) { b.UserID = uuid.Nil }, wantField: "user_id"},
+		{name: "scope", change: func(b *Budget) { b.Scope = "family" }, wantField: "scope"},
+		{name: "overall with a target", change: func(b *Budget) { b.Target = "Netflix" }, wantField: "target"},
+		{name: "service without a target", change: func(b *Budget) { b.Scope = BudgetService }, wantField: "target"},
+		{name: "amount", change: func(b *Budget) { b.Amount = 0 }, wantField: "amount"},
+		{name: "currency", change: func(b *Budget) { b.Currency = "rub" }, wantField: "currency"},
+		{name: "period", change: func(b *Budget) { b.Period = "week" }, wantField: "period"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := Budget{UserID: testUser, Scope: BudgetOverall, Amount: 100, Currency: DefaultCurrency, Period: BudgetYear}
+			tt.change(&b)
+			if err := validateBudget(b, existing); fieldOf(err) != tt.wantField {
+				t.Errorf("validateBudget() error = %v, want a ValidationError on %q", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestBudgets(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(month(time.March))
+	mustCreate(t, svc, validSub())
+
+	b := Budget{UserID: testUser, Scope: BudgetOverall, Amount: 80, Currency: DefaultCurrency, Period: BudgetMonth}
+	added, err := svc.AddBudget(ctx, testTenant, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.AddBudget(ctx, testTenant, b); fieldOf(err) != "scope" {
+		t.Errorf("second AddBudget() error = %v, want a ValidationError on scope", err)
+	}
+	budgets, err := svc.Budgets(ctx, testTenant, testUser)
+	if err != nil || len(budgets) != 1 || budgets[0].ID != added.ID {
+		t.Errorf("Budgets() = %+v, %v", budgets, err)
+	}
+
+	statuses, err := svc.BudgetStatus(ctx, testTenant, testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].Spent != 100 || !statuses[0].Over {
+		t.Errorf("BudgetStatus() = %+v, want 100 spent and over", statuses)
+	}
+
+	if err := svc.RemoveBudget(ctx, testTenant, uuid.New(), added.ID); !errors.Is(err, ErrBudgetNotFound) {
+		t.Errorf("RemoveBudget() of another user error = %v, want ErrBudgetNotFound", err)
+	}
+	if err := svc.RemoveBudget(ctx, testTenant, testUser, added.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.RemoveBudget(ctx, testTenant, testUser, added.ID); !errors.Is(err, ErrBudgetNotFound) || !errors.Is(err, ErrNotFound) {
+		t.Errorf("second RemoveBudget() error = %v, want ErrBudgetNotFound", err)
+	}
+	if budgets, err := svc.Budgets(ctx, testTenant, testUser); err != nil || len(budgets) != 0 {
+		t.Errorf("Budgets() after the removal = %+v, %v", budgets, err)
+	}
+}
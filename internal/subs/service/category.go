@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"usersubs/internal/db"
+
+	"github.com/google/uuid"
+)
+
+// maxLabelLength bounds the names of categories and tags.
+const maxLabelLength = 64
+
+// Category groups subscriptions, the categories of an organization are
+// managed with AddCategory and RemoveCategory.
+type Category struct {
+	ID   int32
+	Name string
+}
+
+func validateLabel(field, name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return &ValidationError{Field: field, Message: "is empty"}
+	case name != strings.TrimSpace(name):
+		return &ValidationError{Field: field, Message: "must not start or end with spaces"}
+	case len(name) > maxLabelLength:
+		return &ValidationError{Field: field, Message: "is too long"}
+	}
+	return nil
+}
+
+func (s *SubscriptionService) AddCategory(ctx context.Context, tenantID uuid.UUID, name string) (Category, error) {
+	if err := validateLabel("name", name); err != nil {
+		return Category{}, err
+	}
+
+	// Names are unique per organization in the database, concurrent adds
+	// of a name can not both pass a lookup before.
+	var c Category
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		row, err := q.AddCategory(ctx, db.AddCategoryParams{TenantID: tenantID, Name: name})
+		if isUniqueViolation(err) {
+			return ErrCategoryExists
+		}
+		c = Category{ID: row.ID, Name: row.Name}
+		return err
+	})
+	return c, err
+}
+
+// Categories lists the categories of the organization by name.
+func (s *SubscriptionService) Categories(ctx context.Context, tenantID uuid.UUID) ([]Category, error) {
+	categories := []Category{}
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		rows, err := q.GetCategories(ctx, tenantID)
+		for _, row := range rows {
+			categories = append(categories, Category{ID: row.ID, Name: row.Name})
+		}
+		return err
+	})
+	return categories, err
+}
+
+// RemoveCategory deletes a category and takes it off every subscription.
+func (s *SubscriptionService) RemoveCategory(ctx context.Context, tenantID uuid.UUID, id int32) error {
+	return s.run(ctx, tenantID, func(q Queries) error {
+		_, err := q.DeleteCategory(ctx, db.DeleteCategoryParams{ID: id, TenantID: tenantID})
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCategoryNotFound
+		}
+		return err
+	})
+}
+
+// Tags lists every tag used in the organization by name.
+func (s *SubscriptionService) Tags(ctx context.Context, tenantID uuid.UUID) ([]string, error) {
+	var tags []string
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		var err error
+		tags, err = q.GetTags(ctx, tenantID)
+		return err
+	})
+	return tags, err
+}
+
+// Categorize puts a subscription in an existing category, doing nothing
+// when it already is.
+func (s *SubscriptionService) Categorize(ctx context.Context, tenantID uuid.UUID, subID int32, category string) (Subscription, error) {
+	return s.label(ctx, tenantID, subID, func(q Queries) error {
+		c, err := q.GetCategoryByName(ctx, db.GetCategoryByNameParams{Name: category, TenantID: tenantID})
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCategoryNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return q.AddSubCategory(ctx, db.AddSubCategoryParams{SubscriptionID: subID, CategoryID: c.ID, TenantID: tenantID})
+	})
+}
+
+func (s *SubscriptionService) Uncategorize(ctx context.Context, tenantID uuid.UUID, subID int32, category string) (Subscription, error) {
+	return s.label(ctx, tenantID, subID, func(q Queries) error {
+		_, err := q.DeleteSubCategory(ctx, db.DeleteSubCategoryParams{SubscriptionID: subID, Name: category, TenantID: tenantID})
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCategoryNotFound
+		}
+		return err
+	})
+}
+
+// AddTag tags a subscription, creating the tag on first use.
+func (s *SubscriptionService) AddTag(ctx context.Context, tenantID uuid.UUID, subID int32, tag string) (Subscription, error) {
+	if err := validateLabel("tag", tag); err != nil {
+		return Subscription{}, err
+	}
+	return s.label(ctx, tenantID, subID, func(q Queries) error {
+		tagID, err := q.UpsertTag(ctx, db.UpsertTagParams{TenantID: tenantID, Name: tag})
+		if err != nil {
+			return err
+		}
+		return q.AddSubTag(ctx, db.AddSubTagParams{SubscriptionID: subID, TagID: tagID, TenantID: tenantID})
+	})
+}
+
+func (s *SubscriptionService) RemoveTag(ctx context.Context, tenantID uuid.UUID, subID int32, tag string) (Subscription, error) {
+	return s.label(ctx, tenantID, subID, func(q Queries) error {
+		_, err := q.DeleteSubTag(ctx, db.DeleteSubTagParams{SubscriptionID: subID, Name: tag, TenantID: tenantID})
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTagNotFound
+		}
+		return err
+	})
+}
+
+// label changes the categories or tags of a subscription with fn and
+// returns it changed.
+func (s *SubscriptionService) label(ctx context.Context, tenantID uuid.UUID, subID int32, fn func(q Queries) error) (Subscription, error) {
+	now := s.now(ctx)
+	var sub Subscription
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		if _, err := q.GetSubForUpdate(ctx, db.GetSubForUpdateParams{ID: subID, TenantID: tenantID}); err != nil {
+			return err
+		}
+		if err := fn(q); err != nil {
+			return err
+		}
+
+		var err error
+		sub, err = get(ctx, q, tenantID, subID, now)
+		return err
+	})
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	s.emit(ctx, EventUpdated, tenantID, sub)
+	return sub, nil
+}
+
+// CategorySpend is the spend of the subscriptions of a category, Category
+// is empty for the subscriptions without one.
+type CategorySpend struct {
+	Category      string
+	Subscriptions int
+	Total         int64
+}
+
+// SpendByCategory sums the cost over [from, to] of the subscriptions
+// matching filter by category, the largest first. A subscription in
+// several categories counts in each. With filter.UserID only the share of
+// the user is counted, see ShareCost.
+func (s *SubscriptionService) SpendByCategory(ctx context.Context, tenantID uuid.UUID, filter ListFilter, from, to time.Time) ([]CategorySpend, error) {
+	filter.Limit, filter.Offset = 0, 0
+	subs, err := s.List(ctx, tenantID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	byCategory := map[string]*CategorySpend{}
+	add := func(category string, cost int64) {
+		c, ok := byCategory[category]
+		if !ok {
+			c = &CategorySpend{Category: category}
+			byCategory[category] = c
+		}
+		c.Subscriptions++
+		c.Total += cost
+	}
+	for _, sub := range subs {
+		if sub.ActiveMonths(from, to) == 0 {
+			continue
+		}
+		cost := sub.Cost(from, to)
+		if filter.UserID != uuid.Nil {
+			cost = sub.ShareCost(filter.UserID, from, to)
+		}
+		if len(sub.Categories) == 0 {
+			add("", cost)
+		}
+		for _, c := range sub.Categories {
+			add(c, cost)
+		}
+	}
+
+	res := make([]CategorySpend, 0, len(byCategory))
+	for _, c := range byCategory {
+		res = append(res, *c)
+	}
+	slices.SortFunc(res, func(a, b CategorySpend) int {
+		switch {
+		case a.Total > b.Total:
+			return -1
+		case a.Total < b.Total:
+			return 1
+		}
+		return strings.Compare(a.Category, b.Category)
+	})
+	return res, nil
+}
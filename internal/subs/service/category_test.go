@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateLabel(t *testing.T) {
+	tests := []struct {
+		name    string
+		label   string
+		wantErr bool
+	}{
+		{name: "word", label: "streaming"},
+		{name: "spaces inside", label: "video streaming"},
+		{name: "longest", label: strings.Repeat("a", maxLabelLength)},
+		{name: "empty", label: "", wantErr: true},
+		{name: "blank", label: "  ", wantErr: true},
+		{name: "leading space", label: " streaming", wantErr: true},
+		{name: "trailing space", label: "streaming ", wantErr: true},
+		{name: "too long", label: strings.Repeat("a", maxLabelLength+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLabel("tag", tt.label)
+			if (fieldOf(err) == "tag") != tt.wantErr {
+				t.Errorf("validateLabel(%q) error = %v, want error %v", tt.label, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAddCategoryDuplicate(t *testing.T) {
+	svc, _ := newTestService(month(time.March))
+	if _, err := svc.AddCategory(context.Background(), testTenant, "streaming"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := svc.AddCategory(context.Background(), testTenant, "streaming")
+	if !errors.Is(err, ErrCategoryExists) || !errors.Is(err, ErrConflict) {
+		t.Errorf("second AddCategory() error = %v, want ErrCategoryExists", err)
+	}
+}
+
+func TestCategories(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(month(time.March))
+	stored := mustCreate(t, svc, validSub())
+	for _, name := range []string{"video", "family"} {
+		if _, err := svc.AddCategory(ctx, testTenant, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	categories, err := svc.Categories(ctx, testTenant)
+	if err != nil || len(categories) != 2 || categories[0].Name != "family" || categories[1].Name != "video" {
+		t.Errorf("Categories() = %+v, %v, want family and video", categories, err)
+	}
+
+	if _, err := svc.Categorize(ctx, testTenant, stored.ID, "music"); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("Categorize() with a missing category error = %v, want ErrCategoryNotFound", err)
+	}
+	if _, err := svc.Categorize(ctx, testTenant, 999, "video"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Categorize() of a missing subscription error = %v, want ErrNotFound", err)
+	}
+	for _, name := range []string{"video", "family", "video"} {
+		if _, err := svc.Categorize(ctx, testTenant, stored.ID, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := svc.Get(ctx, testTenant, stored.ID)
+	if err != nil || !slices.Equal(got.Categories, []string{"family", "video"}) {
+		t.Errorf("categories = %v, %v, want family and video", got.Categories, err)
+	}
+
+	got, err = svc.Uncategorize(ctx, testTenant, stored.ID, "family")
+	if err != nil || !slices.Equal(got.Categories, []string{"video"}) {
+		t.Errorf("Uncategorize() = %v, %v, want video", got.Categories, err)
+	}
+	if _, err := svc.Uncategorize(ctx, testTenant, stored.ID, "family"); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("second Uncategorize() error = %v, want ErrCategoryNotFound", err)
+	}
+
+	if err := svc.RemoveCategory(ctx, testTenant, categories[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.RemoveCategory(ctx, testTenant, categories[1].ID); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("second RemoveCategory() error = %v, want ErrCategoryNotFound", err)
+	}
+	if got, err := svc.Get(ctx, testTenant, stored.ID); err != nil || len(got.Categories) != 0 {
+		t.Errorf("categories after the removal = %v, %v, want none", got.Categories, err)
+	}
+}
+
+func TestTags(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(month(time.March))
+	first := mustCreate(t, svc, validSub())
+	second := mustCreate(t, svc, validSub())
+	events := &recorder{}
+	svc.Events = events
+
+	if _, err := svc.AddTag(ctx, testTenant, first.ID, " work"); fieldOf(err) != "tag" {
+		t.Errorf("AddTag() error = %v, want a ValidationError on tag", err)
+	}
+	for _, tag := range []string{"work", "shared", "work"} {
+		if _, err := svc.AddTag(ctx, testTenant, first.ID, tag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := svc.AddTag(ctx, testTenant, second.ID, "work")
+	if err != nil || !slices.Equal(got.Tags, []string{"work"}) {
+		t.Errorf("AddTag() = %v, %v, want work", got.Tags, err)
+	}
+	if tags, err := svc.Tags(ctx, testTenant); err != nil || !slices.Equal(tags, []string{"shared", "work"}) {
+		t.Errorf("Tags() = %v, %v, want shared and work", tags, err)
+	}
+
+	tagged, err := svc.List(ctx, testTenant, ListFilter{Tag: "shared"})
+	if err != nil || len(tagged) != 1 || tagged[0].ID != first.ID || !slices.Equal(tagged[0].Tags, []string{"shared", "work"}) {
+		t.Errorf("List() by tag = %+v, %v, want the first subscription", tagged, err)
+	}
+
+	if _, err := svc.RemoveTag(ctx, testTenant, second.ID, "shared"); !errors.Is(err, ErrTagNotFound) || !errors.Is(err, ErrNotFound) {
+		t.Errorf("RemoveTag() of a missing tag error = %v, want ErrTagNotFound", err)
+	}
+	got, err = svc.RemoveTag(ctx, testTenant, first.ID, "work")
+	if err != nil || !slices.Equal(got.Tags, []string{"shared"}) {
+		t.Errorf("RemoveTag() = %v, %v, want shared", got.Tags, err)
+	}
+	if len(events.events) != 5 {
+		t.Errorf("events = %v, want one update per change", events.types())
+	}
+}
+
+func TestSpendByCategory(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(month(time.March))
+	alice := uuid.New()
+	for _, name := range []string{"video", "family"} {
+		if _, err := svc.AddCategory(ctx, testTenant, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Netflix is in both categories and shared with alice, Spotify has none
+	// and Kino ended before the range.
+	netflix := mustCreate(t, svc, validSub())
+	for _, name := range []string{"video", "family"} {
+		if _, err := svc.Categorize(ctx, testTenant, netflix.ID, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := svc.AddMember(ctx, testTenant, netflix.ID, Member{UserID: alice, Split: SplitFixed, Amount: 30}); err != nil {
+		t.Fatal(err)
+	}
+	spotify := validSub()
+	spotify.ServiceName, spotify.Price = "Spotify", 300
+	mustCreate(t, svc, spotify)
+	kino := validSub()
+	kino.ServiceName, kino.EndDate = "Kino", month(time.January)
+	kino = mustCreate(t, svc, kino)
+	if _, err := svc.Categorize(ctx, testTenant, kino.ID, "video"); err != nil {
+		t.Fatal(err)
+	}
+
+	from, to := month(time.March), month(time.April)
+	tests := []struct {
+		name   string
+		filter ListFilter
+		want   []CategorySpend
+	}{
+		{
+			name: "all",
+			want: []CategorySpend{
+				{Category: "", Subscriptions: 1, Total: 2 * 300},
+				{Category: "family", Subscriptions: 1, Total: 2 * 100},
+				{Category: "video", Subscriptions: 1, Total: 2 * 100},
+			},
+		},
+		{
+			name:   "share of the owner",
+			filter: ListFilter{UserID: testUser, IncludeShared: true},
+			want: []CategorySpend{
+				{Category: "", Subscriptions: 1, Total: 2 * 300},
+				{Category: "family", Subscriptions: 1, Total: 2 * 70},
+				{Category: "video", Subscriptions: 1, Total: 2 * 70},
+			},
+		},
+		{
+			name:   "share of a member",
+			filter: ListFilter{UserID: alice, IncludeShared: true},
+			want: []CategorySpend{
+				{Category: "family", Subscriptions: 1, Total: 2 * 30},
+				{Category: "video", Subscriptions: 1, Total: 2 * 30},
+			},
+		},
+		{
+			name:   "category",
+			filter: ListFilter{Category: "video"},
+			want: []CategorySpend{
+				{Category: "family", Subscriptions: 1, Total: 2 * 100},
+				{Category: "video", Subscriptions: 1, Total: 2 * 100},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.SpendByCategory(ctx, testTenant, tt.filter, from, to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SpendByCategory() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategoryBudget(t *testing.T) {
+	sub := Subscription{ServiceName: "Netflix", Price: 100, UserID: testUser, StartDate: month(time.January), Status: StatusActive, Categories: []string{"video"}}
+	b := Budget{UserID: testUser, Scope: BudgetCategory, Target: "video", Amount: 100, Currency: DefaultCurrency, Period: BudgetMonth}
+	if got := budgetStatusOf(b, []Subscription{sub}, month(time.March)); got.Spent != 100 {
+		t.Errorf("spent in the category = %d, want 100", got.Spent)
+	}
+	b.Target = "music"
+	if got := budgetStatusOf(b, []Subscription{sub}, month(time.March)); got.Spent != 0 {
+		t.Errorf("spent in another category = %d, want 0", got.Spent)
+	}
+	b.Target = ""
+	if err := validateBudget(b, nil); fieldOf(err) != "target" {
+		t.Errorf("validateBudget() without a target error = %v, want a ValidationError on target", err)
+	}
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"usersubs/internal/db"
+
+	"github.com/google/uuid"
+)
+
+type DiscountKind string
+
+const (
+	// DiscountPercent takes Amount percent off the price.
+	DiscountPercent DiscountKind = "percent"
+	// DiscountFixed takes Amount off the price, down to zero.
+	DiscountFixed DiscountKind = "fixed"
+)
+
+// Discount lowers the price from StartDate for Cycles months or for the
+// months charged before Until, exactly one of them is set. Months are
+// counted on the calendar, paused months use up cycles too.
+type Discount struct {
+	ID        int32
+	Kind      DiscountKind
+	Amount    int32
+	StartDate time.Time
+	Cycles    int32
+	Until     time.Time
+	// EndsAt is the first month charged in full again, set by the service.
+	EndsAt time.Time
+}
+
+func fromDiscountRow(row db.SubscriptionDiscount) Discount {
+	return Discount{
+		ID:        row.ID,
+		Kind:      DiscountKind(row.Kind),
+		Amount:    row.Amount,
+		StartDate: row.StartedAt,
+		Cycles:    row.Cycles.Int32,
+		Until:     row.Until.Time,
+		EndsAt:    row.EndsAt,
+	}
+}
+
+// endsAt is the first month after d, a month is charged on its first day.
+func (d Discount) endsAt() time.Time {
+	if d.Cycles > 0 {
+		return d.StartDate.AddDate(0, int(d.Cycles), 0)
+	}
+	end := monthOf(d.Until)
+	if end.Before(d.Until) {
+		end = end.AddDate(0, 1, 0)
+	}
+	return end
+}
+
+// covers tells if month is charged with the discount.
+func (d Discount) covers(month time.Time) bool {
+	return !month.Before(d.StartDate) && month.Before(d.EndsAt)
+}
+
+// apply is price after the discount.
+func (d Discount) apply(price int32) int32 {
+	switch d.Kind {
+	case DiscountPercent:
+		return price - int32((int64(price)*int64(d.Amount)+50)/100)
+	case DiscountFixed:
+		return max(price-d.Amount, 0)
+	}
+	return price
+}
+
+// validateDiscounts checks the new discount, the last one of discounts,
+// against sub and the discounts already there.
+func validateDiscounts(sub Subscription, discounts []Discount) error {
+	d := discounts[len(discounts)-1]
+	switch {
+	case d.Kind != DiscountPercent && d.Kind != DiscountFixed:
+		return &ValidationError{Field: "kind", Message: "must be percent or fixed"}
+	case d.Amount <= 0:
+		return &ValidationError{Field: "amount", Message: "must be positive"}
+	case d.Kind == DiscountPercent && d.Amount > 100:
+		return &ValidationError{Field: "amount", Message: "must not be over 100 percent"}
+	case d.StartDate.IsZero():
+		return &ValidationError{Field: "start_date", Message: "is empty"}
+	case d.StartDate.Before(sub.StartDate):
+		return &ValidationError{Field: "start_date", Message: "is before the start of the subscription"}
+	case !sub.EndDate.IsZero() && d.StartDate.After(sub.EndDate):
+		return &ValidationError{Field: "start_date", Message: "is after the end of the subscription"}
+	case (d.Cycles > 0) == !d.Until.IsZero():
+		return &ValidationError{Field: "cycles", Message: "or until must be set, but not both"}
+	case d.Cycles < 0:
+		return &ValidationError{Field: "cycles", Message: "must be positive"}
+	case !d.Until.IsZero() && !d.Until.After(d.StartDate):
+		return &ValidationError{Field: "until", Message: "is not after start_date"}
+	}
+	for _, other := range discounts[:len(discounts)-1] {
+		if d.StartDate.Before(other.EndsAt) && other.StartDate.Before(d.EndsAt) {
+			return &ValidationError{Field: "start_date", Message: fmt.Sprintf("overlaps the discount from %s", other.StartDate.Format("01-2006"))}
+		}
+	}
+	return nil
+}
+
+// ChargeAt is the amount charged for month: the trial price during the
+// trial, otherwise the price in effect, less the discount covering month.
+// Pauses and the dates of the subscription are not checked.
+func (s Subscription) ChargeAt(month time.Time) int32 {
+	price := s.PriceAt(month)
+	if !s.TrialEndsAt.IsZero() && month.Before(monthOf(s.TrialEndsAt)) {
+		price = s.TrialPrice
+	}
+	for _, d := range s.Discounts {
+		if d.covers(month) {
+			return d.apply(price)
+		}
+	}
+	return price
+}
+
+// AddDiscount adds a discount to the subscription, discounts must not
+// overlap.
+func (s *SubscriptionService) AddDiscount(ctx context.Context, tenantID uuid.UUID, subID int32, d Discount) (Discount, error) {
+	d.StartDate = monthOf(d.StartDate)
+	if d.Cycles > 0 || !d.Until.IsZero() {
+		d.EndsAt = d.endsAt()
+	}
+
+	now := s.now(ctx)
+	var sub Subscription
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		if _, err := q.GetSubForUpdate(ctx, db.GetSubForUpdateParams{ID: subID, TenantID: tenantID}); err != nil {
+			return err
+		}
+		stored, err := get(ctx, q, tenantID, subID, now)
+		if err != nil {
+			return err
+		}
+		if err := validateDiscounts(stored, append(stored.Discounts, d)); err != nil {
+			return err
+		}
+
+		added, err := q.AddDiscount(ctx, db.AddDiscountParams{
+			SubscriptionID: subID,
+			TenantID:       tenantID,
+			Kind:           string(d.Kind),
+			Amount:         d.Amount,
+			StartedAt:      d.StartDate,
+			Cycles:         sql.NullInt32{Int32: d.Cycles, Valid: d.Cycles > 0},
+			Until:          nullTime(d.Until),
+			EndsAt:         d.EndsAt,
+		})
+		if err != nil {
+			return err
+		}
+		d = fromDiscountRow(added)
+
+		sub, err = get(ctx, q, tenantID, subID, now)
+		return err
+	})
+	if err != nil {
+		return Discount{}, err
+	}
+
+	s.emit(ctx, EventUpdated, tenantID, sub)
+	return d, nil
+}
+
+// Discounts lists the discounts of a subscription, the earliest first.
+func (s *SubscriptionService) Discounts(ctx context.Context, tenantID uuid.UUID, subID int32) ([]Discount, error) {
+	sub, err := s.Get(ctx, tenantID, subID)
+	if err != nil {
+		return nil, err
+	}
+	return sub.Discounts, nil
+}
+
+func (s *SubscriptionService) RemoveDiscount(ctx context.Context, tenantID uuid.UUID, subID, discountID int32) error {
+	now := s.now(ctx)
+	var sub Subscription
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		_, err := q.DeleteDiscount(ctx, db.DeleteDiscountParams{ID: discountID, SubscriptionID: subID, TenantID: tenantID})
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDiscountNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		sub, err = get(ctx, q, tenantID, subID, now)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.emit(ctx, EventUpdated, tenantID, sub)
+	return nil
+}
+
+// ExpiringDiscount is a discount ending soon, the charge of the
+// subscription goes from Charge to NextCharge at Discount.EndsAt.
+type ExpiringDiscount struct {
+	Subscription Subscription
+	Discount     Discount
+	Charge       int32
+	NextCharge   int32
+}
+
+// ExpiringDiscounts lists the discounts ending within days, the soonest
+// first. userID narrows it to one user when set.
+func (s *SubscriptionService) ExpiringDiscounts(ctx context.Context, tenantID, userID uuid.UUID, days int) ([]ExpiringDiscount, error) {
+	if days < 0 {
+		return nil, &ValidationError{Field: "days", Message: "must not be negative"}
+	}
+
+	now := s.now(ctx)
+	var expiring []ExpiringDiscount
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		rows, err := q.GetExpiringDiscounts(ctx, db.GetExpiringDiscountsParams{
+			TenantID: tenantID,
+			Now:      now,
+			Until:    now.AddDate(0, 0, days),
+			UserID:   uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		})
+		if err != nil || len(rows) == 0 {
+			return err
+		}
+
+		ids := make([]int32, 0, len(rows))
+		for _, row := range rows {
+			ids = append(ids, row.SubscriptionID)
+		}
+		subRows, err := q.GetSubsByIDs(ctx, db.GetSubsByIDsParams{Ids: ids, TenantID: tenantID})
+		if err != nil {
+			return err
+		}
+		subs, err := load(ctx, q, tenantID, subRows, now)
+		if err != nil {
+			return err
+		}
+		byID := map[int32]Subscription{}
+		for _, sub := range subs {
+			byID[sub.ID] = sub
+		}
+
+		for _, row := range rows {
+			d := fromDiscountRow(row)
+			sub := byID[row.SubscriptionID]
+			last := d.EndsAt.AddDate(0, -1, 0)
+			expiring = append(expiring, ExpiringDiscount{
+				Subscription: sub,
+				Discount:     d,
+				Charge:       sub.ChargeAt(last),
+				NextCharge:   sub.ChargeAt(d.EndsAt),
+			})
+		}
+		return nil
+	})
+	return expiring, err
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDiscountApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		discount Discount
+		price    int32
+		want     int32
+	}{
+		{name: "percent", discount: Discount{Kind: DiscountPercent, Amount: 20}, price: 100, want: 80},
+		{name: "percent rounds", discount: Discount{Kind: DiscountPercent, Amount: 15}, price: 99, want: 84},
+		{name: "percent rounds half up", discount: Discount{Kind: DiscountPercent, Amount: 50}, price: 99, want: 49},
+		{name: "full percent", discount: Discount{Kind: DiscountPercent, Amount: 100}, price: 100, want: 0},
+		{name: "fixed", discount: Discount{Kind: DiscountFixed, Amount: 30}, price: 100, want: 70},
+		{name: "fixed at the price", discount: Discount{Kind: DiscountFixed, Amount: 100}, price: 100, want: 0},
+		{name: "fixed above the price", discount: Discount{Kind: DiscountFixed, Amount: 150}, price: 100, want: 0},
+		{name: "free month", discount: Discount{Kind: DiscountFixed, Amount: 30}, price: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.discount.apply(tt.price); got != tt.want {
+				t.Errorf("apply(%d) = %d, want %d", tt.price, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscountEndsAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		discount Discount
+		want     time.Time
+	}{
+		{name: "one cycle", discount: Discount{StartDate: month(time.February), Cycles: 1}, want: month(time.March)},
+		{name: "cycles", discount: Discount{StartDate: month(time.February), Cycles: 3}, want: month(time.May)},
+		{name: "cycles over the year", discount: Discount{StartDate: month(time.November), Cycles: 3}, want: month(time.February).AddDate(1, 0, 0)},
+		{name: "until the first of a month", discount: Discount{StartDate: month(time.February), Until: month(time.April)}, want: month(time.April)},
+		{name: "until inside a month", discount: Discount{StartDate: month(time.February), Until: month(time.April).AddDate(0, 0, 14)}, want: month(time.May)},
+		{name: "until the last day of the year", discount: Discount{StartDate: month(time.February), Until: month(time.December).AddDate(0, 0, 30)}, want: month(time.January).AddDate(1, 0, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.discount.endsAt(); !got.Equal(tt.want) {
+				t.Errorf("endsAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChargeAt(t *testing.T) {
+	// 20 percent off from March to May, June is charged in full again.
+	percent := Discount{Kind: DiscountPercent, Amount: 20, StartDate: month(time.March), EndsAt: month(time.June)}
+	// 150 off the price of 100 in February.
+	fixed := Discount{Kind: DiscountFixed, Amount: 150, StartDate: month(time.February), EndsAt: month(time.March)}
+	tests := []struct {
+		name      string
+		discounts []Discount
+		trialEnds time.Time
+		prices    []Price
+		month     time.Time
+		want      int32
+	}{
+		{name: "no discount", month: month(time.March), want: 100},
+		{name: "month before", discounts: []Discount{percent}, month: month(time.February), want: 100},
+		{name: "first month", discounts: []Discount{percent}, month: month(time.March), want: 80},
+		{name: "last month", discounts: []Discount{percent}, month: month(time.May), want: 80},
+		{name: "month after", discounts: []Discount{percent}, month: month(time.June), want: 100},
+		{name: "fixed above the price", discounts: []Discount{fixed}, month: month(time.February), want: 0},
+		{name: "two discounts", discounts: []Discount{fixed, percent}, month: month(time.April), want: 80},
+		{name: "during the trial", discounts: []Discount{percent}, trialEnds: month(time.April), month: month(time.March), want: 8},
+		{name: "after the trial", discounts: []Discount{percent}, trialEnds: month(time.April), month: month(time.April), want: 80},
+		{name: "fixed during the trial", discounts: []Discount{fixed}, trialEnds: month(time.April), month: month(time.February), want: 0},
+		{name: "new price", discounts: []Discount{percent}, prices: []Price{{EffectiveFrom: month(time.April), Price: 150}}, month: month(time.April), want: 120},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := Subscription{Price: 100, StartDate: month(time.January), TrialEndsAt: tt.trialEnds, TrialPrice: 10, Discounts: tt.discounts}
+			if tt.prices != nil {
+				sub.Prices = timeline(sub, 100, tt.prices)
+			}
+			if got := sub.ChargeAt(tt.month); got != tt.want {
+				t.Errorf("ChargeAt(%v) = %d, want %d", tt.month, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscountCost(t *testing.T) {
+	// Half off from February to April.
+	half := Discount{Kind: DiscountPercent, Amount: 50, StartDate: month(time.February), EndsAt: month(time.May)}
+	tests := []struct {
+		name      string
+		discounts []Discount
+		trialEnds time.Time
+		pauses    []Pause
+		from, to  time.Time
+		want      int64
+	}{
+		{name: "whole discount", discounts: []Discount{half}, from: month(time.January), to: month(time.June), want: 3*100 + 3*50},
+		{name: "range inside", discounts: []Discount{half}, from: month(time.March), to: month(time.April), want: 2 * 50},
+		{name: "range ends at the start", discounts: []Discount{half}, from: month(time.January), to: month(time.February), want: 100 + 50},
+		{name: "range starts at the end", discounts: []Discount{half}, from: month(time.April), to: month(time.May), want: 50 + 100},
+		{
+			name:      "fixed above the price",
+			discounts: []Discount{{Kind: DiscountFixed, Amount: 150, StartDate: month(time.February), EndsAt: month(time.April)}},
+			from:      month(time.January), to: month(time.June),
+			want: 4 * 100,
+		},
+		{
+			name:      "with a trial",
+			discounts: []Discount{half},
+			trialEnds: month(time.March),
+			from:      month(time.January), to: month(time.June),
+			want: 10 + 5 + 2*50 + 2*100,
+		},
+		{
+			name:      "paused months use up cycles",
+			discounts: []Discount{half},
+			pauses:    []Pause{{StartDate: month(time.March), EndDate: month(time.April)}},
+			from:      month(time.January), to: month(time.June),
+			want: 100 + 50 + 2*100,
+		},
+		{
+			name:      "pause after the discount",
+			discounts: []Discount{half},
+			pauses:    []Pause{{StartDate: month(time.May), EndDate: month(time.May)}},
+			from:      month(time.January), to: month(time.June),
+			want: 2*100 + 3*50,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := Subscription{Price: 100, StartDate: month(time.January), TrialEndsAt: tt.trialEnds, TrialPrice: 10, Pauses: tt.pauses, Discounts: tt.discounts}
+			if got := sub.Cost(tt.from, tt.to); got != tt.want {
+				t.Errorf("Cost() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddDiscount(t *testing.T) {
+	tests := []struct {
+		name      string
+		discount  Discount
+		wantField string
+		wantEnds  time.Time
+	}{
+		{name: "cycles", discount: Discount{Kind: DiscountPercent, Amount: 20, StartDate: month(time.June), Cycles: 2}, wantEnds: month(time.August)},
+		{name: "until", discount: Discount{Kind: DiscountFixed, Amount: 30, StartDate: month(time.June).AddDate(0, 0, 5), Until: month(time.July).AddDate(0, 0, 5)}, wantEnds: month(time.August)},
+		{name: "kind", discount: Discount{Kind: "free", Amount: 20, StartDate: month(time.June), Cycles: 2}, wantField: "kind"},
+		{name: "amount", discount: Discount{Kind: DiscountFixed, StartDate: month(time.June), Cycles: 2}, wantField: "amount"},
+		{name: "percent over 100", discount: Discount{Kind: DiscountPercent, Amount: 101, StartDate: month(time.June), Cycles: 2}, wantField: "amount"},
+		{name: "no start", discount: Discount{Kind: DiscountPercent, Amount: 20, Cycles: 2}, wantField: "start_date"},
+		{name: "before the subscription", discount: Discount{Kind: DiscountPercent, Amount: 20, StartDate: month(time.January), Cycles: 2}, wantField: "start_date"},
+		{name: "after the subscription", discount: Discount{Kind: DiscountPercent, Amount: 20, StartDate: month(time.December), Cycles: 2}, wantField: "start_date"},
+		{name: "no end", discount: Discount{Kind: DiscountPercent, Amount: 20, StartDate: month(time.June)}, wantField: "cycles"},
+		{name: "two ends", discount: Discount{Kind: DiscountPercent, Amount: 20, StartDate: month(time.June), Cycles: 2, Until: month(time.September)}, wantField: "cycles"},
+		{name: "until before the start", discount: Discount{Kind: DiscountPercent, Amount: 20, StartDate: month(time.June), Until: month(time.May)}, wantField: "until"},
+		{name: "overlap", discount: Discount{Kind: DiscountPercent, Amount: 20, StartDate: month(time.April), Cycles: 2}, wantField: "start_date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			svc, repo := newTestService(month(time.March))
+			sub := validSub()
+			sub.StartDate = month(time.February)
+			sub.EndDate = month(time.October)
+			stored := mustCreate(t, svc, sub)
+			// The discounts of the overlap case.
+			if _, err := svc.AddDiscount(ctx, testTenant, stored.ID, Discount{Kind: DiscountFixed, Amount: 10, StartDate: month(time.March), Cycles: 2}); err != nil {
+				t.Fatal(err)
+			}
+
+			added, err := svc.AddDiscount(ctx, testTenant, stored.ID, tt.discount)
+			if fieldOf(err) != tt.wantField {
+				t.Fatalf("AddDiscount() error = %v, want a ValidationError on %q", err, tt.wantField)
+			}
+			if tt.wantField != "" {
+				if len(repo.data.discounts) != 1 {
+					t.Errorf("invalid discount was stored")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !added.StartDate.Equal(month(time.June)) || !added.EndsAt.Equal(tt.wantEnds) {
+				t.Errorf("added = %+v, want June to %v", added, tt.wantEnds)
+			}
+			discounts, err := svc.Discounts(ctx, testTenant, stored.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(discounts) != 2 || discounts[1].ID != added.ID {
+				t.Errorf("discounts = %+v", discounts)
+			}
+		})
+	}
+}
+
+func TestRemoveDiscount(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(month(time.March))
+	stored := mustCreate(t, svc, validSub())
+	events := &recorder{}
+	svc.Events = events
+
+	if _, err := svc.AddDiscount(ctx, testTenant, 999, Discount{Kind: DiscountFixed, Amount: 10, StartDate: month(time.March), Cycles: 2}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AddDiscount() of a missing subscription error = %v, want ErrNotFound", err)
+	}
+	added, err := svc.AddDiscount(ctx, testTenant, stored.ID, Discount{Kind: DiscountFixed, Amount: 10, StartDate: month(time.March), Cycles: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := svc.Get(ctx, testTenant, stored.ID); err != nil || got.Charge != 90 {
+		t.Errorf("Get() charge = %d, %v, want 90", got.Charge, err)
+	}
+
+	if err := svc.RemoveDiscount(ctx, testTenant, stored.ID, added.ID); err != nil {
+		t.Fatal(err)
+	}
+	err = svc.RemoveDiscount(ctx, testTenant, stored.ID, added.ID)
+	if !errors.Is(err, ErrDiscountNotFound) || !errors.Is(err, ErrNotFound) {
+		t.Errorf("second RemoveDiscount() error = %v, want ErrDiscountNotFound", err)
+	}
+	if got, err := svc.Get(ctx, testTenant, stored.ID); err != nil || got.Charge != 100 || len(got.Discounts) != 0 {
+		t.Errorf("Get() = %+v, %v, want the full charge", got, err)
+	}
+	if len(events.events) != 2 || events.events[0].Type != EventUpdated || events.events[1].Type != EventUpdated {
+		t.Errorf("events = %v, want two updates", events.types())
+	}
+}
+
+func TestExpiringDiscounts(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(month(time.March).AddDate(0, 0, 20))
+	stored := mustCreate(t, svc, validSub())
+	// Ends in April, then in August.
+	for _, d := range []Discount{
+		{Kind: DiscountPercent, Amount: 20, StartDate: month(time.March), Cycles: 1},
+		{Kind: DiscountFixed, Amount: 30, StartDate: month(time.May), Cycles: 3},
+	} {
+		if _, err := svc.AddDiscount(ctx, testTenant, stored.ID, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := svc.ExpiringDiscounts(ctx, testTenant, testUser, -1); fieldOf(err) != "days" {
+		t.Errorf("ExpiringDiscounts(-1) error = %v, want a ValidationError on days", err)
+	}
+	expiring, err := svc.ExpiringDiscounts(ctx, testTenant, testUser, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expiring) != 1 {
+		t.Fatalf("expiring = %+v, want the March discount", expiring)
+	}
+	if e := expiring[0]; e.Subscription.ID != stored.ID || !e.Discount.EndsAt.Equal(month(time.April)) || e.Charge != 80 || e.NextCharge != 100 {
+		t.Errorf("expiring = %+v, want 80 then 100 in April", e)
+	}
+}
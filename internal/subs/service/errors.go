@@ -0,0 +1,134 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned for a missing subscription, including one of
+	// another tenant.
+	ErrNotFound = errors.New("subscription not found")
+	// ErrPauseNotFound is a missing pause of an existing subscription.
+	ErrPauseNotFound error = notFoundError("pause not found")
+	// ErrDiscountNotFound is a missing discount of an existing subscription.
+	ErrDiscountNotFound error = notFoundError("discount not found")
+	// ErrMemberNotFound is a user not sharing an existing subscription.
+	ErrMemberNotFound error = notFoundError("member not found")
+	// ErrBudgetNotFound is a missing budget of the user.
+	ErrBudgetNotFound error = notFoundError("budget not found")
+	// ErrCategoryNotFound is a missing category, or one the subscription
+	// is not in.
+	ErrCategoryNotFound error = notFoundError("category not found")
+	// ErrTagNotFound is a tag the subscription does not have.
+	ErrTagNotFound error = notFoundError("tag not found")
+	// ErrCategoryExists is a category name already taken in the
+	// organization.
+	ErrCategoryExists error = conflictError("category already exists")
+	// ErrInvalid matches every *ValidationError.
+	ErrInvalid = errors.New("invalid subscription")
+	// ErrConflict matches every *TransitionError, *BudgetError and
+	// ErrCategoryExists, a change the stored data does not allow.
+	ErrConflict = errors.New("conflict")
+	// ErrBudgetExceeded matches every *BudgetError, telling it from a status
+	// conflict.
+	ErrBudgetExceeded = errors.New("budget exceeded")
+)
+
+// notFoundError is a missing row other than the subscription, it matches
+// ErrNotFound.
+type notFoundError string
+
+func (e notFoundError) Error() string {
+	return string(e)
+}
+
+func (e notFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// conflictError is a row the stored ones do not allow other than a status
+// or budget conflict, it matches ErrConflict.
+type conflictError string
+
+func (e conflictError) Error() string {
+	return string(e)
+}
+
+func (e conflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// ValidationError reports the first invalid field of a subscription.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// TransitionError reports a status change that is not allowed from the
+// current status.
+type TransitionError struct {
+	From    Status
+	Allowed []Status
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("subscription is %s, expected one of %v", e.From, e.Allowed)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// BudgetError reports a change taking the user over an enforced budget.
+type BudgetError struct {
+	Status BudgetStatus
+}
+
+func (e *BudgetError) Error() string {
+	b := e.Status.Budget
+	return fmt.Sprintf("over the %s %s budget %d of %d %s by %d", b.Period, b.Scope, b.ID, b.Amount, b.Currency, -e.Status.Remaining)
+}
+
+func (e *BudgetError) Is(target error) bool {
+	return target == ErrConflict || target == ErrBudgetExceeded
+}
+
+// isUniqueViolation tells if err is a unique constraint of the database
+// refusing a row.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// Validate checks the fields of a subscription before it is stored.
+func Validate(s Subscription) error {
+	switch {
+	case s.ServiceName == "":
+		return &ValidationError{Field: "service_name", Message: "is empty"}
+	case s.Price < 0:
+		return &ValidationError{Field: "price", Message: "must not be negative"}
+	case s.UserID == uuid.Nil:
+		return &ValidationError{Field: "user_id", Message: "is empty"}
+	case s.StartDate.IsZero():
+		return &ValidationError{Field: "start_date", Message: "is empty"}
+	case !s.EndDate.IsZero() && s.EndDate.Before(s.StartDate):
+		return &ValidationError{Field: "end_date", Message: "is before start_date"}
+	case s.TrialPrice < 0:
+		return &ValidationError{Field: "trial_price", Message: "must not be negative"}
+	case !s.TrialEndsAt.IsZero() && s.TrialEndsAt.Before(s.StartDate):
+		return &ValidationError{Field: "trial_ends_at", Message: "is before start_date"}
+	}
+	return validateMetadata(s.Metadata)
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const (
+	EventCreated EventType = "subscription.created"
+	EventUpdated EventType = "subscription.updated"
+	EventDeleted EventType = "subscription.deleted"
+	// EventStatusChanged is sent by Pause, Resume, Cancel and Reactivate.
+	EventStatusChanged EventType = "subscription.status_changed"
+)
+
+// Event is emitted after a change is committed. Subscription holds the new
+// state, or only the ID when the subscription was deleted.
+type Event struct {
+	Type         EventType
+	TenantID     uuid.UUID
+	Subscription Subscription
+	At           time.Time
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, e Event)
+}
+
+// LogPublisher writes events to the default logger.
+type LogPublisher struct{}
+
+func (LogPublisher) Publish(ctx context.Context, e Event) {
+	slog.InfoContext(ctx, "event",
+		"type", string(e.Type),
+		"tenant_id", e.TenantID.String(),
+		"subscription_id", e.Subscription.ID,
+	)
+}
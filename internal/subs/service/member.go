@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"usersubs/internal/db"
+
+	"github.com/google/uuid"
+)
+
+type MemberStatus string
+
+const (
+	// MemberInvited does not pay until the invite is accepted.
+	MemberInvited MemberStatus = "invited"
+	MemberActive  MemberStatus = "active"
+)
+
+type Split string
+
+const (
+	// SplitEqual shares what the fixed and percent members leave equally
+	// with the owner and the other equal members.
+	SplitEqual Split = "equal"
+	// SplitPercent pays Amount percent of the charge.
+	SplitPercent Split = "percent"
+	// SplitFixed pays Amount, at most what is left of the charge.
+	SplitFixed Split = "fixed"
+)
+
+// Member is a user sharing a subscription with its owner. Removed members
+// are kept with RemovedAt, so the months before keep their split.
+type Member struct {
+	ID     int32
+	UserID uuid.UUID
+	Status MemberStatus
+	Split  Split
+	// Amount is zero for SplitEqual.
+	Amount    int32
+	CreatedAt time.Time
+	// JoinedAt is when the member was added or accepted the invite, zero
+	// while invited.
+	JoinedAt  time.Time
+	RemovedAt time.Time
+	// Share is the part of the current month's charge the member pays when
+	// read, see ShareAt.
+	Share int32
+}
+
+func fromMemberRow(row db.SubscriptionMember) Member {
+	return Member{
+		ID:        row.ID,
+		UserID:    row.UserID,
+		Status:    MemberStatus(row.Status),
+		Split:     Split(row.Split),
+		Amount:    row.Amount,
+		CreatedAt: row.CreatedAt,
+		JoinedAt:  row.JoinedAt.Time,
+		RemovedAt: row.RemovedAt.Time,
+	}
+}
+
+// Current tells if m was not removed.
+func (m Member) Current() bool {
+	return m.RemovedAt.IsZero()
+}
+
+// paysIn tells if m shares the charge of month: from the month they joined
+// up to the month before they were removed.
+func (m Member) paysIn(month time.Time) bool {
+	return !m.JoinedAt.IsZero() && !month.Before(monthOf(m.JoinedAt)) &&
+		(m.Current() || month.Before(monthOf(m.RemovedAt)))
+}
+
+// validateMembers checks the new member, the last one of members, against
+// sub and the current members already there.
+func validateMembers(sub Subscription, members []Member) error {
+	m := members[len(members)-1]
+	switch {
+	case m.UserID == uuid.Nil:
+		return &ValidationError{Field: "user_id", Message: "is empty"}
+	case m.UserID == sub.UserID:
+		return &ValidationError{Field: "user_id", Message: "is the owner of the subscription"}
+	case m.Split != SplitEqual && m.Split != SplitPercent && m.Split != SplitFixed:
+		return &ValidationError{Field: "split", Message: "must be equal, percent or fixed"}
+	case m.Split == SplitEqual && m.Amount != 0:
+		return &ValidationError{Field: "amount", Message: "must be empty for an equal split"}
+	case m.Split != SplitEqual && m.Amount <= 0:
+		return &ValidationError{Field: "amount", Message: "must be positive"}
+	}
+
+	var percent int32
+	for _, other := range members {
+		if other.Current() && other.Split == SplitPercent {
+			percent += other.Amount
+		}
+	}
+	for _, other := range members[:len(members)-1] {
+		if other.Current() && other.UserID == m.UserID {
+			return &ValidationError{Field: "user_id", Message: "is already a member of the subscription"}
+		}
+	}
+	if percent > 100 {
+		return &ValidationError{Field: "amount", Message: "takes the members over 100 percent"}
+	}
+	return nil
+}
+
+// ShareAt is the part of ChargeAt(month) paid by userID, zero when the user
+// neither owns the subscription nor is a member paying in month, see
+// paysIn. Fixed and percent members pay first in the order they were
+// added, the owner and the equal members split the rest, the owner paying
+// what does not divide.
+func (s Subscription) ShareAt(userID uuid.UUID, month time.Time) int32 {
+	charge := s.ChargeAt(month)
+	left := charge
+	share := int32(-1)
+	equal := int32(1)
+	for _, m := range s.Members {
+		if !m.paysIn(month) {
+			continue
+		}
+		var part int32
+		switch m.Split {
+		case SplitEqual:
+			equal++
+			continue
+		case SplitPercent:
+			part = int32(int64(charge) * int64(m.Amount) / 100)
+		case SplitFixed:
+			part = m.Amount
+		}
+		part = min(part, left)
+		left -= part
+		if m.UserID == userID {
+			share = part
+		}
+	}
+	if share >= 0 {
+		return share
+	}
+
+	if userID == s.UserID {
+		return left - (equal-1)*(left/equal)
+	}
+	for _, m := range s.Members {
+		if m.UserID == userID && m.paysIn(month) {
+			return left / equal
+		}
+	}
+	return 0
+}
+
+// AddMember adds userID to the subscription as an active member.
+func (s *SubscriptionService) AddMember(ctx context.Context, tenantID uuid.UUID, subID int32, m Member) (Member, error) {
+	m.Status = MemberActive
+	return s.addMember(ctx, tenantID, subID, m)
+}
+
+// InviteMember adds userID to the subscription, they pay nothing until
+// AcceptInvite.
+func (s *SubscriptionService) InviteMember(ctx context.Context, tenantID uuid.UUID, subID int32, m Member) (Member, error) {
+	m.Status = MemberInvited
+	return s.addMember(ctx, tenantID, subID, m)
+}
+
+func (s *SubscriptionService) addMember(ctx context.Context, tenantID uuid.UUID, subID int32, m Member) (Member, error) {
+	now := s.now(ctx)
+	if m.Status == MemberActive {
+		m.JoinedAt = now
+	}
+	var sub Subscription
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		if _, err := q.GetSubForUpdate(ctx, db.GetSubForUpdateParams{ID: subID, TenantID: tenantID}); err != nil {
+			return err
+		}
+		stored, err := get(ctx, q, tenantID, subID, now)
+		if err != nil {
+			return err
+		}
+		if err := validateMembers(stored, append(stored.Members, m)); err != nil {
+			return err
+		}
+
+		added, err := q.AddMember(ctx, db.AddMemberParams{
+			SubscriptionID: subID,
+			TenantID:       tenantID,
+			UserID:         m.UserID,
+			Status:         string(m.Status),
+			Split:          string(m.Split),
+			Amount:         m.Amount,
+			JoinedAt:       nullTime(m.JoinedAt),
+		})
+		if err != nil {
+			return err
+		}
+		m = fromMemberRow(added)
+
+		sub, err = get(ctx, q, tenantID, subID, now)
+		return err
+	})
+	if err != nil {
+		return Member{}, err
+	}
+
+	s.emit(ctx, EventUpdated, tenantID, sub)
+	return m, nil
+}
+
+// AcceptInvite makes an invited member active, ErrMemberNotFound when
+// userID has no pending invite.
+func (s *SubscriptionService) AcceptInvite(ctx context.Context, tenantID uuid.UUID, subID int32, userID uuid.UUID) (Member, error) {
+	now := s.now(ctx)
+	var m Member
+	var sub Subscription
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		if _, err := q.GetSubForUpdate(ctx, db.GetSubForUpdateParams{ID: subID, TenantID: tenantID}); err != nil {
+			return err
+		}
+		row, err := q.AcceptMember(ctx, db.AcceptMemberParams{
+			SubscriptionID: subID,
+			UserID:         userID,
+			TenantID:       tenantID,
+			JoinedAt:       nullTime(now),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrMemberNotFound
+		}
+		if err != nil {
+			return err
+		}
+		m = fromMemberRow(row)
+
+		sub, err = get(ctx, q, tenantID, subID, now)
+		return err
+	})
+	if err != nil {
+		return Member{}, err
+	}
+
+	s.emit(ctx, EventUpdated, tenantID, sub)
+	return m, nil
+}
+
+// Members lists the members of a subscription in the order they were added,
+// removed members included.
+func (s *SubscriptionService) Members(ctx context.Context, tenantID uuid.UUID, subID int32) ([]Member, error) {
+	sub, err := s.Get(ctx, tenantID, subID)
+	if err != nil {
+		return nil, err
+	}
+	return sub.Members, nil
+}
+
+// RemoveMember removes a member or an invite, the owner pays their share
+// from the current month on.
+func (s *SubscriptionService) RemoveMember(ctx context.Context, tenantID uuid.UUID, subID int32, userID uuid.UUID) error {
+	now := s.now(ctx)
+	var sub Subscription
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		_, err := q.RemoveMember(ctx, db.RemoveMemberParams{
+			SubscriptionID: subID,
+			UserID:         userID,
+			TenantID:       tenantID,
+			RemovedAt:      nullTime(now),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrMemberNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		sub, err = get(ctx, q, tenantID, subID, now)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.emit(ctx, EventUpdated, tenantID, sub)
+	return nil
+}
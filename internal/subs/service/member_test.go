@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+	"usersubs/internal/clock"
+
+	"github.com/google/uuid"
+)
+
+func TestShareAt(t *testing.T) {
+	owner, alice, bob, carol := testUser, uuid.New(), uuid.New(), uuid.New()
+	equal := func(user uuid.UUID) Member {
+		return Member{UserID: user, Status: MemberActive, Split: SplitEqual, JoinedAt: month(time.January)}
+	}
+	percent := func(user uuid.UUID, amount int32) Member {
+		return Member{UserID: user, Status: MemberActive, Split: SplitPercent, Amount: amount, JoinedAt: month(time.January)}
+	}
+	fixed := func(user uuid.UUID, amount int32) Member {
+		return Member{UserID: user, Status: MemberActive, Split: SplitFixed, Amount: amount, JoinedAt: month(time.January)}
+	}
+	tests := []struct {
+		name    string
+		price   int32
+		members []Member
+		want    map[uuid.UUID]int32
+	}{
+		{name: "owner only", price: 100, want: map[uuid.UUID]int32{owner: 100}},
+		{name: "equal", price: 100, members: []Member{equal(alice)}, want: map[uuid.UUID]int32{owner: 50, alice: 50}},
+		{name: "equal remainder", price: 100, members: []Member{equal(alice), equal(bob)}, want: map[uuid.UUID]int32{owner: 34, alice: 33, bob: 33}},
+		{name: "equal remainder of two", price: 101, members: []Member{equal(alice), equal(bob), equal(carol)}, want: map[uuid.UUID]int32{owner: 26, alice: 25, bob: 25, carol: 25}},
+		{name: "percent", price: 100, members: []Member{percent(alice, 30), equal(bob)}, want: map[uuid.UUID]int32{owner: 35, alice: 30, bob: 35}},
+		{name: "percent rounds down", price: 101, members: []Member{percent(alice, 33)}, want: map[uuid.UUID]int32{owner: 68, alice: 33}},
+		{name: "fixed", price: 100, members: []Member{fixed(alice, 40), equal(bob)}, want: map[uuid.UUID]int32{owner: 30, alice: 40, bob: 30}},
+		{name: "fixed above the price", price: 100, members: []Member{fixed(alice, 150), equal(bob)}, want: map[uuid.UUID]int32{owner: 0, alice: 100, bob: 0}},
+		{name: "fixed above what is left", price: 100, members: []Member{percent(alice, 70), fixed(bob, 50)}, want: map[uuid.UUID]int32{owner: 0, alice: 70, bob: 30}},
+		{name: "joined first pays first", price: 100, members: []Member{fixed(bob, 50), percent(alice, 70)}, want: map[uuid.UUID]int32{owner: 0, alice: 50, bob: 50}},
+		{name: "free month", price: 0, members: []Member{fixed(alice, 40), equal(bob)}, want: map[uuid.UUID]int32{owner: 0, alice: 0, bob: 0}},
+		{
+			name:    "invited pays nothing",
+			price:   100,
+			members: []Member{{UserID: alice, Status: MemberInvited, Split: SplitEqual}, {UserID: bob, Status: MemberInvited, Split: SplitFixed, Amount: 40}},
+			want:    map[uuid.UUID]int32{owner: 100, alice: 0, bob: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := Subscription{Price: tt.price, UserID: owner, StartDate: month(time.January), Members: tt.members}
+			var sum int32
+			for user, want := range tt.want {
+				got := sub.ShareAt(user, month(time.March))
+				if got != want {
+					t.Errorf("ShareAt(%v) = %d, want %d", user, got, want)
+				}
+				sum += got
+			}
+			if charge := sub.ChargeAt(month(time.March)); sum != charge {
+				t.Errorf("shares sum to %d, want the charge %d", sum, charge)
+			}
+			if got := sub.ShareAt(uuid.New(), month(time.March)); got != 0 {
+				t.Errorf("ShareAt() of another user = %d, want 0", got)
+			}
+		})
+	}
+}
+
+// A member pays from the month they joined up to the month before they
+// were removed, the months around keep the split they had.
+func TestShareAtEffectiveDates(t *testing.T) {
+	alice := uuid.New()
+	tests := []struct {
+		name   string
+		member Member
+		month  time.Month
+		want   int32
+	}{
+		{name: "before joining", member: Member{JoinedAt: month(time.March).AddDate(0, 0, 20)}, month: time.February, want: 0},
+		{name: "month of joining", member: Member{JoinedAt: month(time.March).AddDate(0, 0, 20)}, month: time.March, want: 50},
+		{name: "before removal", member: Member{JoinedAt: month(time.January), RemovedAt: month(time.March).AddDate(0, 0, 5)}, month: time.February, want: 50},
+		{name: "month of removal", member: Member{JoinedAt: month(time.January), RemovedAt: month(time.March).AddDate(0, 0, 5)}, month: time.March, want: 0},
+		{name: "after removal", member: Member{JoinedAt: month(time.January), RemovedAt: month(time.March)}, month: time.May, want: 0},
+		{name: "invited", member: Member{Status: MemberInvited}, month: time.March, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.member
+			m.UserID, m.Split = alice, SplitEqual
+			if m.Status == "" {
+				m.Status = MemberActive
+			}
+			sub := Subscription{Price: 100, UserID: testUser, StartDate: month(time.January), Members: []Member{m}}
+			if got := sub.ShareAt(alice, month(tt.month)); got != tt.want {
+				t.Errorf("member ShareAt() = %d, want %d", got, tt.want)
+			}
+			if got := sub.ShareAt(testUser, month(tt.month)); got != 100-tt.want {
+				t.Errorf("owner ShareAt() = %d, want %d", got, 100-tt.want)
+			}
+		})
+	}
+}
+
+// Shares follow the charge of the month and add up to Cost.
+func TestShareCost(t *testing.T) {
+	alice := uuid.New()
+	sub := Subscription{
+		Price:       100,
+		UserID:      testUser,
+		StartDate:   month(time.January),
+		TrialEndsAt: month(time.March),
+		TrialPrice:  11,
+		Discounts:   []Discount{{Kind: DiscountFixed, Amount: 40, StartDate: month(time.May), EndsAt: month(time.June)}},
+		Members:     []Member{{UserID: alice, Status: MemberActive, Split: SplitEqual, JoinedAt: month(time.January)}},
+	}
+	if got := sub.ShareAt(testUser, month(time.February)); got != 6 {
+		t.Errorf("owner share of the trial = %d, want 6", got)
+	}
+	if got := sub.ShareAt(alice, month(time.May)); got != 30 {
+		t.Errorf("member share of the discount = %d, want 30", got)
+	}
+
+	from, to := month(time.January), month(time.June)
+	owner, member := sub.ShareCost(testUser, from, to), sub.ShareCost(alice, from, to)
+	if want := int64(2*6 + 2*50 + 30 + 50); owner != want {
+		t.Errorf("owner ShareCost() = %d, want %d", owner, want)
+	}
+	if owner+member != sub.Cost(from, to) {
+		t.Errorf("shares %d + %d, want the cost %d", owner, member, sub.Cost(from, to))
+	}
+	if got := UserCost([]Subscription{sub, {Price: 10, UserID: alice, StartDate: month(time.June)}}, alice, from, to); got != member+10 {
+		t.Errorf("UserCost() = %d, want %d", got, member+10)
+	}
+}
+
+func TestValidateMembers(t *testing.T) {
+	alice := uuid.New()
+	existing := []Member{{UserID: alice, Status: MemberActive, Split: SplitPercent, Amount: 60}}
+	tests := []struct {
+		name      string
+		member    Member
+		wantField string
+	}{
+		{name: "equal", member: Member{UserID: uuid.New(), Split: SplitEqual}},
+		{name: "percent", member: Member{UserID: uuid.New(), Split: SplitPercent, Amount: 40}},
+		{name: "fixed", member: Member{UserID: uuid.New(), Split: SplitFixed, Amount: 500}},
+		{name: "no user", member: Member{Split: SplitEqual}, wantField: "user_id"},
+		{name: "owner", member: Member{UserID: testUser, Split: SplitEqual}, wantField: "user_id"},
+		{name: "member already", member: Member{UserID: alice, Split: SplitEqual}, wantField: "user_id"},
+		{name: "split", member: Member{UserID: uuid.New(), Split: "half"}, wantField: "split"},
+		{name: "equal with an amount", member: Member{UserID: uuid.New(), Split: SplitEqual, Amount: 10}, wantField: "amount"},
+		{name: "no amount", member: Member{UserID: uuid.New(), Split: SplitFixed}, wantField: "amount"},
+		{name: "over 100 percent", member: Member{UserID: uuid.New(), Split: SplitPercent, Amount: 41}, wantField: "amount"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := Subscription{UserID: testUser}
+			err := validateMembers(sub, append(slices.Clone(existing), tt.member))
+			if fieldOf(err) != tt.wantField {
+				t.Errorf("validateMembers() error = %v, want a ValidationError on %q", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestMembers(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(month(time.March))
+	stored := mustCreate(t, svc, validSub())
+	alice, bob := uuid.New(), uuid.New()
+
+	if _, err := svc.AddMember(ctx, testTenant, stored.ID, Member{UserID: alice, Split: SplitEqual}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.InviteMember(ctx, testTenant, stored.ID, Member{UserID: bob, Split: SplitFixed, Amount: 20}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.AddMember(ctx, testTenant, 999, Member{UserID: uuid.New(), Split: SplitEqual}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AddMember() of a missing subscription error = %v, want ErrNotFound", err)
+	}
+
+	shares := func() map[uuid.UUID]int32 {
+		t.Helper()
+		members, err := svc.Members(ctx, testTenant, stored.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[uuid.UUID]int32{}
+		for _, m := range members {
+			got[m.UserID] = m.Share
+		}
+		return got
+	}
+	if got := shares(); got[alice] != 50 || got[bob] != 0 {
+		t.Errorf("shares before the invite is accepted = %v", got)
+	}
+	if subs, err := svc.ListByUsers(ctx, testTenant, []uuid.UUID{bob}); err != nil || len(subs) != 0 {
+		t.Errorf("ListByUsers() of an invited member = %d subscriptions, %v, want none", len(subs), err)
+	}
+
+	m, err := svc.AcceptInvite(ctx, testTenant, stored.ID, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Status != MemberActive {
+		t.Errorf("accepted member status = %s", m.Status)
+	}
+	if _, err := svc.AcceptInvite(ctx, testTenant, stored.ID, bob); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("second AcceptInvite() error = %v, want ErrMemberNotFound", err)
+	}
+	if got := shares(); got[alice] != 40 || got[bob] != 20 {
+		t.Errorf("shares after the invite is accepted = %v", got)
+	}
+	if subs, err := svc.ListByUsers(ctx, testTenant, []uuid.UUID{bob}); err != nil || len(subs) != 1 {
+		t.Errorf("ListByUsers() of a member = %d subscriptions, %v, want 1", len(subs), err)
+	}
+	shared, err := svc.List(ctx, testTenant, ListFilter{UserID: alice, IncludeShared: true})
+	if err != nil || len(shared) != 1 {
+		t.Errorf("List() with shared = %d subscriptions, %v, want 1", len(shared), err)
+	}
+	owned, err := svc.List(ctx, testTenant, ListFilter{UserID: alice})
+	if err != nil || len(owned) != 0 {
+		t.Errorf("List() without shared = %d subscriptions, %v, want none", len(owned), err)
+	}
+
+	stored.UserID = alice
+	if _, err := svc.Update(ctx, testTenant, stored); fieldOf(err) != "user_id" {
+		t.Errorf("Update() to a member error = %v, want a ValidationError on user_id", err)
+	}
+
+	if err := svc.RemoveMember(ctx, testTenant, stored.ID, alice); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.RemoveMember(ctx, testTenant, stored.ID, alice); !errors.Is(err, ErrMemberNotFound) || !errors.Is(err, ErrNotFound) {
+		t.Errorf("second RemoveMember() error = %v, want ErrMemberNotFound", err)
+	}
+	if got := shares(); len(got) != 2 || got[alice] != 0 || got[bob] != 20 {
+		t.Errorf("shares after the removal = %v", got)
+	}
+}
+
+// Adding or removing a member does not change the split of the months
+// before.
+func TestMembersKeepPastShares(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(month(time.January))
+	clk := svc.Clock.(*clock.Fake)
+	stored := mustCreate(t, svc, validSub())
+	alice := uuid.New()
+
+	clk.Set(month(time.March).AddDate(0, 0, 10))
+	if _, err := svc.AddMember(ctx, testTenant, stored.ID, Member{UserID: alice, Split: SplitEqual}); err != nil {
+		t.Fatal(err)
+	}
+	clk.Set(month(time.May).AddDate(0, 0, 10))
+	if err := svc.RemoveMember(ctx, testTenant, stored.ID, alice); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := svc.Get(ctx, testTenant, stored.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, to := month(time.January), month(time.June)
+	if owner, want := got.ShareCost(testUser, from, to), int64(2*100+2*50+2*100); owner != want {
+		t.Errorf("owner ShareCost() = %d, want %d", owner, want)
+	}
+	if member := got.ShareCost(alice, from, to); member != 2*50 {
+		t.Errorf("member ShareCost() = %d, want %d", member, 2*50)
+	}
+	if subs, err := svc.ListByUsers(ctx, testTenant, []uuid.UUID{alice}); err != nil || len(subs) != 1 {
+		t.Errorf("ListByUsers() of a removed member = %d subscriptions, %v, want 1", len(subs), err)
+	}
+}
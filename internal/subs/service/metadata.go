@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"usersubs/internal/db"
+
+	"github.com/google/uuid"
+)
+
+// MaxMetadataSize bounds the metadata of a subscription encoded as JSON.
+const MaxMetadataSize = 4096
+
+var metadataKey = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// validateMetadata checks that metadata is flat: keys of letters, digits,
+// '_', '.' and '-', values strings, numbers or booleans.
+func validateMetadata(metadata map[string]any) error {
+	for k, v := range metadata {
+		if !metadataKey.MatchString(k) {
+			return &ValidationError{Field: "metadata", Message: fmt.Sprintf("key %q must be 1 to 64 letters, digits, '_', '.' or '-'", k)}
+		}
+		switch v.(type) {
+		case string, bool, float64, float32, int, int32, int64, json.Number:
+		default:
+			return &ValidationError{Field: "metadata", Message: fmt.Sprintf("value of %q must be a string, a number or a boolean", k)}
+		}
+	}
+	if len(encodeMetadata(metadata)) > MaxMetadataSize {
+		return &ValidationError{Field: "metadata", Message: fmt.Sprintf("is over %d bytes", MaxMetadataSize)}
+	}
+	return nil
+}
+
+// encodeMetadata is the column value of metadata, an empty object when nil.
+func encodeMetadata(metadata map[string]any) json.RawMessage {
+	if len(metadata) == 0 {
+		return json.RawMessage("{}")
+	}
+	b, _ := json.Marshal(metadata)
+	return b
+}
+
+// validateMetadataFilter checks the keys of a ListFilter, they are quoted
+// into metadataPath.
+func validateMetadataFilter(filter map[string]string) error {
+	for k := range filter {
+		if !metadataKey.MatchString(k) {
+			return &ValidationError{Field: "metadata", Message: fmt.Sprintf("key %q must be 1 to 64 letters, digits, '_', '.' or '-'", k)}
+		}
+	}
+	return nil
+}
+
+// metadataPath is the jsonpath matching every key of filter. The values of
+// a filter are text, so each one matches the stored string and, when it
+// reads as one in JSON, the stored number or boolean: "5" matches "5", 5
+// and 5.0, "true" matches "true" and true.
+func metadataPath(filter map[string]string) string {
+	var conds []string
+	for _, k := range slices.Sorted(maps.Keys(filter)) {
+		v := filter[k]
+		field := `$."` + k + `"`
+		str, _ := json.Marshal(v)
+		alts := []string{field + " == " + string(str)}
+
+		var n float64
+		if json.Unmarshal([]byte(v), &n) == nil {
+			alts = append(alts, field+" == "+strings.TrimSpace(v))
+		}
+		if v == "true" || v == "false" {
+			alts = append(alts, field+" == "+v)
+		}
+		conds = append(conds, "("+strings.Join(alts, " || ")+")")
+	}
+	return strings.Join(conds, " && ")
+}
+
+func decodeMetadata(raw json.RawMessage) map[string]any {
+	var metadata map[string]any
+	_ = json.Unmarshal(raw, &metadata)
+	if len(metadata) == 0 {
+		return nil
+	}
+	return metadata
+}
+
+// Patch changes some fields of a subscription, nil fields keep their
+// values.
+type Patch struct {
+	ServiceName *string
+	Price       *int32
+	UserID      *uuid.UUID
+	StartDate   *time.Time
+	EndDate     *time.Time
+	// Metadata is merged into the metadata of the subscription, a nil value
+	// removes its key.
+	Metadata map[string]any
+}
+
+// apply returns sub with the fields of p.
+func (p Patch) apply(sub Subscription) Subscription {
+	if p.ServiceName != nil {
+		sub.ServiceName = *p.ServiceName
+	}
+	if p.Price != nil {
+		sub.Price = *p.Price
+	}
+	if p.UserID != nil {
+		sub.UserID = *p.UserID
+	}
+	if p.StartDate != nil {
+		sub.StartDate = *p.StartDate
+	}
+	if p.EndDate != nil {
+		sub.EndDate = *p.EndDate
+	}
+
+	metadata := maps.Clone(sub.Metadata)
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	for k, v := range p.Metadata {
+		if v == nil {
+			delete(metadata, k)
+		} else {
+			metadata[k] = v
+		}
+	}
+	sub.Metadata = metadata
+	return sub
+}
+
+// Patch applies p to the subscription with the given ID like Update.
+func (s *SubscriptionService) Patch(ctx context.Context, tenantID uuid.UUID, id int32, p Patch) (Subscription, error) {
+	now := s.now(ctx)
+	var sub Subscription
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		if _, err := q.GetSubForUpdate(ctx, db.GetSubForUpdateParams{ID: id, TenantID: tenantID}); err != nil {
+			return err
+		}
+		stored, err := get(ctx, q, tenantID, id, now)
+		if err != nil {
+			return err
+		}
+
+		patched := p.apply(stored)
+		if err := Validate(patched); err != nil {
+			return err
+		}
+		sub, err = update(ctx, q, tenantID, patched, now)
+		return err
+	})
+	if err != nil {
+		return sub, err
+	}
+
+	s.emit(ctx, EventUpdated, tenantID, sub)
+	return sub, nil
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]any
+		wantErr  bool
+	}{
+		{name: "nil"},
+		{name: "flat", metadata: map[string]any{"external_id": "acc_42", "seats": 5.0, "trial": true, "plan.v2-x": "family"}},
+		{name: "json number", metadata: map[string]any{"seats": json.Number("5")}},
+		{name: "empty key", metadata: map[string]any{"": "x"}, wantErr: true},
+		{name: "space in key", metadata: map[string]any{"a b": "x"}, wantErr: true},
+		{name: "long key", metadata: map[string]any{strings.Repeat("a", 65): "x"}, wantErr: true},
+		{name: "nested object", metadata: map[string]any{"a": map[string]any{"b": "c"}}, wantErr: true},
+		{name: "array", metadata: map[string]any{"a": []any{"b"}}, wantErr: true},
+		{name: "null", metadata: map[string]any{"a": nil}, wantErr: true},
+		{name: "too large", metadata: map[string]any{"a": strings.Repeat("x", MaxMetadataSize)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMetadata(tt.metadata)
+			if (fieldOf(err) == "metadata") != tt.wantErr {
+				t.Errorf("validateMetadata() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEncodeMetadata(t *testing.T) {
+	if got := string(encodeMetadata(nil)); got != "{}" {
+		t.Errorf("encodeMetadata(nil) = %s, want {}", got)
+	}
+	if got := decodeMetadata(json.RawMessage("{}")); got != nil {
+		t.Errorf("decodeMetadata({}) = %v, want nil", got)
+	}
+	metadata := map[string]any{"external_id": "acc_42", "seats": 5.0, "trial": true}
+	if got := decodeMetadata(encodeMetadata(metadata)); !maps.Equal(got, metadata) {
+		t.Errorf("round trip = %v, want %v", got, metadata)
+	}
+}
+
+func TestMetadataPath(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter map[string]string
+		want   string
+	}{
+		{name: "string", filter: map[string]string{"external_id": "acc_42"}, want: `($."external_id" == "acc_42")`},
+		{name: "number", filter: map[string]string{"seats": "5"}, want: `($."seats" == "5" || $."seats" == 5)`},
+		{name: "boolean", filter: map[string]string{"trial": "true"}, want: `($."trial" == "true" || $."trial" == true)`},
+		{name: "not json", filter: map[string]string{"plan": "0x10"}, want: `($."plan" == "0x10")`},
+		{name: "quoted", filter: map[string]string{"note": `a "b" \c`}, want: `($."note" == "a \"b\" \\c")`},
+		{
+			name:   "sorted keys",
+			filter: map[string]string{"b.key": "x", "a-key": "-1.5"},
+			want:   `($."a-key" == "-1.5" || $."a-key" == -1.5) && ($."b.key" == "x")`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := metadataPath(tt.filter); got != tt.want {
+				t.Errorf("metadataPath() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateMetadataFilter(t *testing.T) {
+	if err := validateMetadataFilter(map[string]string{"external_id": "acc_42"}); err != nil {
+		t.Errorf("valid key: %v", err)
+	}
+	for _, key := range []string{"", `a"b`, "a b", "$"} {
+		if err := validateMetadataFilter(map[string]string{key: "x"}); !errors.Is(err, ErrInvalid) {
+			t.Errorf("key %q: error = %v, want ErrInvalid", key, err)
+		}
+	}
+}
+
+func TestMetadataFilterParams(t *testing.T) {
+	if got := (ListFilter{}).params(testTenant, month(time.March)).Metadata; got.Valid {
+		t.Errorf("params without metadata = %q, want none", got.String)
+	}
+	filter := ListFilter{Metadata: map[string]string{"external_id": "acc_42"}}
+	if got := filter.params(testTenant, month(time.March)).Metadata; got.String != `($."external_id" == "acc_42")` {
+		t.Errorf("params with metadata = %q", got.String)
+	}
+}
+
+// List rejects filter keys that could not be quoted into the jsonpath.
+func TestListMetadataFilter(t *testing.T) {
+	svc, _ := newTestService(month(time.March))
+	if _, err := svc.List(context.Background(), testTenant, ListFilter{Metadata: map[string]string{`a"b`: "x"}}); fieldOf(err) != "metadata" {
+		t.Errorf("List() error = %v, want a ValidationError on metadata", err)
+	}
+}
+
+// Update keeps the metadata when the subscription has none, Create rejects
+// invalid metadata.
+func TestUpdateKeepsMetadata(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(month(time.March))
+	sub := validSub()
+	sub.Metadata = map[string]any{"a b": "x"}
+	if _, err := svc.Create(ctx, testTenant, sub); fieldOf(err) != "metadata" {
+		t.Errorf("Create() error = %v, want a ValidationError on metadata", err)
+	}
+
+	sub.Metadata = map[string]any{"external_id": "acc_42"}
+	stored := mustCreate(t, svc, sub)
+	if !maps.Equal(stored.Metadata, sub.Metadata) {
+		t.Errorf("created metadata = %v, want %v", stored.Metadata, sub.Metadata)
+	}
+
+	stored.Metadata = nil
+	stored.ServiceName = "Spotify"
+	updated, err := svc.Update(ctx, testTenant, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(updated.Metadata, sub.Metadata) {
+		t.Errorf("updated metadata = %v, want %v", updated.Metadata, sub.Metadata)
+	}
+
+	updated.Metadata = map[string]any{"plan": "family"}
+	updated, err = svc.Update(ctx, testTenant, updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]any{"plan": "family"}; !maps.Equal(updated.Metadata, want) {
+		t.Errorf("replaced metadata = %v, want %v", updated.Metadata, want)
+	}
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+	"usersubs/internal/db"
+
+	"github.com/google/uuid"
+)
+
+// Pause is a range of months the subscription is not charged for, both
+// ends included. A pause without EndDate is opened by Pause and lasts until
+// the subscription is resumed, see closePause.
+type Pause struct {
+	ID        int32
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+func fromPauseRow(row db.SubscriptionPause) Pause {
+	return Pause{ID: row.ID, StartDate: row.StartedAt, EndDate: row.EndedAt.Time}
+}
+
+// open tells if p lasts until the subscription is resumed.
+func (p Pause) open() bool {
+	return p.EndDate.IsZero()
+}
+
+// overlaps tells if p and other share a month, open pauses never end.
+func (p Pause) overlaps(other Pause) bool {
+	return (p.open() || !p.EndDate.Before(other.StartDate)) &&
+		(other.open() || !other.EndDate.Before(p.StartDate))
+}
+
+// validatePause checks p is within the dates of sub, an open pause only
+// has to start before the subscription ends.
+func validatePause(sub Subscription, p Pause) error {
+	switch {
+	case p.StartDate.IsZero():
+		return &ValidationError{Field: "start_date", Message: "is empty"}
+	case !p.open() && p.EndDate.Before(p.StartDate):
+		return &ValidationError{Field: "end_date", Message: "is before start_date"}
+	case p.StartDate.Before(sub.StartDate):
+		return &ValidationError{Field: "pauses", Message: fmt.Sprintf("pause from %s starts before the subscription", p.StartDate.Format("01-2006"))}
+	case !sub.EndDate.IsZero() && p.open() && p.StartDate.After(sub.EndDate):
+		return &ValidationError{Field: "pauses", Message: fmt.Sprintf("pause from %s starts after the subscription", p.StartDate.Format("01-2006"))}
+	case !sub.EndDate.IsZero() && !p.open() && p.EndDate.After(sub.EndDate):
+		return &ValidationError{Field: "pauses", Message: fmt.Sprintf("pause from %s ends after the subscription", p.StartDate.Format("01-2006"))}
+	}
+	return nil
+}
+
+// validatePauses checks pauses are within the dates of sub, pauses closed
+// by closePause may overlap the ones planned before, see ActiveMonths.
+func validatePauses(sub Subscription, pauses []Pause) error {
+	for _, p := range pauses {
+		if err := validatePause(sub, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateNewPause checks p has an end, is within the dates of sub and does
+// not overlap pauses, the pauses of sub.
+func validateNewPause(sub Subscription, pauses []Pause, p Pause) error {
+	if p.open() {
+		return &ValidationError{Field: "end_date", Message: "is empty"}
+	}
+	if err := validatePause(sub, p); err != nil {
+		return err
+	}
+	for _, other := range pauses {
+		if p.overlaps(other) {
+			return &ValidationError{Field: "pauses", Message: fmt.Sprintf("pause from %s overlaps the pause from %s", p.StartDate.Format("01-2006"), other.StartDate.Format("01-2006"))}
+		}
+	}
+	return nil
+}
+
+// openPause stops charging sub from the month after now, which is already
+// paid, until closePause. Nothing is left to pause when sub ends before.
+func openPause(ctx context.Context, q Queries, tenantID uuid.UUID, sub Subscription, now time.Time) error {
+	start := latest(monthOf(now).AddDate(0, 1, 0), sub.StartDate)
+	if !sub.EndDate.IsZero() && start.After(sub.EndDate) {
+		return nil
+	}
+	_, err := q.AddPause(ctx, db.AddPauseParams{
+		SubscriptionID: sub.ID,
+		TenantID:       tenantID,
+		StartedAt:      start,
+	})
+	return err
+}
+
+// closePause ends the open pause of sub with the month end, the pause is
+// removed when it would end before it starts.
+func closePause(ctx context.Context, q Queries, tenantID uuid.UUID, sub Subscription, end time.Time) error {
+	for _, p := range sub.Pauses {
+		if !p.open() {
+			continue
+		}
+		if end.Before(p.StartDate) {
+			_, err := q.DeletePause(ctx, db.DeletePauseParams{ID: p.ID, SubscriptionID: sub.ID, TenantID: tenantID})
+			return err
+		}
+		_, err := q.EndPause(ctx, db.EndPauseParams{
+			EndedAt:        sql.NullTime{Time: end, Valid: true},
+			ID:             p.ID,
+			SubscriptionID: sub.ID,
+			TenantID:       tenantID,
+		})
+		return err
+	}
+	return nil
+}
+
+// pauseGap stops charging sub, about to be reactivated, for the months
+// between its end and the month of now. The open pause of a subscription
+// that expired while paused is stretched over them instead.
+func pauseGap(ctx context.Context, q Queries, tenantID uuid.UUID, sub Subscription, now time.Time) error {
+	last := monthOf(now).AddDate(0, -1, 0)
+	if slices.ContainsFunc(sub.Pauses, Pause.open) {
+		return closePause(ctx, q, tenantID, sub, latest(last, sub.EndDate))
+	}
+	start := sub.EndDate.AddDate(0, 1, 0)
+	if start.After(last) {
+		return nil
+	}
+	_, err := q.AddPause(ctx, db.AddPauseParams{
+		SubscriptionID: sub.ID,
+		TenantID:       tenantID,
+		StartedAt:      start,
+		EndedAt:        sql.NullTime{Time: last, Valid: true},
+	})
+	return err
+}
+
+// AddPause plans a pause for the months of p, it can not start within or
+// after the open pause. The subscription is locked, so concurrent pauses
+// can not overlap.
+func (s *SubscriptionService) AddPause(ctx context.Context, tenantID uuid.UUID, subID int32, p Pause) (Pause, error) {
+	now := s.now(ctx)
+	var sub Subscription
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		row, err := q.GetSubForUpdate(ctx, db.GetSubForUpdateParams{ID: subID, TenantID: tenantID})
+		if err != nil {
+			return err
+		}
+		rows, err := q.GetPauses(ctx, db.GetPausesParams{SubscriptionID: subID, TenantID: tenantID})
+		if err != nil {
+			return err
+		}
+
+		var pauses []Pause
+		for _, r := range rows {
+			pauses = append(pauses, fromPauseRow(r))
+		}
+		if err := validateNewPause(fromRow(row), pauses, p); err != nil {
+			return err
+		}
+
+		added, err := q.AddPause(ctx, db.AddPauseParams{
+			SubscriptionID: subID,
+			TenantID:       tenantID,
+			StartedAt:      p.StartDate,
+			EndedAt:        nullTime(p.EndDate),
+		})
+		if err != nil {
+			return err
+		}
+		p = fromPauseRow(added)
+
+		sub, err = get(ctx, q, tenantID, subID, now)
+		return err
+	})
+	if err != nil {
+		return Pause{}, err
+	}
+
+	s.emit(ctx, EventUpdated, tenantID, sub)
+	return p, nil
+}
+
+// Pauses lists the pauses of a subscription, the earliest first.
+func (s *SubscriptionService) Pauses(ctx context.Context, tenantID uuid.UUID, subID int32) ([]Pause, error) {
+	var sub Subscription
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		var err error
+		sub, err = get(ctx, q, tenantID, subID, s.now(ctx))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sub.Pauses, nil
+}
+
+// RemovePause deletes a pause of the subscription, the months are charged
+// again.
+func (s *SubscriptionService) RemovePause(ctx context.Context, tenantID uuid.UUID, subID, pauseID int32) error {
+	now := s.now(ctx)
+	var sub Subscription
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		_, err := q.DeletePause(ctx, db.DeletePauseParams{ID: pauseID, SubscriptionID: subID, TenantID: tenantID})
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPauseNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		sub, err = get(ctx, q, tenantID, subID, now)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.emit(ctx, EventUpdated, tenantID, sub)
+	return nil
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// The last pause of each case is added to the others.
+func TestValidateNewPause(t *testing.T) {
+	sub := Subscription{StartDate: month(time.February), EndDate: month(time.October)}
+	tests := []struct {
+		name      string
+		pauses    []Pause
+		wantField string
+	}{
+		{name: "inside", pauses: []Pause{{StartDate: month(time.March), EndDate: month(time.April)}, {StartDate: month(time.June), EndDate: month(time.June)}}},
+		{name: "whole subscription", pauses: []Pause{{StartDate: month(time.February), EndDate: month(time.October)}}},
+		{name: "no start", pauses: []Pause{{EndDate: month(time.April)}}, wantField: "start_date"},
+		{name: "no end", pauses: []Pause{{StartDate: month(time.April)}}, wantField: "end_date"},
+		{name: "end before start", pauses: []Pause{{StartDate: month(time.April), EndDate: month(time.March)}}, wantField: "end_date"},
+		{name: "before the subscription", pauses: []Pause{{StartDate: month(time.January), EndDate: month(time.March)}}, wantField: "pauses"},
+		{name: "after the subscription", pauses: []Pause{{StartDate: month(time.September), EndDate: month(time.November)}}, wantField: "pauses"},
+		{name: "overlap", pauses: []Pause{{StartDate: month(time.March), EndDate: month(time.May)}, {StartDate: month(time.May), EndDate: month(time.June)}}, wantField: "pauses"},
+		{name: "inside another", pauses: []Pause{{StartDate: month(time.March), EndDate: month(time.July)}, {StartDate: month(time.April), EndDate: month(time.May)}}, wantField: "pauses"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := len(tt.pauses) - 1
+			if err := validateNewPause(sub, tt.pauses[:n], tt.pauses[n]); fieldOf(err) != tt.wantField {
+				t.Errorf("validateNewPause() error = %v, want a ValidationError on %q", err, tt.wantField)
+			}
+		})
+	}
+}
+
+// Stored pauses may be open, an open pause overlaps every later one.
+func TestValidatePauses(t *testing.T) {
+	sub := Subscription{StartDate: month(time.February), EndDate: month(time.October)}
+	open := Pause{StartDate: month(time.May)}
+	if err := validatePauses(sub, []Pause{{StartDate: month(time.March), EndDate: month(time.April)}, open}); err != nil {
+		t.Errorf("validatePauses() with an open pause error = %v", err)
+	}
+	if err := validatePauses(sub, []Pause{{StartDate: month(time.November)}}); fieldOf(err) != "pauses" {
+		t.Errorf("validatePauses() with an open pause after the end error = %v, want a ValidationError on pauses", err)
+	}
+	if err := validateNewPause(sub, []Pause{open}, Pause{StartDate: month(time.August), EndDate: month(time.September)}); fieldOf(err) != "pauses" {
+		t.Errorf("validateNewPause() after an open pause error = %v, want a ValidationError on pauses", err)
+	}
+}
+
+func TestPauseCost(t *testing.T) {
+	sub := Subscription{
+		Price:     100,
+		StartDate: month(time.January),
+		Pauses: []Pause{
+			{StartDate: month(time.February), EndDate: month(time.March)},
+			{StartDate: month(time.June), EndDate: month(time.June)},
+		},
+	}
+	tests := []struct {
+		from, to time.Time
+		want     int64
+	}{
+		{from: month(time.January), to: month(time.December), want: 9 * 100},
+		{from: month(time.March), to: month(time.May), want: 2 * 100},
+		{from: month(time.February), to: month(time.March), want: 0},
+		{from: month(time.June), to: month(time.July), want: 100},
+	}
+	for _, tt := range tests {
+		if got := sub.Cost(tt.from, tt.to); got != tt.want {
+			t.Errorf("Cost(%v, %v) = %d, want %d", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestAddPause(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newTestService(month(time.March))
+	events := &recorder{}
+	svc.Events = events
+	stored := mustCreate(t, svc, validSub())
+
+	added, err := svc.AddPause(ctx, testTenant, stored.ID, Pause{StartDate: month(time.April), EndDate: month(time.May)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.ID == 0 {
+		t.Errorf("AddPause() = %+v, want a pause with an ID", added)
+	}
+	if _, err := svc.AddPause(ctx, testTenant, stored.ID, Pause{StartDate: month(time.May), EndDate: month(time.June)}); fieldOf(err) != "pauses" {
+		t.Errorf("overlapping AddPause() error = %v, want a ValidationError on pauses", err)
+	}
+	if _, err := svc.AddPause(ctx, testTenant, 999, Pause{StartDate: month(time.May), EndDate: month(time.June)}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AddPause() to a missing subscription error = %v, want ErrNotFound", err)
+	}
+	if len(repo.data.pauses) != 1 {
+		t.Errorf("stored %d pauses, want 1", len(repo.data.pauses))
+	}
+
+	got, err := svc.Get(ctx, testTenant, stored.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost := got.Cost(month(time.January), month(time.December)); cost != 10*100 {
+		t.Errorf("cost = %d, want %d", cost, 10*100)
+	}
+
+	if err := svc.RemovePause(ctx, testTenant, stored.ID, added.ID); err != nil {
+		t.Fatal(err)
+	}
+	err = svc.RemovePause(ctx, testTenant, stored.ID, added.ID)
+	if !errors.Is(err, ErrPauseNotFound) || !errors.Is(err, ErrNotFound) {
+		t.Errorf("second RemovePause() error = %v, want ErrPauseNotFound", err)
+	}
+	if pauses, err := svc.Pauses(ctx, testTenant, stored.ID); err != nil || len(pauses) != 0 {
+		t.Errorf("Pauses() = %v, %v, want none", pauses, err)
+	}
+	if want := []EventType{EventCreated, EventUpdated, EventUpdated}; !slices.Equal(events.types(), want) {
+		t.Errorf("events = %v, want %v", events.types(), want)
+	}
+}
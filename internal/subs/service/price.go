@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"time"
+	"usersubs/internal/db"
+
+	"github.com/google/uuid"
+)
+
+// DefaultCurrency is the currency of subscriptions.price and of changes
+// stored without one.
+const DefaultCurrency = "RUB"
+
+// Price is the monthly price of a subscription from EffectiveFrom until the
+// next change.
+type Price struct {
+	ID            int32
+	EffectiveFrom time.Time
+	Price         int32
+	Currency      string
+}
+
+func fromPriceRow(row db.SubscriptionPrice) Price {
+	return Price{ID: row.ID, EffectiveFrom: row.EffectiveFrom, Price: row.Price, Currency: row.Currency}
+}
+
+// timeline is the starting price of sub followed by its changes, a change
+// from the start month replaces the starting price.
+func timeline(sub Subscription, base int32, changes []Price) []Price {
+	prices := []Price{{EffectiveFrom: sub.StartDate, Price: base, Currency: DefaultCurrency}}
+	for _, p := range changes {
+		if !p.EffectiveFrom.After(sub.StartDate) {
+			prices = prices[:0]
+		}
+		prices = append(prices, p)
+	}
+	return prices
+}
+
+// withPrice is s with p added to its timeline the way SetPrice stores it,
+// replacing a change from the same month.
+func (s Subscription) withPrice(p Price) Subscription {
+	prices := s.Prices
+	if len(prices) == 0 {
+		prices = timeline(s, s.Price, nil)
+	}
+	prices = slices.DeleteFunc(slices.Clone(prices), func(other Price) bool { return other.EffectiveFrom.Equal(p.EffectiveFrom) })
+	i, _ := slices.BinarySearchFunc(prices, p, func(a, b Price) int { return a.EffectiveFrom.Compare(b.EffectiveFrom) })
+	s.Prices = slices.Insert(prices, i, p)
+	return s
+}
+
+// PriceAt is the price in effect in month.
+func (s Subscription) PriceAt(month time.Time) int32 {
+	if len(s.Prices) == 0 {
+		return s.Price
+	}
+	price := s.Prices[0].Price
+	for _, p := range s.Prices {
+		if p.EffectiveFrom.After(month) {
+			break
+		}
+		price = p.Price
+	}
+	return price
+}
+
+// CurrencyAt is the currency of the price in effect in month.
+func (s Subscription) CurrencyAt(month time.Time) string {
+	currency := DefaultCurrency
+	for _, p := range s.Prices {
+		if p.EffectiveFrom.After(month) {
+			break
+		}
+		currency = p.Currency
+	}
+	return currency
+}
+
+func validatePrice(sub Subscription, p Price, now time.Time) error {
+	switch {
+	case p.EffectiveFrom.IsZero():
+		return &ValidationError{Field: "effective_from", Message: "is empty"}
+	case p.EffectiveFrom.Before(monthOf(now)):
+		return &ValidationError{Field: "effective_from", Message: "is in the past"}
+	case !p.EffectiveFrom.After(sub.StartDate):
+		return &ValidationError{Field: "effective_from", Message: "is not after start_date, update the price instead"}
+	case !sub.EndDate.IsZero() && p.EffectiveFrom.After(sub.EndDate):
+		return &ValidationError{Field: "effective_from", Message: "is after end_date"}
+	case p.Price < 0:
+		return &ValidationError{Field: "price", Message: "must not be negative"}
+	case !validCurrency(p.Currency):
+		return &ValidationError{Field: "currency", Message: "must be an ISO 4217 code like RUB"}
+	}
+	return nil
+}
+
+func validCurrency(c string) bool {
+	if len(c) != 3 {
+		return false
+	}
+	for _, r := range c {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// SchedulePrice changes the price from p.EffectiveFrom on, the months
+// before keep their price. A change of the same month is replaced.
+func (s *SubscriptionService) SchedulePrice(ctx context.Context, tenantID uuid.UUID, subID int32, p Price) ([]Price, error) {
+	if p.Currency == "" {
+		p.Currency = DefaultCurrency
+	}
+
+	now := s.now(ctx)
+	var sub Subscription
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		if _, err := q.GetSubForUpdate(ctx, db.GetSubForUpdateParams{ID: subID, TenantID: tenantID}); err != nil {
+			return err
+		}
+		stored, err := get(ctx, q, tenantID, subID, now)
+		if err != nil {
+			return err
+		}
+		if err := validatePrice(stored, p, now); err != nil {
+			return err
+		}
+
+		_, err = q.SetPrice(ctx, db.SetPriceParams{
+			SubscriptionID: subID,
+			TenantID:       tenantID,
+			EffectiveFrom:  p.EffectiveFrom,
+			Price:          p.Price,
+			Currency:       p.Currency,
+		})
+		if err != nil {
+			return err
+		}
+
+		sub, err = get(ctx, q, tenantID, subID, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.emit(ctx, EventUpdated, tenantID, sub)
+	return sub.Prices, nil
+}
+
+// Prices is the price timeline of a subscription, starting with the price
+// from its start date.
+func (s *SubscriptionService) Prices(ctx context.Context, tenantID uuid.UUID, subID int32) ([]Price, error) {
+	sub, err := s.Get(ctx, tenantID, subID)
+	if err != nil {
+		return nil, err
+	}
+	return sub.Prices, nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"time"
+	"usersubs/internal/db"
+
+	"github.com/google/uuid"
+)
+
+// PriceChange sets a new price for every subscription of a service active
+// in the month of EffectiveFrom. Subscriptions starting in that month or
+// later keep the price they were created with.
+type PriceChange struct {
+	ServiceName   string
+	Price         int32
+	EffectiveFrom time.Time
+	Currency      string
+	// OldPrice only changes subscriptions paying it before the change.
+	OldPrice *int32
+	// DryRun reports the affected subscriptions without changing them.
+	DryRun bool
+}
+
+// ChangedPrice is a subscription affected by a PriceChange.
+type ChangedPrice struct {
+	Subscription Subscription
+	OldPrice     int32
+	NewPrice     int32
+}
+
+type PriceChangeResult struct {
+	Changed []ChangedPrice
+	// MonthlyDelta is how much the charges of all subscriptions change in
+	// the month of EffectiveFrom, trials and discounts included.
+	MonthlyDelta int64
+}
+
+// ChangeServicePrice applies c to all matching subscriptions in one
+// transaction, or only computes the result when c.DryRun is set.
+// Subscriptions already at the new price are left out.
+func (s *SubscriptionService) ChangeServicePrice(ctx context.Context, tenantID uuid.UUID, c PriceChange) (PriceChangeResult, error) {
+	if c.ServiceName == "" {
+		return PriceChangeResult{}, &ValidationError{Field: "service_name", Message: "is empty"}
+	}
+	if c.Currency == "" {
+		c.Currency = DefaultCurrency
+	}
+	now := s.now(ctx)
+	p := Price{EffectiveFrom: c.EffectiveFrom, Price: c.Price, Currency: c.Currency}
+	if err := validatePrice(Subscription{}, p, now); err != nil {
+		return PriceChangeResult{}, err
+	}
+
+	var res PriceChangeResult
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		rows, err := q.GetServiceSubsForUpdate(ctx, db.GetServiceSubsForUpdateParams{
+			TenantID:      tenantID,
+			ServiceName:   c.ServiceName,
+			EffectiveFrom: c.EffectiveFrom,
+		})
+		if err != nil {
+			return err
+		}
+		subs, err := load(ctx, q, tenantID, rows, now)
+		if err != nil {
+			return err
+		}
+
+		for _, sub := range subs {
+			old := sub.PriceAt(c.EffectiveFrom)
+			if old == c.Price || (c.OldPrice != nil && old != *c.OldPrice) {
+				continue
+			}
+			res.Changed = append(res.Changed, ChangedPrice{Subscription: sub, OldPrice: old, NewPrice: c.Price})
+			res.MonthlyDelta += int64(sub.withPrice(p).ChargeAt(c.EffectiveFrom) - sub.ChargeAt(c.EffectiveFrom))
+			if c.DryRun {
+				continue
+			}
+
+			_, err := q.SetPrice(ctx, db.SetPriceParams{
+				SubscriptionID: sub.ID,
+				TenantID:       tenantID,
+				EffectiveFrom:  c.EffectiveFrom,
+				Price:          c.Price,
+				Currency:       c.Currency,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if c.DryRun {
+			return nil
+		}
+
+		// Reload for the events, the timelines have the new price now.
+		subs, err = load(ctx, q, tenantID, rows, now)
+		if err != nil {
+			return err
+		}
+		byID := map[int32]Subscription{}
+		for _, sub := range subs {
+			byID[sub.ID] = sub
+		}
+		for i := range res.Changed {
+			res.Changed[i].Subscription = byID[res.Changed[i].Subscription.ID]
+		}
+		return nil
+	})
+	if err != nil {
+		return PriceChangeResult{}, err
+	}
+
+	if !c.DryRun {
+		for _, changed := range res.Changed {
+			s.emit(ctx, EventUpdated, tenantID, changed.Subscription)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// seedPriceChange creates Netflix subscriptions at 100, 120 and 150, one
+// starting in April, one ended in February and one for another service.
+// It returns the IDs of the ones at 100 and 120.
+func seedPriceChange(t *testing.T, svc *SubscriptionService) (int32, int32) {
+	t.Helper()
+	var ids []int32
+	for _, change := range []func(sub *Subscription){
+		func(sub *Subscription) {},
+		func(sub *Subscription) { sub.Price = 120 },
+		func(sub *Subscription) { sub.Price = 150 },
+		func(sub *Subscription) { sub.StartDate = month(time.April) },
+		func(sub *Subscription) { sub.EndDate = month(time.February) },
+		func(sub *Subscription) { sub.ServiceName = "Spotify" },
+	} {
+		sub := validSub()
+		change(&sub)
+		ids = append(ids, mustCreate(t, svc, sub).ID)
+	}
+	return ids[0], ids[1]
+}
+
+func TestChangeServicePriceValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		change    PriceChange
+		wantField string
+	}{
+		{name: "no service", change: PriceChange{Price: 150, EffectiveFrom: month(time.April)}, wantField: "service_name"},
+		{name: "no month", change: PriceChange{ServiceName: "Netflix", Price: 150}, wantField: "effective_from"},
+		{name: "past", change: PriceChange{ServiceName: "Netflix", Price: 150, EffectiveFrom: month(time.February)}, wantField: "effective_from"},
+		{name: "negative", change: PriceChange{ServiceName: "Netflix", Price: -1, EffectiveFrom: month(time.April)}, wantField: "price"},
+		{name: "currency", change: PriceChange{ServiceName: "Netflix", Price: 150, EffectiveFrom: month(time.April), Currency: "rub"}, wantField: "currency"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo := newTestService(month(time.March).AddDate(0, 0, 10))
+			seedPriceChange(t, svc)
+
+			_, err := svc.ChangeServicePrice(context.Background(), testTenant, tt.change)
+			if fieldOf(err) != tt.wantField {
+				t.Fatalf("ChangeServicePrice() error = %v, want a ValidationError on %q", err, tt.wantField)
+			}
+			if len(repo.data.prices) != 0 {
+				t.Errorf("invalid change stored %d prices", len(repo.data.prices))
+			}
+		})
+	}
+}
+
+func TestChangeServicePrice(t *testing.T) {
+	price100, price120 := int32(100), int32(120)
+	tests := []struct {
+		name      string
+		oldPrice  *int32
+		wantIDs   func(a, b int32) []int32
+		wantDelta int64
+	}{
+		{name: "all", wantIDs: func(a, b int32) []int32 { return []int32{a, b} }, wantDelta: 50 + 30},
+		{name: "old price 100", oldPrice: &price100, wantIDs: func(a, b int32) []int32 { return []int32{a} }, wantDelta: 50},
+		{name: "old price 120", oldPrice: &price120, wantIDs: func(a, b int32) []int32 { return []int32{b} }, wantDelta: 30},
+	}
+
+	for _, tt := range tests {
+		for _, dryRun := range []bool{true, false} {
+			name := tt.name
+			if dryRun {
+				name += " dry run"
+			}
+			t.Run(name, func(t *testing.T) {
+				ctx := context.Background()
+				svc, repo := newTestService(month(time.March).AddDate(0, 0, 10))
+				a, b := seedPriceChange(t, svc)
+				events := &recorder{}
+				svc.Events = events
+
+				res, err := svc.ChangeServicePrice(ctx, testTenant, PriceChange{
+					ServiceName:   "Netflix",
+					Price:         150,
+					EffectiveFrom: month(time.April),
+					OldPrice:      tt.oldPrice,
+					DryRun:        dryRun,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var ids []int32
+				for _, changed := range res.Changed {
+					ids = append(ids, changed.Subscription.ID)
+					if changed.NewPrice != 150 || changed.OldPrice != changed.Subscription.PriceAt(month(time.March)) {
+						t.Errorf("changed = %+v", changed)
+					}
+				}
+				want := tt.wantIDs(a, b)
+				if !slices.Equal(ids, want) {
+					t.Errorf("changed IDs = %v, want %v", ids, want)
+				}
+				if res.MonthlyDelta != tt.wantDelta {
+					t.Errorf("MonthlyDelta = %d, want %d", res.MonthlyDelta, tt.wantDelta)
+				}
+
+				if dryRun {
+					if len(repo.data.prices) != 0 || len(events.events) != 0 {
+						t.Errorf("dry run stored %d prices and sent %d events", len(repo.data.prices), len(events.events))
+					}
+					return
+				}
+				if len(repo.data.prices) != len(want) || len(events.events) != len(want) {
+					t.Errorf("stored %d prices and sent %d events, want %d", len(repo.data.prices), len(events.events), len(want))
+				}
+				for _, e := range events.events {
+					if e.Type != EventUpdated || e.Subscription.PriceAt(month(time.April)) != 150 {
+						t.Errorf("event %s has price %d in April", e.Type, e.Subscription.PriceAt(month(time.April)))
+					}
+				}
+				for _, id := range want {
+					sub, err := svc.Get(ctx, testTenant, id)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if got := sub.PriceAt(month(time.April)); got != 150 {
+						t.Errorf("price of %d in April = %d, want 150", id, got)
+					}
+					if got := sub.PriceAt(month(time.March)); got == 150 {
+						t.Errorf("price of %d in March changed to 150", id)
+					}
+				}
+			})
+		}
+	}
+}
+
+// MonthlyDelta is the change of ChargeAt, not of the price: a trial keeps
+// its price and discounts apply to the new one.
+func TestChangeServicePriceDelta(t *testing.T) {
+	for _, dryRun := range []bool{true, false} {
+		ctx := context.Background()
+		svc, _ := newTestService(month(time.March).AddDate(0, 0, 10))
+
+		trial := validSub()
+		trial.TrialEndsAt, trial.TrialPrice = month(time.May), 10
+		mustCreate(t, svc, trial)
+		discounts := []Discount{
+			{Kind: DiscountPercent, Amount: 50, StartDate: month(time.April), Cycles: 2},
+			{Kind: DiscountFixed, Amount: 120, StartDate: month(time.April), Cycles: 2},
+			{Kind: DiscountFixed, Amount: 20, StartDate: month(time.January), Cycles: 3},
+		}
+		for _, d := range discounts {
+			stored := mustCreate(t, svc, validSub())
+			if _, err := svc.AddDiscount(ctx, testTenant, stored.ID, d); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		res, err := svc.ChangeServicePrice(ctx, testTenant, PriceChange{ServiceName: "Netflix", Price: 150, EffectiveFrom: month(time.April), DryRun: dryRun})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Changed) != 4 {
+			t.Errorf("dry run %v: changed %d subscriptions, want 4", dryRun, len(res.Changed))
+		}
+		// The trial 0, half of 50, 30 above the fixed discount and the
+		// discount ended in March 50.
+		if want := int64(0 + 25 + 30 + 50); res.MonthlyDelta != want {
+			t.Errorf("dry run %v: MonthlyDelta = %d, want %d", dryRun, res.MonthlyDelta, want)
+		}
+	}
+}
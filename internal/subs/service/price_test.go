@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPriceTimelineCost(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end time.Time
+		trialEnds  time.Time
+		changes    []Price
+		from, to   time.Time
+		want       int64
+		wantFirst  int32
+	}{
+		{
+			name:  "no change",
+			start: month(time.January), from: month(time.January), to: month(time.December),
+			want: 12 * 100, wantFirst: 100,
+		},
+		{
+			name:    "change mid-range",
+			start:   month(time.January),
+			changes: []Price{{EffectiveFrom: month(time.April), Price: 150}},
+			from:    month(time.February), to: month(time.May),
+			want: 2*100 + 2*150, wantFirst: 100,
+		},
+		{
+			name:    "range after the change",
+			start:   month(time.January),
+			changes: []Price{{EffectiveFrom: month(time.April), Price: 150}},
+			from:    month(time.July), to: month(time.August),
+			want: 2 * 150, wantFirst: 100,
+		},
+		{
+			name:    "range before the change",
+			start:   month(time.January),
+			changes: []Price{{EffectiveFrom: month(time.April), Price: 150}},
+			from:    month(time.January), to: month(time.March),
+			want: 3 * 100, wantFirst: 100,
+		},
+		{
+			name:  "several changes",
+			start: month(time.January),
+			changes: []Price{
+				{EffectiveFrom: month(time.March), Price: 150},
+				{EffectiveFrom: month(time.June), Price: 200},
+				{EffectiveFrom: month(time.September), Price: 120},
+			},
+			from: month(time.January), to: month(time.December),
+			want: 2*100 + 3*150 + 3*200 + 4*120, wantFirst: 100,
+		},
+		{
+			name:    "change before start_date",
+			start:   month(time.March),
+			changes: []Price{{EffectiveFrom: month(time.January), Price: 150}},
+			from:    month(time.January), to: month(time.December),
+			want: 10 * 150, wantFirst: 150,
+		},
+		{
+			name:    "change at start_date",
+			start:   month(time.March),
+			changes: []Price{{EffectiveFrom: month(time.March), Price: 150}, {EffectiveFrom: month(time.May), Price: 200}},
+			from:    month(time.January), to: month(time.December),
+			want: 2*150 + 8*200, wantFirst: 150,
+		},
+		{
+			name:  "change after end_date",
+			start: month(time.January), end: month(time.June),
+			changes: []Price{{EffectiveFrom: month(time.September), Price: 150}},
+			from:    month(time.January), to: month(time.December),
+			want: 6 * 100, wantFirst: 100,
+		},
+		{
+			name:  "change during a trial",
+			start: month(time.January), trialEnds: month(time.March).AddDate(0, 0, 9),
+			changes: []Price{{EffectiveFrom: month(time.February), Price: 150}},
+			from:    month(time.January), to: month(time.December),
+			want: 2*10 + 10*150, wantFirst: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := Subscription{StartDate: tt.start, EndDate: tt.end, TrialEndsAt: tt.trialEnds, TrialPrice: 10}
+			sub.Prices = timeline(sub, 100, tt.changes)
+			sub.Price = sub.PriceAt(tt.to)
+
+			if got := sub.Prices[0].Price; got != tt.wantFirst {
+				t.Errorf("first price = %d, want %d", got, tt.wantFirst)
+			}
+			if got := sub.Cost(tt.from, tt.to); got != tt.want {
+				t.Errorf("Cost() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriceAt(t *testing.T) {
+	sub := Subscription{Price: 100, StartDate: month(time.January)}
+	if got := sub.PriceAt(month(time.June)); got != 100 {
+		t.Errorf("PriceAt() without a timeline = %d, want 100", got)
+	}
+
+	sub.Prices = timeline(sub, 100, []Price{{EffectiveFrom: month(time.April), Price: 150}, {EffectiveFrom: month(time.July), Price: 80}})
+	tests := []struct {
+		month time.Time
+		want  int32
+	}{
+		{month: month(time.January).AddDate(0, -1, 0), want: 100},
+		{month: month(time.March), want: 100},
+		{month: month(time.April), want: 150},
+		{month: month(time.June), want: 150},
+		{month: month(time.July), want: 80},
+		{month: month(time.December), want: 80},
+	}
+	for _, tt := range tests {
+		if got := sub.PriceAt(tt.month); got != tt.want {
+			t.Errorf("PriceAt(%v) = %d, want %d", tt.month, got, tt.want)
+		}
+	}
+}
+
+func TestSchedulePrice(t *testing.T) {
+	now := month(time.March).AddDate(0, 0, 10)
+	tests := []struct {
+		name      string
+		price     Price
+		wantField string
+	}{
+		{name: "next month", price: Price{EffectiveFrom: month(time.April), Price: 150}},
+		{name: "this month", price: Price{EffectiveFrom: month(time.March), Price: 150, Currency: "USD"}},
+		{name: "no month", price: Price{Price: 150}, wantField: "effective_from"},
+		{name: "past", price: Price{EffectiveFrom: month(time.February), Price: 150}, wantField: "effective_from"},
+		{name: "after the end", price: Price{EffectiveFrom: month(time.November), Price: 150}, wantField: "effective_from"},
+		{name: "negative", price: Price{EffectiveFrom: month(time.April), Price: -1}, wantField: "price"},
+		{name: "currency", price: Price{EffectiveFrom: month(time.April), Price: 150, Currency: "rub"}, wantField: "currency"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo := newTestService(now)
+			sub := validSub()
+			sub.EndDate = month(time.October)
+			stored := mustCreate(t, svc, sub)
+
+			prices, err := svc.SchedulePrice(context.Background(), testTenant, stored.ID, tt.price)
+			if fieldOf(err) != tt.wantField {
+				t.Fatalf("SchedulePrice() error = %v, want a ValidationError on %q", err, tt.wantField)
+			}
+			if tt.wantField != "" {
+				if len(repo.data.prices) != 0 {
+					t.Errorf("invalid price was stored")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(prices) != 2 || prices[0].Price != 100 || prices[1].Price != 150 || !prices[1].EffectiveFrom.Equal(tt.price.EffectiveFrom) {
+				t.Errorf("prices = %+v", prices)
+			}
+			if prices[1].Currency == "" {
+				t.Errorf("currency of %+v is empty", prices[1])
+			}
+		})
+	}
+}
+
+// A second change of the same month replaces the first.
+func TestSchedulePriceSameMonth(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(month(time.March))
+	stored := mustCreate(t, svc, validSub())
+
+	for _, price := range []int32{150, 180} {
+		if _, err := svc.SchedulePrice(ctx, testTenant, stored.ID, Price{EffectiveFrom: month(time.June), Price: price}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prices, err := svc.Prices(ctx, testTenant, stored.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prices) != 2 || prices[1].Price != 180 {
+		t.Errorf("prices = %+v, want 100 then 180", prices)
+	}
+}
+
+// A new price applies from the current month, earlier months keep the old
+// one.
+func TestUpdatePrice(t *testing.T) {
+	svc, _ := newTestService(month(time.March).AddDate(0, 0, 10))
+	stored := mustCreate(t, svc, validSub())
+
+	stored.Price = 150
+	updated, err := svc.Update(context.Background(), testTenant, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Price != 150 {
+		t.Errorf("price = %d, want 150", updated.Price)
+	}
+	if got := updated.Cost(month(time.January), month(time.April)); got != 100+100+150+150 {
+		t.Errorf("cost = %d, want %d", got, 100+100+150+150)
+	}
+}
+
+// Before the first month is over the starting price is replaced.
+func TestUpdatePriceFirstMonth(t *testing.T) {
+	svc, repo := newTestService(month(time.January).AddDate(0, 0, 10))
+	stored := mustCreate(t, svc, validSub())
+
+	stored.Price = 150
+	updated, err := svc.Update(context.Background(), testTenant, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.data.prices) != 0 || len(updated.Prices) != 1 || updated.Prices[0].Price != 150 {
+		t.Errorf("prices = %+v, stored %d changes, want only the starting price of 150", updated.Prices, len(repo.data.prices))
+	}
+}
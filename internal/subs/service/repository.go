@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"usersubs/internal/db"
+	"usersubs/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+// Queries are the queries of db.Queries the service uses.
+type Queries interface {
+	GetSub(ctx context.Context, arg db.GetSubParams) (db.Subscription, error)
+	FilterSubs(ctx context.Context, arg db.FilterSubsParams) ([]db.Subscription, error)
+	GetSubsByUsers(ctx context.Context, arg db.GetSubsByUsersParams) ([]db.Subscription, error)
+	GetSubsByServices(ctx context.Context, arg db.GetSubsByServicesParams) ([]db.Subscription, error)
+	GetEndingTrials(ctx context.Context, arg db.GetEndingTrialsParams) ([]db.Subscription, error)
+	AddSub(ctx context.Context, arg db.AddSubParams) (int32, error)
+	UpdateSub(ctx context.Context, arg db.UpdateSubParams) (int32, error)
+	DeleteSub(ctx context.Context, arg db.DeleteSubParams) (int32, error)
+	DeleteUserSubs(ctx context.Context, arg db.DeleteUserSubsParams) ([]int32, error)
+	GetSubForUpdate(ctx context.Context, arg db.GetSubForUpdateParams) (db.Subscription, error)
+	SetSubStatus(ctx context.Context, arg db.SetSubStatusParams) (db.Subscription, error)
+	AddStatusChange(ctx context.Context, arg db.AddStatusChangeParams) error
+	GetStatusChanges(ctx context.Context, arg db.GetStatusChangesParams) ([]db.SubscriptionStatusChange, error)
+	AddPause(ctx context.Context, arg db.AddPauseParams) (db.SubscriptionPause, error)
+	GetPauses(ctx context.Context, arg db.GetPausesParams) ([]db.SubscriptionPause, error)
+	GetPausesBySubs(ctx context.Context, arg db.GetPausesBySubsParams) ([]db.SubscriptionPause, error)
+	DeletePause(ctx context.Context, arg db.DeletePauseParams) (int32, error)
+	EndPause(ctx context.Context, arg db.EndPauseParams) (db.SubscriptionPause, error)
+	SetPrice(ctx context.Context, arg db.SetPriceParams) (db.SubscriptionPrice, error)
+	GetPricesBySubs(ctx context.Context, arg db.GetPricesBySubsParams) ([]db.SubscriptionPrice, error)
+	GetServiceSubsForUpdate(ctx context.Context, arg db.GetServiceSubsForUpdateParams) ([]db.Subscription, error)
+	GetSubsByIDs(ctx context.Context, arg db.GetSubsByIDsParams) ([]db.Subscription, error)
+	AddDiscount(ctx context.Context, arg db.AddDiscountParams) (db.SubscriptionDiscount, error)
+	GetDiscountsBySubs(ctx context.Context, arg db.GetDiscountsBySubsParams) ([]db.SubscriptionDiscount, error)
+	DeleteDiscount(ctx context.Context, arg db.DeleteDiscountParams) (int32, error)
+	GetExpiringDiscounts(ctx context.Context, arg db.GetExpiringDiscountsParams) ([]db.SubscriptionDiscount, error)
+	AddMember(ctx context.Context, arg db.AddMemberParams) (db.SubscriptionMember, error)
+	AcceptMember(ctx context.Context, arg db.AcceptMemberParams) (db.SubscriptionMember, error)
+	GetMembersBySubs(ctx context.Context, arg db.GetMembersBySubsParams) ([]db.SubscriptionMember, error)
+	RemoveMember(ctx context.Context, arg db.RemoveMemberParams) (int32, error)
+	AddBudget(ctx context.Context, arg db.AddBudgetParams) (db.Budget, error)
+	GetBudgets(ctx context.Context, arg db.GetBudgetsParams) ([]db.Budget, error)
+	DeleteBudget(ctx context.Context, arg db.DeleteBudgetParams) (int32, error)
+	AddCategory(ctx context.Context, arg db.AddCategoryParams) (db.Category, error)
+	GetCategories(ctx context.Context, tenantID uuid.UUID) ([]db.Category, error)
+	GetCategoryByName(ctx context.Context, arg db.GetCategoryByNameParams) (db.Category, error)
+	DeleteCategory(ctx context.Context, arg db.DeleteCategoryParams) (int32, error)
+	AddSubCategory(ctx context.Context, arg db.AddSubCategoryParams) error
+	DeleteSubCategory(ctx context.Context, arg db.DeleteSubCategoryParams) (int32, error)
+	GetCategoriesBySubs(ctx context.Context, arg db.GetCategoriesBySubsParams) ([]db.GetCategoriesBySubsRow, error)
+	UpsertTag(ctx context.Context, arg db.UpsertTagParams) (int32, error)
+	GetTags(ctx context.Context, tenantID uuid.UUID) ([]string, error)
+	AddSubTag(ctx context.Context, arg db.AddSubTagParams) error
+	DeleteSubTag(ctx context.Context, arg db.DeleteSubTagParams) (int32, error)
+	GetTagsBySubs(ctx context.Context, arg db.GetTagsBySubsParams) ([]db.GetTagsBySubsRow, error)
+}
+
+// Repository runs fn in a transaction scoped to a tenant, the changes are
+// committed when fn returns nil.
+type Repository interface {
+	InTx(ctx context.Context, tenantID uuid.UUID, fn func(Queries) error) error
+}
+
+// PostgresRepository keeps subscriptions in Postgres behind row level
+// security.
+type PostgresRepository struct {
+	DB *sql.DB
+}
+
+func (r PostgresRepository) InTx(ctx context.Context, tenantID uuid.UUID, fn func(Queries) error) error {
+	return tenant.RunInTx(ctx, r.DB, tenantID, func(q *db.Queries) error {
+		return fn(q)
+	})
+}
@@ -0,0 +1,664 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"sync"
+	"time"
+	"usersubs/internal/clock"
+	"usersubs/internal/db"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// fakeData are the tables of fakeRepo.
+type fakeData struct {
+	subs      []db.Subscription
+	changes   []db.SubscriptionStatusChange
+	pauses    []db.SubscriptionPause
+	prices    []db.SubscriptionPrice
+	discounts []db.SubscriptionDiscount
+	members   []db.SubscriptionMember
+	budgets   []db.Budget
+	// categories and tags with the rows linking them to subscriptions.
+	categories    []db.Category
+	subCategories []db.SubscriptionCategory
+	tags          []db.Tag
+	subTags       []db.SubscriptionTag
+	lastID        int32
+}
+
+func (d fakeData) clone() fakeData {
+	d.subs = slices.Clone(d.subs)
+	d.changes = slices.Clone(d.changes)
+	d.pauses = slices.Clone(d.pauses)
+	d.prices = slices.Clone(d.prices)
+	d.discounts = slices.Clone(d.discounts)
+	d.members = slices.Clone(d.members)
+	d.budgets = slices.Clone(d.budgets)
+	d.categories = slices.Clone(d.categories)
+	d.subCategories = slices.Clone(d.subCategories)
+	d.tags = slices.Clone(d.tags)
+	d.subTags = slices.Clone(d.subTags)
+	return d
+}
+
+// fakeRepo keeps the rows in memory, a transaction sees one tenant and
+// is rolled back when fn fails.
+type fakeRepo struct {
+	mu   sync.Mutex
+	data fakeData
+}
+
+func (r *fakeRepo) InTx(ctx context.Context, tenantID uuid.UUID, fn func(Queries) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saved := r.data.clone()
+	if err := fn(fakeQueries{data: &r.data, tenantID: tenantID}); err != nil {
+		r.data = saved
+		return err
+	}
+	return nil
+}
+
+// fakeQueries implements the queries the tests reach, the others panic on
+// the nil Queries.
+type fakeQueries struct {
+	Queries
+	data     *fakeData
+	tenantID uuid.UUID
+}
+
+func (q fakeQueries) nextID() int32 {
+	q.data.lastID++
+	return q.data.lastID
+}
+
+func (q fakeQueries) sub(id int32, tenantID uuid.UUID) (*db.Subscription, error) {
+	for i, row := range q.data.subs {
+		if row.ID == id && row.TenantID == tenantID && tenantID == q.tenantID {
+			return &q.data.subs[i], nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (q fakeQueries) GetSub(ctx context.Context, arg db.GetSubParams) (db.Subscription, error) {
+	row, err := q.sub(arg.ID, arg.TenantID)
+	if err != nil {
+		return db.Subscription{}, err
+	}
+	return *row, nil
+}
+
+func (q fakeQueries) GetSubForUpdate(ctx context.Context, arg db.GetSubForUpdateParams) (db.Subscription, error) {
+	return q.GetSub(ctx, db.GetSubParams{ID: arg.ID, TenantID: arg.TenantID})
+}
+
+// FilterSubs only supports the user, service, category and tag filters.
+func (q fakeQueries) FilterSubs(ctx context.Context, arg db.FilterSubsParams) ([]db.Subscription, error) {
+	var rows []db.Subscription
+	for _, row := range q.data.subs {
+		if row.TenantID != arg.TenantID {
+			continue
+		}
+		if arg.UserID.Valid && row.UserID != arg.UserID.UUID && !(arg.IncludeShared && q.joined(row.ID, arg.UserID.UUID)) {
+			continue
+		}
+		if arg.ServiceName.Valid && row.ServiceName != arg.ServiceName.String {
+			continue
+		}
+		if arg.Category.Valid && !slices.Contains(q.categoriesOf(row.ID), arg.Category.String) {
+			continue
+		}
+		if arg.Tag.Valid && !slices.Contains(q.tagsOf(row.ID), arg.Tag.String) {
+			continue
+		}
+		if arg.AfterID.Valid && row.ID <= arg.AfterID.Int32 {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	rows = rows[min(int(arg.PageOffset), len(rows)):]
+	if arg.PageLimit.Valid {
+		rows = rows[:min(int(arg.PageLimit.Int32), len(rows))]
+	}
+	return rows, nil
+}
+
+func (q fakeQueries) joined(subID int32, userID uuid.UUID) bool {
+	for _, m := range q.data.members {
+		if m.SubscriptionID == subID && m.UserID == userID && m.JoinedAt.Valid {
+			return true
+		}
+	}
+	return false
+}
+
+func (q fakeQueries) GetSubsByIDs(ctx context.Context, arg db.GetSubsByIDsParams) ([]db.Subscription, error) {
+	var rows []db.Subscription
+	for _, row := range q.data.subs {
+		if row.TenantID == arg.TenantID && slices.Contains(arg.Ids, row.ID) {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (q fakeQueries) GetSubsByUsers(ctx context.Context, arg db.GetSubsByUsersParams) ([]db.Subscription, error) {
+	var rows []db.Subscription
+	for _, row := range q.data.subs {
+		if row.TenantID != arg.TenantID {
+			continue
+		}
+		if slices.Contains(arg.UserIds, row.UserID) || slices.ContainsFunc(arg.UserIds, func(id uuid.UUID) bool { return q.joined(row.ID, id) }) {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (q fakeQueries) AddSub(ctx context.Context, arg db.AddSubParams) (int32, error) {
+	row := db.Subscription{
+		ID:          q.nextID(),
+		ServiceName: arg.ServiceName,
+		Price:       arg.Price,
+		UserID:      arg.UserID,
+		StartedAt:   arg.StartedAt,
+		EndedAt:     arg.EndedAt,
+		TenantID:    arg.TenantID,
+		Status:      arg.Status,
+		TrialEndsAt: arg.TrialEndsAt,
+		TrialPrice:  arg.TrialPrice,
+		Metadata:    arg.Metadata,
+	}
+	q.data.subs = append(q.data.subs, row)
+	return row.ID, nil
+}
+
+func (q fakeQueries) UpdateSub(ctx context.Context, arg db.UpdateSubParams) (int32, error) {
+	row, err := q.sub(arg.ID, arg.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	row.ServiceName = arg.ServiceName
+	row.Price = arg.Price
+	row.UserID = arg.UserID
+	row.StartedAt = arg.StartedAt
+	row.EndedAt = arg.EndedAt
+	row.UpdatedAt = arg.UpdatedAt
+	row.TrialEndsAt = arg.TrialEndsAt
+	row.TrialPrice = arg.TrialPrice
+	row.Metadata = arg.Metadata
+	return row.ID, nil
+}
+
+func (q fakeQueries) SetSubStatus(ctx context.Context, arg db.SetSubStatusParams) (db.Subscription, error) {
+	row, err := q.sub(arg.ID, arg.TenantID)
+	if err != nil {
+		return db.Subscription{}, err
+	}
+	row.Status = arg.Status
+	row.StatusChangedAt = arg.ChangedAt
+	row.CancelAtPeriodEnd = arg.CancelAtPeriodEnd
+	row.EndedAt = arg.EndedAt
+	return *row, nil
+}
+
+func (q fakeQueries) AddStatusChange(ctx context.Context, arg db.AddStatusChangeParams) error {
+	q.data.changes = append(q.data.changes, db.SubscriptionStatusChange{
+		ID:             q.nextID(),
+		SubscriptionID: arg.SubscriptionID,
+		TenantID:       arg.TenantID,
+		FromStatus:     arg.FromStatus,
+		ToStatus:       arg.ToStatus,
+		ChangedAt:      arg.ChangedAt,
+	})
+	return nil
+}
+
+func (q fakeQueries) GetStatusChanges(ctx context.Context, arg db.GetStatusChangesParams) ([]db.SubscriptionStatusChange, error) {
+	var rows []db.SubscriptionStatusChange
+	for _, row := range q.data.changes {
+		if row.SubscriptionID == arg.SubscriptionID && row.TenantID == arg.TenantID {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (q fakeQueries) AddPause(ctx context.Context, arg db.AddPauseParams) (db.SubscriptionPause, error) {
+	row := db.SubscriptionPause{
+		ID:             q.nextID(),
+		SubscriptionID: arg.SubscriptionID,
+		TenantID:       arg.TenantID,
+		StartedAt:      arg.StartedAt,
+		EndedAt:        arg.EndedAt,
+	}
+	q.data.pauses = append(q.data.pauses, row)
+	return row, nil
+}
+
+func (q fakeQueries) GetPauses(ctx context.Context, arg db.GetPausesParams) ([]db.SubscriptionPause, error) {
+	return q.GetPausesBySubs(ctx, db.GetPausesBySubsParams{SubscriptionIds: []int32{arg.SubscriptionID}, TenantID: arg.TenantID})
+}
+
+func (q fakeQueries) GetPausesBySubs(ctx context.Context, arg db.GetPausesBySubsParams) ([]db.SubscriptionPause, error) {
+	var rows []db.SubscriptionPause
+	for _, row := range q.data.pauses {
+		if row.TenantID == arg.TenantID && slices.Contains(arg.SubscriptionIds, row.SubscriptionID) {
+			rows = append(rows, row)
+		}
+	}
+	slices.SortFunc(rows, func(a, b db.SubscriptionPause) int {
+		return cmp.Or(cmp.Compare(a.SubscriptionID, b.SubscriptionID), a.StartedAt.Compare(b.StartedAt))
+	})
+	return rows, nil
+}
+
+func (q fakeQueries) pause(id, subID int32, tenantID uuid.UUID) (int, error) {
+	for i, row := range q.data.pauses {
+		if row.ID == id && row.SubscriptionID == subID && row.TenantID == tenantID {
+			return i, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (q fakeQueries) DeletePause(ctx context.Context, arg db.DeletePauseParams) (int32, error) {
+	i, err := q.pause(arg.ID, arg.SubscriptionID, arg.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	q.data.pauses = slices.Delete(q.data.pauses, i, i+1)
+	return arg.ID, nil
+}
+
+func (q fakeQueries) EndPause(ctx context.Context, arg db.EndPauseParams) (db.SubscriptionPause, error) {
+	i, err := q.pause(arg.ID, arg.SubscriptionID, arg.TenantID)
+	if err != nil {
+		return db.SubscriptionPause{}, err
+	}
+	q.data.pauses[i].EndedAt = arg.EndedAt
+	return q.data.pauses[i], nil
+}
+
+func (q fakeQueries) SetPrice(ctx context.Context, arg db.SetPriceParams) (db.SubscriptionPrice, error) {
+	q.data.prices = slices.DeleteFunc(q.data.prices, func(row db.SubscriptionPrice) bool {
+		return row.SubscriptionID == arg.SubscriptionID && row.EffectiveFrom.Equal(arg.EffectiveFrom)
+	})
+	row := db.SubscriptionPrice{
+		ID:             q.nextID(),
+		SubscriptionID: arg.SubscriptionID,
+		TenantID:       arg.TenantID,
+		EffectiveFrom:  arg.EffectiveFrom,
+		Price:          arg.Price,
+		Currency:       arg.Currency,
+	}
+	q.data.prices = append(q.data.prices, row)
+	return row, nil
+}
+
+func (q fakeQueries) GetPricesBySubs(ctx context.Context, arg db.GetPricesBySubsParams) ([]db.SubscriptionPrice, error) {
+	var rows []db.SubscriptionPrice
+	for _, row := range q.data.prices {
+		if row.TenantID == arg.TenantID && slices.Contains(arg.SubscriptionIds, row.SubscriptionID) {
+			rows = append(rows, row)
+		}
+	}
+	slices.SortFunc(rows, func(a, b db.SubscriptionPrice) int {
+		return cmp.Or(cmp.Compare(a.SubscriptionID, b.SubscriptionID), a.EffectiveFrom.Compare(b.EffectiveFrom))
+	})
+	return rows, nil
+}
+
+func (q fakeQueries) AddDiscount(ctx context.Context, arg db.AddDiscountParams) (db.SubscriptionDiscount, error) {
+	row := db.SubscriptionDiscount{
+		ID:             q.nextID(),
+		SubscriptionID: arg.SubscriptionID,
+		TenantID:       arg.TenantID,
+		Kind:           arg.Kind,
+		Amount:         arg.Amount,
+		StartedAt:      arg.StartedAt,
+		Cycles:         arg.Cycles,
+		Until:          arg.Until,
+		EndsAt:         arg.EndsAt,
+	}
+	q.data.discounts = append(q.data.discounts, row)
+	return row, nil
+}
+
+func (q fakeQueries) GetDiscountsBySubs(ctx context.Context, arg db.GetDiscountsBySubsParams) ([]db.SubscriptionDiscount, error) {
+	var rows []db.SubscriptionDiscount
+	for _, row := range q.data.discounts {
+		if row.TenantID == arg.TenantID && slices.Contains(arg.SubscriptionIds, row.SubscriptionID) {
+			rows = append(rows, row)
+		}
+	}
+	slices.SortFunc(rows, func(a, b db.SubscriptionDiscount) int {
+		return cmp.Or(cmp.Compare(a.SubscriptionID, b.SubscriptionID), a.StartedAt.Compare(b.StartedAt))
+	})
+	return rows, nil
+}
+
+func (q fakeQueries) DeleteDiscount(ctx context.Context, arg db.DeleteDiscountParams) (int32, error) {
+	for i, row := range q.data.discounts {
+		if row.ID == arg.ID && row.SubscriptionID == arg.SubscriptionID && row.TenantID == arg.TenantID {
+			q.data.discounts = slices.Delete(q.data.discounts, i, i+1)
+			return arg.ID, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+// GetExpiringDiscounts ignores the user filter.
+func (q fakeQueries) GetExpiringDiscounts(ctx context.Context, arg db.GetExpiringDiscountsParams) ([]db.SubscriptionDiscount, error) {
+	var rows []db.SubscriptionDiscount
+	for _, row := range q.data.discounts {
+		if row.TenantID == arg.TenantID && row.EndsAt.After(arg.Now) && !row.EndsAt.After(arg.Until) {
+			rows = append(rows, row)
+		}
+	}
+	slices.SortFunc(rows, func(a, b db.SubscriptionDiscount) int {
+		return cmp.Or(a.EndsAt.Compare(b.EndsAt), cmp.Compare(a.ID, b.ID))
+	})
+	return rows, nil
+}
+
+func (q fakeQueries) AddMember(ctx context.Context, arg db.AddMemberParams) (db.SubscriptionMember, error) {
+	row := db.SubscriptionMember{
+		ID:             q.nextID(),
+		SubscriptionID: arg.SubscriptionID,
+		TenantID:       arg.TenantID,
+		UserID:         arg.UserID,
+		Status:         arg.Status,
+		Split:          arg.Split,
+		Amount:         arg.Amount,
+		JoinedAt:       arg.JoinedAt,
+	}
+	q.data.members = append(q.data.members, row)
+	return row, nil
+}
+
+func (q fakeQueries) AcceptMember(ctx context.Context, arg db.AcceptMemberParams) (db.SubscriptionMember, error) {
+	for i, row := range q.data.members {
+		if row.SubscriptionID == arg.SubscriptionID && row.UserID == arg.UserID && row.TenantID == arg.TenantID && row.Status == string(MemberInvited) && !row.RemovedAt.Valid {
+			q.data.members[i].Status = string(MemberActive)
+			q.data.members[i].JoinedAt = arg.JoinedAt
+			return q.data.members[i], nil
+		}
+	}
+	return db.SubscriptionMember{}, sql.ErrNoRows
+}
+
+func (q fakeQueries) RemoveMember(ctx context.Context, arg db.RemoveMemberParams) (int32, error) {
+	for i, row := range q.data.members {
+		if row.SubscriptionID == arg.SubscriptionID && row.UserID == arg.UserID && row.TenantID == arg.TenantID && !row.RemovedAt.Valid {
+			q.data.members[i].RemovedAt = arg.RemovedAt
+			return row.ID, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (q fakeQueries) GetMembersBySubs(ctx context.Context, arg db.GetMembersBySubsParams) ([]db.SubscriptionMember, error) {
+	var rows []db.SubscriptionMember
+	for _, row := range q.data.members {
+		if row.TenantID == arg.TenantID && slices.Contains(arg.SubscriptionIds, row.SubscriptionID) {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (q fakeQueries) AddBudget(ctx context.Context, arg db.AddBudgetParams) (db.Budget, error) {
+	row := db.Budget{
+		ID:       q.nextID(),
+		TenantID: arg.TenantID,
+		UserID:   arg.UserID,
+		Scope:    arg.Scope,
+		Target:   arg.Target,
+		Amount:   arg.Amount,
+		Currency: arg.Currency,
+		Period:   arg.Period,
+		Enforce:  arg.Enforce,
+	}
+	q.data.budgets = append(q.data.budgets, row)
+	return row, nil
+}
+
+func (q fakeQueries) GetBudgets(ctx context.Context, arg db.GetBudgetsParams) ([]db.Budget, error) {
+	var rows []db.Budget
+	for _, row := range q.data.budgets {
+		if row.UserID == arg.UserID && row.TenantID == arg.TenantID {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (q fakeQueries) DeleteBudget(ctx context.Context, arg db.DeleteBudgetParams) (int32, error) {
+	for i, row := range q.data.budgets {
+		if row.ID == arg.ID && row.UserID == arg.UserID && row.TenantID == arg.TenantID {
+			q.data.budgets = slices.Delete(q.data.budgets, i, i+1)
+			return row.ID, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (q fakeQueries) AddCategory(ctx context.Context, arg db.AddCategoryParams) (db.Category, error) {
+	for _, row := range q.data.categories {
+		if row.TenantID == arg.TenantID && row.Name == arg.Name {
+			return db.Category{}, &pq.Error{Code: "23505"}
+		}
+	}
+	row := db.Category{ID: q.nextID(), TenantID: arg.TenantID, Name: arg.Name}
+	q.data.categories = append(q.data.categories, row)
+	return row, nil
+}
+
+func (q fakeQueries) GetCategories(ctx context.Context, tenantID uuid.UUID) ([]db.Category, error) {
+	var rows []db.Category
+	for _, row := range q.data.categories {
+		if row.TenantID == tenantID {
+			rows = append(rows, row)
+		}
+	}
+	slices.SortFunc(rows, func(a, b db.Category) int { return cmp.Compare(a.Name, b.Name) })
+	return rows, nil
+}
+
+func (q fakeQueries) GetCategoryByName(ctx context.Context, arg db.GetCategoryByNameParams) (db.Category, error) {
+	for _, row := range q.data.categories {
+		if row.Name == arg.Name && row.TenantID == arg.TenantID {
+			return row, nil
+		}
+	}
+	return db.Category{}, sql.ErrNoRows
+}
+
+func (q fakeQueries) DeleteCategory(ctx context.Context, arg db.DeleteCategoryParams) (int32, error) {
+	for i, row := range q.data.categories {
+		if row.ID == arg.ID && row.TenantID == arg.TenantID {
+			q.data.categories = slices.Delete(q.data.categories, i, i+1)
+			q.data.subCategories = slices.DeleteFunc(q.data.subCategories, func(sc db.SubscriptionCategory) bool { return sc.CategoryID == row.ID })
+			return row.ID, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (q fakeQueries) AddSubCategory(ctx context.Context, arg db.AddSubCategoryParams) error {
+	row := db.SubscriptionCategory(arg)
+	if !slices.Contains(q.data.subCategories, row) {
+		q.data.subCategories = append(q.data.subCategories, row)
+	}
+	return nil
+}
+
+func (q fakeQueries) DeleteSubCategory(ctx context.Context, arg db.DeleteSubCategoryParams) (int32, error) {
+	c, err := q.GetCategoryByName(ctx, db.GetCategoryByNameParams{Name: arg.Name, TenantID: arg.TenantID})
+	if err != nil {
+		return 0, err
+	}
+	row := db.SubscriptionCategory{SubscriptionID: arg.SubscriptionID, CategoryID: c.ID, TenantID: arg.TenantID}
+	i := slices.Index(q.data.subCategories, row)
+	if i < 0 {
+		return 0, sql.ErrNoRows
+	}
+	q.data.subCategories = slices.Delete(q.data.subCategories, i, i+1)
+	return c.ID, nil
+}
+
+// categoriesOf are the names of the categories of a subscription in order.
+func (q fakeQueries) categoriesOf(subID int32) []string {
+	var names []string
+	for _, sc := range q.data.subCategories {
+		for _, c := range q.data.categories {
+			if sc.SubscriptionID == subID && sc.CategoryID == c.ID {
+				names = append(names, c.Name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (q fakeQueries) GetCategoriesBySubs(ctx context.Context, arg db.GetCategoriesBySubsParams) ([]db.GetCategoriesBySubsRow, error) {
+	var rows []db.GetCategoriesBySubsRow
+	for _, row := range q.data.subs {
+		if row.TenantID != arg.TenantID || !slices.Contains(arg.SubscriptionIds, row.ID) {
+			continue
+		}
+		for _, name := range q.categoriesOf(row.ID) {
+			rows = append(rows, db.GetCategoriesBySubsRow{SubscriptionID: row.ID, Name: name})
+		}
+	}
+	return rows, nil
+}
+
+func (q fakeQueries) UpsertTag(ctx context.Context, arg db.UpsertTagParams) (int32, error) {
+	for _, row := range q.data.tags {
+		if row.Name == arg.Name && row.TenantID == arg.TenantID {
+			return row.ID, nil
+		}
+	}
+	row := db.Tag{ID: q.nextID(), TenantID: arg.TenantID, Name: arg.Name}
+	q.data.tags = append(q.data.tags, row)
+	return row.ID, nil
+}
+
+func (q fakeQueries) GetTags(ctx context.Context, tenantID uuid.UUID) ([]string, error) {
+	var names []string
+	for _, row := range q.data.tags {
+		if row.TenantID == tenantID {
+			names = append(names, row.Name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+func (q fakeQueries) AddSubTag(ctx context.Context, arg db.AddSubTagParams) error {
+	row := db.SubscriptionTag(arg)
+	if !slices.Contains(q.data.subTags, row) {
+		q.data.subTags = append(q.data.subTags, row)
+	}
+	return nil
+}
+
+func (q fakeQueries) DeleteSubTag(ctx context.Context, arg db.DeleteSubTagParams) (int32, error) {
+	for _, tag := range q.data.tags {
+		if tag.Name != arg.Name || tag.TenantID != arg.TenantID {
+			continue
+		}
+		i := slices.Index(q.data.subTags, db.SubscriptionTag{SubscriptionID: arg.SubscriptionID, TagID: tag.ID, TenantID: arg.TenantID})
+		if i >= 0 {
+			q.data.subTags = slices.Delete(q.data.subTags, i, i+1)
+			return tag.ID, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+// tagsOf are the tags of a subscription in order.
+func (q fakeQueries) tagsOf(subID int32) []string {
+	var names []string
+	for _, st := range q.data.subTags {
+		for _, tag := range q.data.tags {
+			if st.SubscriptionID == subID && st.TagID == tag.ID {
+				names = append(names, tag.Name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (q fakeQueries) GetTagsBySubs(ctx context.Context, arg db.GetTagsBySubsParams) ([]db.GetTagsBySubsRow, error) {
+	var rows []db.GetTagsBySubsRow
+	for _, row := range q.data.subs {
+		if row.TenantID != arg.TenantID || !slices.Contains(arg.SubscriptionIds, row.ID) {
+			continue
+		}
+		for _, name := range q.tagsOf(row.ID) {
+			rows = append(rows, db.GetTagsBySubsRow{SubscriptionID: row.ID, Name: name})
+		}
+	}
+	return rows, nil
+}
+
+func (q fakeQueries) GetServiceSubsForUpdate(ctx context.Context, arg db.GetServiceSubsForUpdateParams) ([]db.Subscription, error) {
+	var rows []db.Subscription
+	for _, row := range q.data.subs {
+		if row.TenantID != arg.TenantID || row.ServiceName != arg.ServiceName || !row.StartedAt.Before(arg.EffectiveFrom) {
+			continue
+		}
+		if row.EndedAt.Valid && row.EndedAt.Time.Before(arg.EffectiveFrom) {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (q fakeQueries) DeleteSub(ctx context.Context, arg db.DeleteSubParams) (int32, error) {
+	if _, err := q.sub(arg.ID, arg.TenantID); err != nil {
+		return 0, err
+	}
+	q.data.subs = slices.DeleteFunc(q.data.subs, func(row db.Subscription) bool { return row.ID == arg.ID })
+	return arg.ID, nil
+}
+
+func (q fakeQueries) DeleteUserSubs(ctx context.Context, arg db.DeleteUserSubsParams) ([]int32, error) {
+	var ids []int32
+	q.data.subs = slices.DeleteFunc(q.data.subs, func(row db.Subscription) bool {
+		if row.UserID == arg.UserID && row.TenantID == arg.TenantID {
+			ids = append(ids, row.ID)
+			return true
+		}
+		return false
+	})
+	return ids, nil
+}
+
+var (
+	testTenant = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	testUser   = uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+)
+
+// month is the first day of a month of 2025.
+func month(m time.Month) time.Time {
+	return time.Date(2025, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// newTestService is a service on an empty fakeRepo, stopped at now.
+func newTestService(now time.Time) (*SubscriptionService, *fakeRepo) {
+	repo := &fakeRepo{}
+	return &SubscriptionService{Repo: repo, Clock: clock.NewFake(now)}, repo
+}
@@ -0,0 +1,416 @@
+// Package service holds the subscription logic shared by the REST, gRPC and
+// GraphQL APIs and by subsctl: validation, transactions and events.
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"usersubs/internal/clock"
+	"usersubs/internal/db"
+
+	"github.com/google/uuid"
+)
+
+type SubscriptionService struct {
+	Repo Repository
+	// Events receives committed changes, none are sent when nil.
+	Events Publisher
+	// QueryTimeout bounds the database work of a single call.
+	QueryTimeout time.Duration
+	// Clock is the system clock when nil, a time fixed on the context with
+	// clock.WithNow takes precedence.
+	Clock clock.Clock
+}
+
+func (s *SubscriptionService) now(ctx context.Context) time.Time {
+	return clock.Now(ctx, s.Clock)
+}
+
+// run calls fn in a transaction of the tenant and reports a missing row as
+// ErrNotFound.
+func (s *SubscriptionService) run(ctx context.Context, tenantID uuid.UUID, fn func(q Queries) error) error {
+	if s.QueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.QueryTimeout)
+		defer cancel()
+	}
+
+	err := s.Repo.InTx(ctx, tenantID, fn)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *SubscriptionService) emit(ctx context.Context, t EventType, tenantID uuid.UUID, sub Subscription) {
+	if s.Events == nil {
+		return
+	}
+	s.Events.Publish(ctx, Event{Type: t, TenantID: tenantID, Subscription: sub, At: s.now(ctx)})
+}
+
+func (s *SubscriptionService) List(ctx context.Context, tenantID uuid.UUID, filter ListFilter) ([]Subscription, error) {
+	if err := validateMetadataFilter(filter.Metadata); err != nil {
+		return nil, err
+	}
+	now := s.now(ctx)
+	var subs []Subscription
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		rows, err := q.FilterSubs(ctx, filter.params(tenantID, now))
+		if err != nil {
+			return err
+		}
+		subs, err = load(ctx, q, tenantID, rows, now)
+		return err
+	})
+	return subs, err
+}
+
+// ListByUsers returns the subscriptions of all given users with one query,
+// including the ones they are active members of.
+func (s *SubscriptionService) ListByUsers(ctx context.Context, tenantID uuid.UUID, userIDs []uuid.UUID) ([]Subscription, error) {
+	now := s.now(ctx)
+	var subs []Subscription
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		rows, err := q.GetSubsByUsers(ctx, db.GetSubsByUsersParams{UserIds: userIDs, TenantID: tenantID})
+		if err != nil {
+			return err
+		}
+		subs, err = load(ctx, q, tenantID, rows, now)
+		return err
+	})
+	return subs, err
+}
+
+// ListByServices returns the subscriptions of all given services with one
+// query.
+func (s *SubscriptionService) ListByServices(ctx context.Context, tenantID uuid.UUID, names []string) ([]Subscription, error) {
+	now := s.now(ctx)
+	var subs []Subscription
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		rows, err := q.GetSubsByServices(ctx, db.GetSubsByServicesParams{ServiceNames: names, TenantID: tenantID})
+		if err != nil {
+			return err
+		}
+		subs, err = load(ctx, q, tenantID, rows, now)
+		return err
+	})
+	return subs, err
+}
+
+// EndingTrials lists the trials converting to paid within days, the
+// soonest first. userID narrows it to one user when set.
+func (s *SubscriptionService) EndingTrials(ctx context.Context, tenantID, userID uuid.UUID, days int) ([]Subscription, error) {
+	if days < 0 {
+		return nil, &ValidationError{Field: "days", Message: "must not be negative"}
+	}
+
+	now := s.now(ctx)
+	var subs []Subscription
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		rows, err := q.GetEndingTrials(ctx, db.GetEndingTrialsParams{
+			TenantID:     tenantID,
+			Now:          now,
+			Until:        now.AddDate(0, 0, days),
+			CurrentMonth: monthOf(now),
+			UserID:       uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		})
+		if err != nil {
+			return err
+		}
+		subs, err = load(ctx, q, tenantID, rows, now)
+		return err
+	})
+	return subs, err
+}
+
+func (s *SubscriptionService) Get(ctx context.Context, tenantID uuid.UUID, id int32) (Subscription, error) {
+	var sub Subscription
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		var err error
+		sub, err = get(ctx, q, tenantID, id, s.now(ctx))
+		return err
+	})
+	return sub, err
+}
+
+// Create stores sub and returns it as stored, it starts as a trial when
+// TrialEndsAt is set. It fails with a *BudgetError when sub takes its user
+// over an enforced budget, other budgets over are set in OverBudget.
+func (s *SubscriptionService) Create(ctx context.Context, tenantID uuid.UUID, sub Subscription) (Subscription, error) {
+	if err := Validate(sub); err != nil {
+		return sub, err
+	}
+	sub.Status = StatusActive
+	if !sub.TrialEndsAt.IsZero() {
+		sub.Status = StatusTrial
+	}
+
+	now := s.now(ctx)
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		before, err := budgetStatuses(ctx, q, tenantID, sub.UserID, now)
+		if err != nil {
+			return err
+		}
+
+		sub.ID, err = q.AddSub(ctx, db.AddSubParams{
+			ServiceName: sub.ServiceName,
+			Price:       sub.Price,
+			UserID:      sub.UserID,
+			StartedAt:   sub.StartDate,
+			EndedAt:     nullTime(sub.EndDate),
+			TenantID:    tenantID,
+			TrialEndsAt: nullTime(sub.TrialEndsAt),
+			TrialPrice:  sub.TrialPrice,
+			Status:      string(sub.Status),
+			Metadata:    encodeMetadata(sub.Metadata),
+		})
+		if err != nil {
+			return err
+		}
+		sub, err = get(ctx, q, tenantID, sub.ID, now)
+		if err != nil {
+			return err
+		}
+
+		after, err := budgetStatuses(ctx, q, tenantID, sub.UserID, now)
+		if err != nil {
+			return err
+		}
+		sub.OverBudget, err = checkBudgets(before, after)
+		return err
+	})
+	if err != nil {
+		return sub, err
+	}
+
+	s.emit(ctx, EventCreated, tenantID, sub)
+	return sub, nil
+}
+
+// Update replaces every field of the subscription with the ID of sub but the
+// status, which only changes through transitions, and the pauses, which
+// must stay within the new dates. A new price applies from the current
+// month until the next scheduled change, earlier months keep theirs. Budgets
+// are checked like in Create. Metadata is kept when nil.
+func (s *SubscriptionService) Update(ctx context.Context, tenantID uuid.UUID, sub Subscription) (Subscription, error) {
+	if err := Validate(sub); err != nil {
+		return sub, err
+	}
+
+	now := s.now(ctx)
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		var err error
+		sub, err = update(ctx, q, tenantID, sub, now)
+		return err
+	})
+	if err != nil {
+		return sub, err
+	}
+
+	s.emit(ctx, EventUpdated, tenantID, sub)
+	return sub, nil
+}
+
+// update stores sub for Update and Patch and reads it back, sub must be
+// valid.
+func update(ctx context.Context, q Queries, tenantID uuid.UUID, sub Subscription, now time.Time) (Subscription, error) {
+	if _, err := q.GetSubForUpdate(ctx, db.GetSubForUpdateParams{ID: sub.ID, TenantID: tenantID}); err != nil {
+		return sub, err
+	}
+	stored, err := get(ctx, q, tenantID, sub.ID, now)
+	if err != nil {
+		return sub, err
+	}
+
+	for _, m := range stored.Members {
+		if m.Current() && m.UserID == sub.UserID {
+			return sub, &ValidationError{Field: "user_id", Message: "is a member of the subscription"}
+		}
+	}
+	before, err := budgetStatuses(ctx, q, tenantID, sub.UserID, now)
+	if err != nil {
+		return sub, err
+	}
+	if sub.Metadata == nil {
+		sub.Metadata = stored.Metadata
+	}
+
+	base := stored.Prices[0].Price
+	if sub.Price != stored.Price {
+		from := latest(monthOf(now), sub.StartDate)
+		if from.Equal(sub.StartDate) && len(stored.Prices) == 1 {
+			// Nothing was charged at the old price yet.
+			base = sub.Price
+		} else {
+			_, err = q.SetPrice(ctx, db.SetPriceParams{
+				SubscriptionID: sub.ID,
+				TenantID:       tenantID,
+				EffectiveFrom:  from,
+				Price:          sub.Price,
+				Currency:       stored.Prices[len(stored.Prices)-1].Currency,
+			})
+			if err != nil {
+				return sub, err
+			}
+		}
+	}
+
+	_, err = q.UpdateSub(ctx, db.UpdateSubParams{
+		ID:          sub.ID,
+		ServiceName: sub.ServiceName,
+		Price:       base,
+		UserID:      sub.UserID,
+		StartedAt:   sub.StartDate,
+		EndedAt:     nullTime(sub.EndDate),
+		UpdatedAt:   now,
+		TenantID:    tenantID,
+		TrialEndsAt: nullTime(sub.TrialEndsAt),
+		TrialPrice:  sub.TrialPrice,
+		Metadata:    encodeMetadata(sub.Metadata),
+	})
+	if err != nil {
+		return sub, err
+	}
+	stored, err = get(ctx, q, tenantID, sub.ID, now)
+	if err != nil {
+		return sub, err
+	}
+	if err := validatePauses(stored, stored.Pauses); err != nil {
+		return sub, err
+	}
+
+	after, err := budgetStatuses(ctx, q, tenantID, sub.UserID, now)
+	if err != nil {
+		return sub, err
+	}
+	stored.OverBudget, err = checkBudgets(before, after)
+	return stored, err
+}
+
+func (s *SubscriptionService) Delete(ctx context.Context, tenantID uuid.UUID, id int32) (int32, error) {
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		var err error
+		id, err = q.DeleteSub(ctx, db.DeleteSubParams{ID: id, TenantID: tenantID})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.emit(ctx, EventDeleted, tenantID, Subscription{ID: id})
+	return id, nil
+}
+
+// DeleteUser deletes every subscription of the user and returns their IDs.
+func (s *SubscriptionService) DeleteUser(ctx context.Context, tenantID, userID uuid.UUID) ([]int32, error) {
+	var ids []int32
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		var err error
+		ids, err = q.DeleteUserSubs(ctx, db.DeleteUserSubsParams{UserID: userID, TenantID: tenantID})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		s.emit(ctx, EventDeleted, tenantID, Subscription{ID: id, UserID: userID})
+	}
+	return ids, nil
+}
+
+// get reads one subscription with its pauses and status at now.
+func get(ctx context.Context, q Queries, tenantID uuid.UUID, id int32, now time.Time) (Subscription, error) {
+	row, err := q.GetSub(ctx, db.GetSubParams{ID: id, TenantID: tenantID})
+	if err != nil {
+		return Subscription{}, err
+	}
+	subs, err := load(ctx, q, tenantID, []db.Subscription{row}, now)
+	if err != nil {
+		return Subscription{}, err
+	}
+	return subs[0], nil
+}
+
+// load converts rows and adds their pauses, prices, discounts, members,
+// categories and tags with a query each, Price and Charge are set for the month of now.
+func load(ctx context.Context, q Queries, tenantID uuid.UUID, rows []db.Subscription, now time.Time) ([]Subscription, error) {
+	subs := fromRows(rows, now)
+	if len(subs) == 0 {
+		return subs, nil
+	}
+
+	ids := make([]int32, 0, len(subs))
+	byID := make(map[int32]*Subscription, len(subs))
+	for i := range subs {
+		ids = append(ids, subs[i].ID)
+		byID[subs[i].ID] = &subs[i]
+	}
+
+	pauses, err := q.GetPausesBySubs(ctx, db.GetPausesBySubsParams{SubscriptionIds: ids, TenantID: tenantID})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pauses {
+		sub := byID[p.SubscriptionID]
+		sub.Pauses = append(sub.Pauses, fromPauseRow(p))
+	}
+
+	prices, err := q.GetPricesBySubs(ctx, db.GetPricesBySubsParams{SubscriptionIds: ids, TenantID: tenantID})
+	if err != nil {
+		return nil, err
+	}
+	changes := map[int32][]Price{}
+	for _, p := range prices {
+		changes[p.SubscriptionID] = append(changes[p.SubscriptionID], fromPriceRow(p))
+	}
+
+	discounts, err := q.GetDiscountsBySubs(ctx, db.GetDiscountsBySubsParams{SubscriptionIds: ids, TenantID: tenantID})
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range discounts {
+		sub := byID[d.SubscriptionID]
+		sub.Discounts = append(sub.Discounts, fromDiscountRow(d))
+	}
+
+	members, err := q.GetMembersBySubs(ctx, db.GetMembersBySubsParams{SubscriptionIds: ids, TenantID: tenantID})
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		sub := byID[m.SubscriptionID]
+		sub.Members = append(sub.Members, fromMemberRow(m))
+	}
+
+	categories, err := q.GetCategoriesBySubs(ctx, db.GetCategoriesBySubsParams{SubscriptionIds: ids, TenantID: tenantID})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range categories {
+		sub := byID[c.SubscriptionID]
+		sub.Categories = append(sub.Categories, c.Name)
+	}
+
+	tags, err := q.GetTagsBySubs(ctx, db.GetTagsBySubsParams{SubscriptionIds: ids, TenantID: tenantID})
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tags {
+		sub := byID[t.SubscriptionID]
+		sub.Tags = append(sub.Tags, t.Name)
+	}
+
+	for i := range subs {
+		subs[i].Prices = timeline(subs[i], subs[i].Price, changes[subs[i].ID])
+		subs[i].Price = subs[i].PriceAt(monthOf(now))
+		subs[i].Charge = subs[i].ChargeAt(monthOf(now))
+		for j, m := range subs[i].Members {
+			subs[i].Members[j].Share = subs[i].ShareAt(m.UserID, monthOf(now))
+		}
+	}
+	return subs, nil
+}
@@ -0,0 +1,410 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+	"usersubs/internal/clock"
+	"usersubs/internal/db"
+
+	"github.com/google/uuid"
+)
+
+func validSub() Subscription {
+	return Subscription{ServiceName: "Netflix", Price: 100, UserID: testUser, StartDate: month(time.January)}
+}
+
+func mustCreate(t *testing.T, svc *SubscriptionService, sub Subscription) Subscription {
+	t.Helper()
+	created, err := svc.Create(context.Background(), testTenant, sub)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return created
+}
+
+func fieldOf(err error) string {
+	var v *ValidationError
+	if errors.As(err, &v) {
+		return v.Field
+	}
+	return ""
+}
+
+// recorder keeps the published events.
+type recorder struct {
+	events []Event
+}
+
+func (r *recorder) Publish(ctx context.Context, e Event) {
+	r.events = append(r.events, e)
+}
+
+func (r *recorder) types() []EventType {
+	var types []EventType
+	for _, e := range r.events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestCreateValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		change    func(sub *Subscription)
+		wantField string
+	}{
+		{name: "valid", change: func(sub *Subscription) {}},
+		{name: "no service", change: func(sub *Subscription) { sub.ServiceName = "" }, wantField: "service_name"},
+		{name: "negative price", change: func(sub *Subscription) { sub.Price = -1 }, wantField: "price"},
+		{name: "no user", change: func(sub *Subscription) { sub.UserID = uuid.Nil }, wantField: "user_id"},
+		{name: "no start", change: func(sub *Subscription) { sub.StartDate = time.Time{} }, wantField: "start_date"},
+		{name: "end before start", change: func(sub *Subscription) { sub.EndDate = month(time.January).AddDate(0, -1, 0) }, wantField: "end_date"},
+		{name: "negative trial price", change: func(sub *Subscription) { sub.TrialPrice = -1 }, wantField: "trial_price"},
+		{name: "trial before start", change: func(sub *Subscription) { sub.TrialEndsAt = month(time.January).AddDate(0, 0, -1) }, wantField: "trial_ends_at"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo := newTestService(month(time.March))
+			events := &recorder{}
+			svc.Events = events
+			sub := validSub()
+			tt.change(&sub)
+
+			created, err := svc.Create(context.Background(), testTenant, sub)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+				if created.ID == 0 || created.Status != StatusActive {
+					t.Errorf("Create() = %+v, want an active subscription with an ID", created)
+				}
+				if !slices.Equal(events.types(), []EventType{EventCreated}) {
+					t.Errorf("events = %v, want %s", events.types(), EventCreated)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrInvalid) || fieldOf(err) != tt.wantField {
+				t.Fatalf("Create() error = %v, want a ValidationError on %s", err, tt.wantField)
+			}
+			if len(repo.data.subs) != 0 || len(events.events) != 0 {
+				t.Errorf("invalid subscription was stored or announced")
+			}
+		})
+	}
+}
+
+func TestCreateTrial(t *testing.T) {
+	svc, _ := newTestService(month(time.January).AddDate(0, 0, 5))
+	sub := validSub()
+	sub.TrialEndsAt = month(time.February).AddDate(0, 0, 10)
+
+	created := mustCreate(t, svc, sub)
+	if created.Status != StatusTrial {
+		t.Errorf("status = %s, want %s", created.Status, StatusTrial)
+	}
+
+	svc.Clock.(*clock.Fake).Set(sub.TrialEndsAt)
+	if got, _ := svc.Get(context.Background(), testTenant, created.ID); got.Status != StatusActive {
+		t.Errorf("status after the trial = %s, want %s", got.Status, StatusActive)
+	}
+}
+
+func TestEndingTrialsDays(t *testing.T) {
+	svc, _ := newTestService(month(time.March))
+	if _, err := svc.EndingTrials(context.Background(), testTenant, testUser, -1); fieldOf(err) != "days" {
+		t.Errorf("EndingTrials(-1) error = %v, want a ValidationError on days", err)
+	}
+}
+
+func TestUpdateValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(repo *fakeRepo, id int32)
+		change  func(sub *Subscription)
+		wantErr error
+		// wantField is the field of a *ValidationError.
+		wantField string
+	}{
+		{name: "valid", change: func(sub *Subscription) { sub.ServiceName = "Spotify" }},
+		{name: "invalid", change: func(sub *Subscription) { sub.ServiceName = "" }, wantErr: ErrInvalid, wantField: "service_name"},
+		{name: "missing", change: func(sub *Subscription) { sub.ID = 999 }, wantErr: ErrNotFound},
+		{
+			name: "pause after the new end",
+			setup: func(repo *fakeRepo, id int32) {
+				repo.data.pauses = append(repo.data.pauses, db.SubscriptionPause{
+					ID: 100, SubscriptionID: id, TenantID: testTenant, StartedAt: month(time.June), EndedAt: nullTime(month(time.July)),
+				})
+			},
+			change:  func(sub *Subscription) { sub.EndDate = month(time.June) },
+			wantErr: ErrInvalid, wantField: "pauses",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo := newTestService(month(time.March))
+			stored := mustCreate(t, svc, validSub())
+			if tt.setup != nil {
+				tt.setup(repo, stored.ID)
+			}
+
+			sub := stored
+			tt.change(&sub)
+			updated, err := svc.Update(context.Background(), testTenant, sub)
+			if !errors.Is(err, tt.wantErr) || fieldOf(err) != tt.wantField {
+				t.Fatalf("Update() error = %v, want %v on %q", err, tt.wantErr, tt.wantField)
+			}
+			if tt.wantErr != nil {
+				if got, _ := svc.Get(context.Background(), testTenant, stored.ID); got.ServiceName != stored.ServiceName || !got.EndDate.Equal(stored.EndDate) {
+					t.Errorf("failed Update() changed the subscription to %+v", got)
+				}
+				return
+			}
+			if updated.ServiceName != sub.ServiceName {
+				t.Errorf("Update() = %+v, want %+v", updated, sub)
+			}
+		})
+	}
+}
+
+// A subscription of another tenant is reported as missing.
+func TestOtherTenant(t *testing.T) {
+	ctx := context.Background()
+	other := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	svc, _ := newTestService(month(time.March))
+	stored := mustCreate(t, svc, validSub())
+
+	if _, err := svc.Get(ctx, other, stored.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+	if _, err := svc.Update(ctx, other, stored); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update() error = %v, want ErrNotFound", err)
+	}
+	if _, err := svc.Delete(ctx, other, stored.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() error = %v, want ErrNotFound", err)
+	}
+	if subs, err := svc.List(ctx, other, ListFilter{}); err != nil || len(subs) != 0 {
+		t.Errorf("List() = %v, %v, want none", subs, err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	other := uuid.MustParse("00000000-0000-0000-0000-0000000000aa")
+	svc, repo := newTestService(month(time.March))
+	events := &recorder{}
+	svc.Events = events
+
+	first := mustCreate(t, svc, validSub())
+	mustCreate(t, svc, validSub())
+	sub := validSub()
+	sub.UserID = other
+	kept := mustCreate(t, svc, sub)
+	events.events = nil
+
+	if id, err := svc.Delete(ctx, testTenant, first.ID); err != nil || id != first.ID {
+		t.Fatalf("Delete() = %d, %v", id, err)
+	}
+	ids, err := svc.DeleteUser(ctx, testTenant, testUser)
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("DeleteUser() = %v, %v, want the second subscription", ids, err)
+	}
+	if len(repo.data.subs) != 1 || repo.data.subs[0].ID != kept.ID {
+		t.Errorf("left %v, want only the subscription of the other user", repo.data.subs)
+	}
+	if want := []EventType{EventDeleted, EventDeleted}; !slices.Equal(events.types(), want) {
+		t.Errorf("events = %v, want %v", events.types(), want)
+	}
+}
+
+func TestListByUsers(t *testing.T) {
+	other := uuid.MustParse("00000000-0000-0000-0000-0000000000aa")
+	svc, _ := newTestService(month(time.March))
+	mustCreate(t, svc, validSub())
+	sub := validSub()
+	sub.UserID = other
+	mustCreate(t, svc, sub)
+
+	subs, err := svc.ListByUsers(context.Background(), testTenant, []uuid.UUID{other})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 1 || subs[0].UserID != other {
+		t.Errorf("ListByUsers() = %v, want the subscription of %s", subs, other)
+	}
+}
+
+// Paging by key neither skips nor repeats rows when one is deleted between
+// pages.
+func TestListAfterID(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(month(time.March))
+	var ids []int32
+	for range 5 {
+		ids = append(ids, mustCreate(t, svc, validSub()).ID)
+	}
+
+	var got []int32
+	filter := ListFilter{Limit: 2}
+	for {
+		page, err := svc.List(ctx, testTenant, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, sub := range page {
+			got = append(got, sub.ID)
+		}
+		if len(page) < int(filter.Limit) {
+			break
+		}
+		if len(got) == 2 {
+			if _, err := svc.Delete(ctx, testTenant, ids[0]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		filter.AfterID = page[len(page)-1].ID
+	}
+	if !slices.Equal(got, ids) {
+		t.Errorf("pages = %v, want %v", got, ids)
+	}
+}
+
+// deadlineRepo reports whether the transaction got a deadline.
+type deadlineRepo struct {
+	hasDeadline bool
+}
+
+func (r *deadlineRepo) InTx(ctx context.Context, tenantID uuid.UUID, fn func(Queries) error) error {
+	_, r.hasDeadline = ctx.Deadline()
+	return nil
+}
+
+func TestQueryTimeout(t *testing.T) {
+	for _, timeout := range []time.Duration{0, time.Minute} {
+		repo := &deadlineRepo{}
+		svc := &SubscriptionService{Repo: repo, QueryTimeout: timeout}
+		if _, err := svc.Get(context.Background(), testTenant, 1); err != nil {
+			t.Fatal(err)
+		}
+		if repo.hasDeadline != (timeout > 0) {
+			t.Errorf("timeout %v: deadline set = %v", timeout, repo.hasDeadline)
+		}
+	}
+}
+
+// Events carry the time of the service clock, or the one fixed on the
+// context.
+func TestEventTime(t *testing.T) {
+	now := month(time.March).AddDate(0, 0, 10)
+	svc, _ := newTestService(now)
+	events := &recorder{}
+	svc.Events = events
+
+	mustCreate(t, svc, validSub())
+	fixed := month(time.December)
+	if _, err := svc.Create(clock.WithNow(context.Background(), fixed), testTenant, validSub()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events.events) != 2 || !events.events[0].At.Equal(now) || !events.events[1].At.Equal(fixed) {
+		t.Errorf("events = %+v, want them at %v and %v", events.events, now, fixed)
+	}
+}
+
+func TestPatchApply(t *testing.T) {
+	name := "Spotify"
+	price := int32(250)
+	end := month(time.December)
+
+	tests := []struct {
+		name         string
+		metadata     map[string]any
+		patch        Patch
+		wantName     string
+		wantPrice    int32
+		wantEnd      time.Time
+		wantMetadata map[string]any
+	}{
+		{
+			name:     "empty patch keeps everything",
+			metadata: map[string]any{"a": "x"},
+			wantName: "Netflix", wantPrice: 100,
+			wantMetadata: map[string]any{"a": "x"},
+		},
+		{
+			name:     "fields",
+			patch:    Patch{ServiceName: &name, Price: &price, EndDate: &end},
+			wantName: "Spotify", wantPrice: 250, wantEnd: end,
+			wantMetadata: map[string]any{},
+		},
+		{
+			name:     "metadata is merged",
+			metadata: map[string]any{"a": "x", "b": true, "c": 1.0},
+			patch:    Patch{Metadata: map[string]any{"a": "y", "b": nil, "d": 2.0}},
+			wantName: "Netflix", wantPrice: 100,
+			wantMetadata: map[string]any{"a": "y", "c": 1.0, "d": 2.0},
+		},
+		{
+			name:     "removing a missing key",
+			patch:    Patch{Metadata: map[string]any{"a": nil}},
+			wantName: "Netflix", wantPrice: 100,
+			wantMetadata: map[string]any{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := validSub()
+			sub.Metadata = tt.metadata
+			before := maps.Clone(tt.metadata)
+
+			got := tt.patch.apply(sub)
+			if got.ServiceName != tt.wantName || got.Price != tt.wantPrice || !got.EndDate.Equal(tt.wantEnd) {
+				t.Errorf("apply() = %+v", got)
+			}
+			if !maps.Equal(got.Metadata, tt.wantMetadata) {
+				t.Errorf("metadata = %v, want %v", got.Metadata, tt.wantMetadata)
+			}
+			if !maps.Equal(sub.Metadata, before) {
+				t.Errorf("apply() changed the metadata of its argument to %v", sub.Metadata)
+			}
+		})
+	}
+}
+
+func TestPatch(t *testing.T) {
+	svc, _ := newTestService(month(time.March))
+	sub := validSub()
+	sub.Metadata = map[string]any{"external_id": "acc_42", "seats": 5.0}
+	stored := mustCreate(t, svc, sub)
+
+	price := int32(-1)
+	if _, err := svc.Patch(context.Background(), testTenant, stored.ID, Patch{Price: &price}); fieldOf(err) != "price" {
+		t.Errorf("Patch() with a negative price error = %v, want a ValidationError on price", err)
+	}
+
+	name := "Spotify"
+	patched, err := svc.Patch(context.Background(), testTenant, stored.ID, Patch{
+		ServiceName: &name,
+		Metadata:    map[string]any{"seats": nil, "plan": "family"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.ServiceName != "Spotify" || patched.Price != 100 || patched.UserID != testUser || !patched.StartDate.Equal(stored.StartDate) {
+		t.Errorf("Patch() = %+v, want only the service name changed", patched)
+	}
+	if want := map[string]any{"external_id": "acc_42", "plan": "family"}; !maps.Equal(patched.Metadata, want) {
+		t.Errorf("metadata = %v, want %v", patched.Metadata, want)
+	}
+
+	if _, err := svc.Patch(context.Background(), testTenant, 999, Patch{ServiceName: &name}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Patch() of a missing subscription error = %v, want ErrNotFound", err)
+	}
+}
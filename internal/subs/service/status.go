@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+	"usersubs/internal/db"
+
+	"github.com/google/uuid"
+)
+
+type Status string
+
+const (
+	StatusTrial     Status = "trial"
+	StatusActive    Status = "active"
+	StatusPaused    Status = "paused"
+	StatusCancelled Status = "cancelled"
+	StatusExpired   Status = "expired"
+)
+
+var Statuses = []Status{StatusTrial, StatusActive, StatusPaused, StatusCancelled, StatusExpired}
+
+// ParseStatus checks s against the known statuses.
+func ParseStatus(s string) (Status, error) {
+	if !slices.Contains(Statuses, Status(s)) {
+		return "", &ValidationError{Field: "status", Message: fmt.Sprintf("%q is not one of trial, active, paused, cancelled, expired", s)}
+	}
+	return Status(s), nil
+}
+
+// StatusChange is a transition of a subscription, From and To are equal
+// when only the cancellation at period end was set or undone.
+type StatusChange struct {
+	From Status
+	To   Status
+	At   time.Time
+}
+
+// StatusAt is the status of s in the month of now. Statuses are stored as
+// they were last set, a subscription whose last month is over is expired,
+// or cancelled when it was cancelled at period end, and a trial becomes
+// active when it ends.
+func (s Subscription) StatusAt(now time.Time) Status {
+	switch s.Status {
+	case StatusTrial, StatusActive, StatusPaused:
+		if !s.EndDate.IsZero() && s.EndDate.Before(monthOf(now)) {
+			if s.CancelAtPeriodEnd {
+				return StatusCancelled
+			}
+			return StatusExpired
+		}
+	}
+	if s.Status == StatusTrial && !s.TrialEndsAt.IsZero() && !now.Before(s.TrialEndsAt) {
+		return StatusActive
+	}
+	return s.Status
+}
+
+func monthOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Pause stops an active or trial subscription until it is resumed: an
+// open pause starts with the next month, the current one is already paid.
+func (s *SubscriptionService) Pause(ctx context.Context, tenantID uuid.UUID, id int32) (Subscription, error) {
+	return s.transition(ctx, tenantID, id, []Status{StatusTrial, StatusActive}, func(q Queries, sub *Subscription, now time.Time) error {
+		sub.Status = StatusPaused
+		return openPause(ctx, q, tenantID, *sub, now)
+	})
+}
+
+// Resume ends the open pause with the previous month, the current one is
+// charged again.
+func (s *SubscriptionService) Resume(ctx context.Context, tenantID uuid.UUID, id int32) (Subscription, error) {
+	return s.transition(ctx, tenantID, id, []Status{StatusPaused}, func(q Queries, sub *Subscription, now time.Time) error {
+		sub.Status = StatusActive
+		return closePause(ctx, q, tenantID, *sub, monthOf(now).AddDate(0, -1, 0))
+	})
+}
+
+// Cancel ends the subscription with the current month, which is already
+// paid, and the open pause with it. With atPeriodEnd the status is kept
+// until the month is over.
+func (s *SubscriptionService) Cancel(ctx context.Context, tenantID uuid.UUID, id int32, atPeriodEnd bool) (Subscription, error) {
+	return s.transition(ctx, tenantID, id, []Status{StatusTrial, StatusActive, StatusPaused}, func(q Queries, sub *Subscription, now time.Time) error {
+		last := latest(monthOf(now), sub.StartDate)
+		if sub.EndDate.IsZero() || sub.EndDate.After(last) {
+			sub.EndDate = last
+		}
+		if atPeriodEnd {
+			sub.CancelAtPeriodEnd = true
+		} else {
+			sub.Status = StatusCancelled
+		}
+		return closePause(ctx, q, tenantID, *sub, sub.EndDate)
+	})
+}
+
+// Reactivate makes a cancelled or expired subscription active again with
+// no end, the months since it ended are recorded as a pause and not
+// charged. A subscription that expired while paused stays paused up to the
+// month before. Reactivate also undoes a pending cancellation at period
+// end: the subscription keeps its status and loses its end.
+func (s *SubscriptionService) Reactivate(ctx context.Context, tenantID uuid.UUID, id int32) (Subscription, error) {
+	ended := []Status{StatusCancelled, StatusExpired}
+	return s.transition(ctx, tenantID, id, append(ended, StatusTrial, StatusActive, StatusPaused), func(q Queries, sub *Subscription, now time.Time) error {
+		if !slices.Contains(ended, sub.Status) {
+			if !sub.CancelAtPeriodEnd {
+				return &TransitionError{From: sub.Status, Allowed: ended}
+			}
+			sub.EndDate = time.Time{}
+			sub.CancelAtPeriodEnd = false
+			if sub.Status == StatusPaused {
+				return openPause(ctx, q, tenantID, *sub, now)
+			}
+			return nil
+		}
+
+		if err := pauseGap(ctx, q, tenantID, *sub, now); err != nil {
+			return err
+		}
+		sub.Status = StatusActive
+		sub.EndDate = time.Time{}
+		sub.CancelAtPeriodEnd = false
+		return nil
+	})
+}
+
+// transition locks the subscription, checks its current status is one of
+// from, applies the change and records it in the history. apply gets the
+// subscription with its pauses and keeps them in line with the status.
+func (s *SubscriptionService) transition(ctx context.Context, tenantID uuid.UUID, id int32, from []Status, apply func(q Queries, sub *Subscription, now time.Time) error) (Subscription, error) {
+	now := s.now(ctx)
+
+	var sub Subscription
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		row, err := q.GetSubForUpdate(ctx, db.GetSubForUpdateParams{ID: id, TenantID: tenantID})
+		if err != nil {
+			return err
+		}
+		locked, err := load(ctx, q, tenantID, []db.Subscription{row}, now)
+		if err != nil {
+			return err
+		}
+		sub = locked[0]
+		current := sub.Status
+		if !slices.Contains(from, current) {
+			return &TransitionError{From: current, Allowed: from}
+		}
+
+		if err := apply(q, &sub, now); err != nil {
+			return err
+		}
+		row, err = q.SetSubStatus(ctx, db.SetSubStatusParams{
+			Status:            string(sub.Status),
+			ChangedAt:         now,
+			CancelAtPeriodEnd: sub.CancelAtPeriodEnd,
+			EndedAt:           nullTime(sub.EndDate),
+			ID:                id,
+			TenantID:          tenantID,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.AddStatusChange(ctx, db.AddStatusChangeParams{
+			SubscriptionID: id,
+			TenantID:       tenantID,
+			FromStatus:     string(current),
+			ToStatus:       string(sub.Status),
+			ChangedAt:      now,
+		})
+		if err != nil {
+			return err
+		}
+
+		subs, err := load(ctx, q, tenantID, []db.Subscription{row}, now)
+		if err != nil {
+			return err
+		}
+		sub = subs[0]
+		return nil
+	})
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	s.emit(ctx, EventStatusChanged, tenantID, sub)
+	return sub, nil
+}
+
+// StatusHistory lists the transitions of a subscription, oldest first.
+func (s *SubscriptionService) StatusHistory(ctx context.Context, tenantID uuid.UUID, id int32) ([]StatusChange, error) {
+	var rows []db.SubscriptionStatusChange
+	err := s.run(ctx, tenantID, func(q Queries) error {
+		if _, err := q.GetSub(ctx, db.GetSubParams{ID: id, TenantID: tenantID}); err != nil {
+			return err
+		}
+		var err error
+		rows, err = q.GetStatusChanges(ctx, db.GetStatusChangesParams{SubscriptionID: id, TenantID: tenantID})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]StatusChange, 0, len(rows))
+	for _, row := range rows {
+		changes = append(changes, StatusChange{From: Status(row.FromStatus), To: Status(row.ToStatus), At: row.ChangedAt})
+	}
+	return changes, nil
+}
@@ -0,0 +1,393 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+	"usersubs/internal/clock"
+)
+
+func TestStatusAt(t *testing.T) {
+	now := month(time.March).AddDate(0, 0, 10)
+	tests := []struct {
+		name string
+		sub  Subscription
+		want Status
+	}{
+		{name: "open", sub: Subscription{Status: StatusActive}, want: StatusActive},
+		{name: "ends this month", sub: Subscription{Status: StatusActive, EndDate: month(time.March)}, want: StatusActive},
+		{name: "ended", sub: Subscription{Status: StatusActive, EndDate: month(time.February)}, want: StatusExpired},
+		{name: "ended while paused", sub: Subscription{Status: StatusPaused, EndDate: month(time.February)}, want: StatusExpired},
+		{name: "cancelled at period end", sub: Subscription{Status: StatusActive, EndDate: month(time.February), CancelAtPeriodEnd: true}, want: StatusCancelled},
+		{name: "pending cancel", sub: Subscription{Status: StatusActive, EndDate: month(time.March), CancelAtPeriodEnd: true}, want: StatusActive},
+		{name: "cancelled stays", sub: Subscription{Status: StatusCancelled, EndDate: month(time.January)}, want: StatusCancelled},
+		{name: "trial", sub: Subscription{Status: StatusTrial, TrialEndsAt: month(time.March).AddDate(0, 0, 11)}, want: StatusTrial},
+		{name: "trial ends today", sub: Subscription{Status: StatusTrial, TrialEndsAt: month(time.March).AddDate(0, 0, 10)}, want: StatusActive},
+	}
+	for _, tt := range tests {
+		if got := tt.sub.StatusAt(now); got != tt.want {
+			t.Errorf("%s: StatusAt() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	for _, status := range Statuses {
+		if got, err := ParseStatus(string(status)); err != nil || got != status {
+			t.Errorf("ParseStatus(%q) = %q, %v", status, got, err)
+		}
+	}
+	if _, err := ParseStatus("deleted"); !errors.Is(err, ErrInvalid) || fieldOf(err) != "status" {
+		t.Errorf("ParseStatus(deleted) error = %v, want a ValidationError on status", err)
+	}
+}
+
+func TestTransitions(t *testing.T) {
+	ctx := context.Background()
+	pause := func(svc *SubscriptionService, id int32) (Subscription, error) { return svc.Pause(ctx, testTenant, id) }
+	resume := func(svc *SubscriptionService, id int32) (Subscription, error) { return svc.Resume(ctx, testTenant, id) }
+	cancel := func(svc *SubscriptionService, id int32) (Subscription, error) {
+		return svc.Cancel(ctx, testTenant, id, false)
+	}
+	cancelAtEnd := func(svc *SubscriptionService, id int32) (Subscription, error) {
+		return svc.Cancel(ctx, testTenant, id, true)
+	}
+	reactivate := func(svc *SubscriptionService, id int32) (Subscription, error) {
+		return svc.Reactivate(ctx, testTenant, id)
+	}
+
+	type action func(svc *SubscriptionService, id int32) (Subscription, error)
+	tests := []struct {
+		name       string
+		before     []action
+		do         action
+		wantErr    error
+		wantStatus Status
+		wantEnd    time.Time
+		wantPauses []Pause
+	}{
+		{name: "pause", do: pause, wantStatus: StatusPaused, wantPauses: []Pause{{StartDate: month(time.April)}}},
+		{name: "pause paused", before: []action{pause}, do: pause, wantErr: ErrConflict},
+		{name: "resume in the month of the pause", before: []action{pause}, do: resume, wantStatus: StatusActive},
+		{name: "resume active", do: resume, wantErr: ErrConflict},
+		{name: "cancel", do: cancel, wantStatus: StatusCancelled, wantEnd: month(time.March)},
+		{name: "cancel at period end", do: cancelAtEnd, wantStatus: StatusActive, wantEnd: month(time.March)},
+		{name: "cancel paused", before: []action{pause}, do: cancel, wantStatus: StatusCancelled, wantEnd: month(time.March)},
+		{name: "cancel cancelled", before: []action{cancel}, do: cancel, wantErr: ErrConflict},
+		{name: "reactivate", before: []action{cancel}, do: reactivate, wantStatus: StatusActive},
+		{name: "reactivate active", do: reactivate, wantErr: ErrConflict},
+		{name: "reactivate pending cancel", before: []action{cancelAtEnd}, do: reactivate, wantStatus: StatusActive},
+		{
+			name:       "reactivate paused pending cancel",
+			before:     []action{pause, cancelAtEnd},
+			do:         reactivate,
+			wantStatus: StatusPaused,
+			wantPauses: []Pause{{StartDate: month(time.April)}},
+		},
+		{name: "reactivate paused", before: []action{pause}, do: reactivate, wantErr: ErrConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo := newTestService(month(time.March).AddDate(0, 0, 10))
+			stored := mustCreate(t, svc, validSub())
+			for _, before := range tt.before {
+				if _, err := before(svc, stored.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+			changes := len(repo.data.changes)
+
+			got, err := tt.do(svc, stored.ID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				var transitionErr *TransitionError
+				if !errors.As(err, &transitionErr) || errors.Is(err, ErrBudgetExceeded) {
+					t.Errorf("error %T is not a *TransitionError", err)
+				}
+				if len(repo.data.changes) != changes {
+					t.Errorf("refused transition was recorded")
+				}
+				return
+			}
+
+			if got.Status != tt.wantStatus || !got.EndDate.Equal(tt.wantEnd) {
+				t.Errorf("got %s ending %v, want %s ending %v", got.Status, got.EndDate, tt.wantStatus, tt.wantEnd)
+			}
+			if !slices.EqualFunc(got.Pauses, tt.wantPauses, samePause) {
+				t.Errorf("pauses = %v, want %v", got.Pauses, tt.wantPauses)
+			}
+			if len(repo.data.changes) != changes+1 {
+				t.Errorf("recorded %d transitions, want 1", len(repo.data.changes)-changes)
+			}
+		})
+	}
+}
+
+// A subscription cancelled at period end turns cancelled once the month is
+// over, one that ran out turns expired, both can be reactivated.
+func TestStatusOverTime(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(month(time.March).AddDate(0, 0, 10))
+	clk := svc.Clock.(*clock.Fake)
+
+	cancelled := mustCreate(t, svc, validSub())
+	if _, err := svc.Cancel(ctx, testTenant, cancelled.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	sub := validSub()
+	sub.EndDate = month(time.March)
+	expired := mustCreate(t, svc, sub)
+
+	clk.Set(month(time.April))
+	for _, tt := range []struct {
+		id   int32
+		want Status
+	}{{cancelled.ID, StatusCancelled}, {expired.ID, StatusExpired}} {
+		if got, _ := svc.Get(ctx, testTenant, tt.id); got.Status != tt.want {
+			t.Errorf("subscription %d is %s, want %s", tt.id, got.Status, tt.want)
+		}
+		got, err := svc.Reactivate(ctx, testTenant, tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != StatusActive || !got.EndDate.IsZero() || got.CancelAtPeriodEnd {
+			t.Errorf("reactivated %+v, want active without an end", got)
+		}
+	}
+
+	history, err := svc.StatusHistory(ctx, testTenant, cancelled.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []StatusChange{
+		{From: StatusActive, To: StatusActive},
+		{From: StatusCancelled, To: StatusActive},
+	}
+	if !slices.EqualFunc(history, want, func(a, b StatusChange) bool { return a.From == b.From && a.To == b.To }) {
+		t.Errorf("history = %v, want %v", history, want)
+	}
+	if _, err := svc.StatusHistory(ctx, testTenant, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("StatusHistory() of a missing subscription error = %v, want ErrNotFound", err)
+	}
+}
+
+func samePause(a, b Pause) bool {
+	return a.StartDate.Equal(b.StartDate) && a.EndDate.Equal(b.EndDate)
+}
+
+// The pauses follow the status: the months between Pause and Resume, or
+// Cancel, are not charged.
+func TestPauseLifecycle(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(month(time.March).AddDate(0, 0, 10))
+	clk := svc.Clock.(*clock.Fake)
+	stored := mustCreate(t, svc, validSub())
+
+	steps := []struct {
+		at         time.Time
+		do         func(id int32) (Subscription, error)
+		wantStatus Status
+		wantPauses []Pause
+		wantCost   int64
+	}{
+		{
+			at:         month(time.March).AddDate(0, 0, 10),
+			do:         func(id int32) (Subscription, error) { return svc.Pause(ctx, testTenant, id) },
+			wantStatus: StatusPaused,
+			wantPauses: []Pause{{StartDate: month(time.April)}},
+			wantCost:   3 * 100,
+		},
+		{
+			at:         month(time.June).AddDate(0, 0, 5),
+			do:         func(id int32) (Subscription, error) { return svc.Resume(ctx, testTenant, id) },
+			wantStatus: StatusActive,
+			wantPauses: []Pause{{StartDate: month(time.April), EndDate: month(time.May)}},
+			wantCost:   10 * 100,
+		},
+		{
+			at:         month(time.August),
+			do:         func(id int32) (Subscription, error) { return svc.Pause(ctx, testTenant, id) },
+			wantStatus: StatusPaused,
+			wantPauses: []Pause{{StartDate: month(time.April), EndDate: month(time.May)}, {StartDate: month(time.September)}},
+			wantCost:   6 * 100,
+		},
+		{
+			at:         month(time.October).AddDate(0, 0, 20),
+			do:         func(id int32) (Subscription, error) { return svc.Cancel(ctx, testTenant, id, false) },
+			wantStatus: StatusCancelled,
+			wantPauses: []Pause{{StartDate: month(time.April), EndDate: month(time.May)}, {StartDate: month(time.September), EndDate: month(time.October)}},
+			wantCost:   6 * 100,
+		},
+		{
+			at:         month(time.November),
+			do:         func(id int32) (Subscription, error) { return svc.Reactivate(ctx, testTenant, id) },
+			wantStatus: StatusActive,
+			wantPauses: []Pause{{StartDate: month(time.April), EndDate: month(time.May)}, {StartDate: month(time.September), EndDate: month(time.October)}},
+			wantCost:   8 * 100,
+		},
+	}
+
+	for _, step := range steps {
+		clk.Set(step.at)
+		got, err := step.do(stored.ID)
+		if err != nil {
+			t.Fatalf("%v: %v", step.at, err)
+		}
+		if got.Status != step.wantStatus {
+			t.Errorf("%v: status = %s, want %s", step.at, got.Status, step.wantStatus)
+		}
+		if !slices.EqualFunc(got.Pauses, step.wantPauses, samePause) {
+			t.Errorf("%v: pauses = %v, want %v", step.at, got.Pauses, step.wantPauses)
+		}
+		if cost := got.Cost(month(time.January), month(time.December)); cost != step.wantCost {
+			t.Errorf("%v: cost = %d, want %d", step.at, cost, step.wantCost)
+		}
+	}
+
+	history, err := svc.StatusHistory(ctx, testTenant, stored.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []StatusChange{
+		{From: StatusActive, To: StatusPaused},
+		{From: StatusPaused, To: StatusActive},
+		{From: StatusActive, To: StatusPaused},
+		{From: StatusPaused, To: StatusCancelled},
+		{From: StatusCancelled, To: StatusActive},
+	}
+	if !slices.EqualFunc(history, want, func(a, b StatusChange) bool { return a.From == b.From && a.To == b.To }) {
+		t.Errorf("history = %v, want %v", history, want)
+	}
+}
+
+// A subscription that ran out while paused stays paused up to the month
+// before it is reactivated.
+func TestReactivateExpiredPause(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(month(time.March).AddDate(0, 0, 10))
+	sub := validSub()
+	sub.EndDate = month(time.June)
+	stored := mustCreate(t, svc, sub)
+
+	if _, err := svc.Pause(ctx, testTenant, stored.ID); err != nil {
+		t.Fatal(err)
+	}
+	svc.Clock.(*clock.Fake).Set(month(time.August))
+	if got, _ := svc.Get(ctx, testTenant, stored.ID); got.Status != StatusExpired {
+		t.Fatalf("status = %s, want %s", got.Status, StatusExpired)
+	}
+
+	got, err := svc.Reactivate(ctx, testTenant, stored.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Pause{{StartDate: month(time.April), EndDate: month(time.July)}}; !slices.EqualFunc(got.Pauses, want, samePause) {
+		t.Errorf("pauses = %v, want %v", got.Pauses, want)
+	}
+	if cost := got.Cost(month(time.January), month(time.December)); cost != 8*100 {
+		t.Errorf("cost = %d, want %d", cost, 8*100)
+	}
+}
+
+// The months between the end of a subscription and its reactivation cost
+// nothing, the month of the reactivation is charged.
+func TestReactivateGap(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name        string
+		atPeriodEnd bool
+		reactivate  time.Time
+		wantPauses  []Pause
+	}{
+		{name: "cancelled", reactivate: month(time.June).AddDate(0, 0, 3), wantPauses: []Pause{{StartDate: month(time.April), EndDate: month(time.May)}}},
+		{name: "cancelled at period end", atPeriodEnd: true, reactivate: month(time.June).AddDate(0, 0, 3), wantPauses: []Pause{{StartDate: month(time.April), EndDate: month(time.May)}}},
+		{name: "next month", reactivate: month(time.April), wantPauses: nil},
+		{name: "same month", reactivate: month(time.March).AddDate(0, 0, 20), wantPauses: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newTestService(month(time.March).AddDate(0, 0, 10))
+			stored := mustCreate(t, svc, validSub())
+			if _, err := svc.Cancel(ctx, testTenant, stored.ID, tt.atPeriodEnd); err != nil {
+				t.Fatal(err)
+			}
+			svc.Clock.(*clock.Fake).Set(tt.reactivate)
+
+			got, err := svc.Reactivate(ctx, testTenant, stored.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.EqualFunc(got.Pauses, tt.wantPauses, samePause) {
+				t.Errorf("pauses = %v, want %v", got.Pauses, tt.wantPauses)
+			}
+			for m := month(time.April); m.Before(monthOf(tt.reactivate)); m = m.AddDate(0, 1, 0) {
+				if cost := got.Cost(m, m); cost != 0 {
+					t.Errorf("cost of %s = %d, want 0", m.Format("01-2006"), cost)
+				}
+			}
+			if cost := got.Cost(monthOf(tt.reactivate), monthOf(tt.reactivate)); cost != 100 {
+				t.Errorf("cost of the reactivation month = %d, want 100", cost)
+			}
+		})
+	}
+}
+
+func TestAddPauseOverlap(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(month(time.March))
+	stored := mustCreate(t, svc, validSub())
+	if _, err := svc.Pause(ctx, testTenant, stored.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		pause   Pause
+		wantErr error
+	}{
+		{name: "before the open pause", pause: Pause{StartDate: month(time.February), EndDate: month(time.March)}},
+		{name: "after the open pause starts", pause: Pause{StartDate: month(time.June), EndDate: month(time.July)}, wantErr: ErrInvalid},
+		{name: "without an end", pause: Pause{StartDate: month(time.January)}, wantErr: ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.AddPause(ctx, testTenant, stored.ID, tt.pause); !errors.Is(err, tt.wantErr) {
+				t.Errorf("AddPause() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Budgets count the charged months only, a pause frees the rest of the
+// year.
+func TestBudgetSkipsPauses(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newTestService(month(time.March))
+	stored := mustCreate(t, svc, validSub())
+	addBudget(repo, Budget{UserID: testUser, Scope: BudgetOverall, Amount: 1000, Currency: DefaultCurrency, Period: BudgetYear})
+
+	spent := func() int64 {
+		t.Helper()
+		statuses, err := svc.BudgetStatus(ctx, testTenant, testUser)
+		if err != nil || len(statuses) != 1 {
+			t.Fatalf("BudgetStatus() = %v, %v", statuses, err)
+		}
+		return statuses[0].Spent
+	}
+
+	if got := spent(); got != 12*100 {
+		t.Errorf("spent = %d, want %d", got, 12*100)
+	}
+	if _, err := svc.Pause(ctx, testTenant, stored.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := spent(); got != 3*100 {
+		t.Errorf("spent while paused = %d, want %d", got, 3*100)
+	}
+}
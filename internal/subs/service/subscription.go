@@ -0,0 +1,285 @@
+package service
+
+import (
+	"database/sql"
+	"slices"
+	"time"
+	"usersubs/internal/db"
+
+	"github.com/google/uuid"
+)
+
+// Subscription is a monthly subscription of a user to a service. Dates are
+// months, StartDate and EndDate are both included and EndDate is zero while
+// the subscription has no end.
+type Subscription struct {
+	ID          int32
+	ServiceName string
+	Price       int32
+	UserID      uuid.UUID
+	StartDate   time.Time
+	EndDate     time.Time
+	// TrialEndsAt is the day of the first paid charge, months before its
+	// month cost TrialPrice. Zero when there is no trial.
+	TrialEndsAt time.Time
+	TrialPrice  int32
+	// Pauses are the months the subscription is not charged for, ordered
+	// by start. The open pause of a paused subscription may overlap the
+	// pauses planned before it.
+	Pauses []Pause
+	// Prices is the price timeline when read, starting at StartDate. Price
+	// is then the price in effect now and is charged for every month when
+	// Prices is empty.
+	Prices []Price
+	// Discounts are ordered and do not overlap.
+	Discounts []Discount
+	// Members share the charge with the owner, UserID, see ShareAt.
+	Members []Member
+	// Categories and Tags are names in alphabetical order.
+	Categories []string
+	Tags       []string
+	// Metadata is free-form data of integrators, see validateMetadata.
+	Metadata map[string]any
+	// OverBudget are the budgets of the owner that are over after Create or
+	// Update, empty on reads.
+	OverBudget []BudgetStatus
+	// Charge is the amount charged for the current month when read, see
+	// ChargeAt.
+	Charge int32
+	// Status is derived for the current month on reads, see StatusAt.
+	Status            Status
+	StatusChangedAt   time.Time
+	CancelAtPeriodEnd bool
+}
+
+// ActiveMonths counts the months of [from, to] the subscription is active
+// and not paused in.
+func (s Subscription) ActiveMonths(from, to time.Time) int64 {
+	start := s.StartDate
+	if start.Before(from) {
+		start = from
+	}
+	end := to
+	if !s.EndDate.IsZero() && s.EndDate.Before(end) {
+		end = s.EndDate
+	}
+
+	// Pauses are ordered by start and may overlap, next is the first month
+	// not paused by the ones before.
+	months := monthsBetween(start, end)
+	next := start
+	for _, p := range s.Pauses {
+		pauseEnd := end
+		if !p.open() {
+			pauseEnd = earliest(end, p.EndDate)
+		}
+		from := latest(next, p.StartDate)
+		if paused := monthsBetween(from, pauseEnd); paused > 0 {
+			months -= paused
+			next = pauseEnd.AddDate(0, 1, 0)
+		}
+	}
+	return months
+}
+
+// monthsBetween counts the months of [start, end], zero when end is before
+// start.
+func monthsBetween(start, end time.Time) int64 {
+	if end.Before(start) {
+		return 0
+	}
+	return int64(end.Year()-start.Year())*12 + int64(end.Month()-start.Month()) + 1
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// Cost is the charge of every month of [from, to] the subscription is
+// active and not paused in, see ChargeAt.
+func (s Subscription) Cost(from, to time.Time) int64 {
+	return s.cost(from, to, s.ChargeAt)
+}
+
+// ShareCost is the part of Cost paid by userID, see ShareAt.
+func (s Subscription) ShareCost(userID uuid.UUID, from, to time.Time) int64 {
+	return s.cost(from, to, func(month time.Time) int32 {
+		return s.ShareAt(userID, month)
+	})
+}
+
+// cost sums charge over the active months of [from, to], charge must only
+// change in the months of chargeChanges.
+func (s Subscription) cost(from, to time.Time, charge func(month time.Time) int32) int64 {
+	bounds := s.chargeChanges()
+	var cost int64
+	for i, start := range bounds {
+		part := s
+		part.StartDate = start
+		if i+1 < len(bounds) {
+			part.EndDate = bounds[i+1].AddDate(0, -1, 0)
+			if !s.EndDate.IsZero() && s.EndDate.Before(part.EndDate) {
+				part.EndDate = s.EndDate
+			}
+		}
+		cost += part.ActiveMonths(from, to) * int64(charge(start))
+	}
+	return cost
+}
+
+// chargeChanges are the months ChargeAt and ShareAt may change in, in order
+// and starting with StartDate.
+func (s Subscription) chargeChanges() []time.Time {
+	months := []time.Time{s.StartDate}
+	if !s.TrialEndsAt.IsZero() {
+		months = append(months, monthOf(s.TrialEndsAt))
+	}
+	for _, p := range s.Prices {
+		months = append(months, p.EffectiveFrom)
+	}
+	for _, d := range s.Discounts {
+		months = append(months, d.StartDate, d.EndsAt)
+	}
+	for _, m := range s.Members {
+		if !m.JoinedAt.IsZero() {
+			months = append(months, monthOf(m.JoinedAt))
+		}
+		if !m.Current() {
+			months = append(months, monthOf(m.RemovedAt))
+		}
+	}
+
+	slices.SortFunc(months, time.Time.Compare)
+	months = slices.CompactFunc(months, time.Time.Equal)
+	i := 0
+	for i < len(months) && !months[i].After(s.StartDate) {
+		i++
+	}
+	return append([]time.Time{s.StartDate}, months[i:]...)
+}
+
+// FirstChargeAt is the day of the first charge at the full price.
+func (s Subscription) FirstChargeAt() time.Time {
+	if s.TrialEndsAt.IsZero() {
+		return s.StartDate
+	}
+	return s.TrialEndsAt
+}
+
+// TotalCost sums the cost of subs over [from, to].
+func TotalCost(subs []Subscription, from, to time.Time) int64 {
+	var total int64
+	for _, s := range subs {
+		total += s.Cost(from, to)
+	}
+	return total
+}
+
+// UserCost sums the share of userID in subs over [from, to], see ShareAt.
+func UserCost(subs []Subscription, userID uuid.UUID, from, to time.Time) int64 {
+	var total int64
+	for _, s := range subs {
+		total += s.ShareCost(userID, from, to)
+	}
+	return total
+}
+
+// ListFilter narrows List, zero fields match everything.
+type ListFilter struct {
+	UserID uuid.UUID
+	// IncludeShared also matches the subscriptions UserID is or was an
+	// active member of, they paid a share of the months in between.
+	IncludeShared bool
+
+	ServiceName string
+	Category    string
+	Tag         string
+	MinPrice    *int32
+	MaxPrice    *int32
+	// Only subscriptions active in some month of [ActiveFrom, ActiveTo].
+	ActiveFrom time.Time
+	ActiveTo   time.Time
+	Status     Status
+	// Metadata matches the subscriptions with these values, see
+	// metadataPath.
+	Metadata map[string]string
+	// Limit is the max number of rows, all when zero.
+	Limit  int32
+	Offset int32
+	// AfterID pages by key: only subscriptions with a greater ID, ignored
+	// when zero. Unlike Offset, rows added or deleted meanwhile do not
+	// shift the following pages.
+	AfterID int32
+}
+
+// params builds the query, now is needed to derive the status.
+func (f ListFilter) params(tenantID uuid.UUID, now time.Time) db.FilterSubsParams {
+	params := db.FilterSubsParams{
+		TenantID:      tenantID,
+		UserID:        uuid.NullUUID{UUID: f.UserID, Valid: f.UserID != uuid.Nil},
+		IncludeShared: f.IncludeShared,
+		ServiceName:   sql.NullString{String: f.ServiceName, Valid: f.ServiceName != ""},
+		Category:      sql.NullString{String: f.Category, Valid: f.Category != ""},
+		Tag:           sql.NullString{String: f.Tag, Valid: f.Tag != ""},
+		ActiveFrom:    nullTime(f.ActiveFrom),
+		ActiveTo:      nullTime(f.ActiveTo),
+		PageLimit:     sql.NullInt32{Int32: f.Limit, Valid: f.Limit > 0},
+		PageOffset:    f.Offset,
+		AfterID:       sql.NullInt32{Int32: f.AfterID, Valid: f.AfterID > 0},
+		Status:        sql.NullString{String: string(f.Status), Valid: f.Status != ""},
+		CurrentMonth:  monthOf(now),
+		Now:           now,
+	}
+	if len(f.Metadata) > 0 {
+		params.Metadata = sql.NullString{String: metadataPath(f.Metadata), Valid: true}
+	}
+	if f.MinPrice != nil {
+		params.MinPrice = sql.NullInt32{Int32: *f.MinPrice, Valid: true}
+	}
+	if f.MaxPrice != nil {
+		params.MaxPrice = sql.NullInt32{Int32: *f.MaxPrice, Valid: true}
+	}
+	return params
+}
+
+func fromRow(row db.Subscription) Subscription {
+	return Subscription{
+		ID:          row.ID,
+		ServiceName: row.ServiceName,
+		Price:       row.Price,
+		UserID:      row.UserID,
+		StartDate:   row.StartedAt,
+		EndDate:     row.EndedAt.Time,
+		TrialEndsAt: row.TrialEndsAt.Time,
+		TrialPrice:  row.TrialPrice,
+		Metadata:    decodeMetadata(row.Metadata),
+
+		Status:            Status(row.Status),
+		StatusChangedAt:   row.StatusChangedAt,
+		CancelAtPeriodEnd: row.CancelAtPeriodEnd,
+	}
+}
+
+func fromRows(rows []db.Subscription, now time.Time) []Subscription {
+	subs := make([]Subscription, 0, len(rows))
+	for _, row := range rows {
+		sub := fromRow(row)
+		sub.Status = sub.StatusAt(now)
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
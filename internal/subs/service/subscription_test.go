@@ -0,0 +1,78 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCost(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end time.Time
+		wantMonths int64
+	}{
+		{name: "open", start: month(time.January), wantMonths: 6},
+		{name: "starts inside", start: month(time.April), wantMonths: 3},
+		{name: "ends inside", start: month(time.January), end: month(time.February), wantMonths: 2},
+		{name: "inside", start: month(time.February), end: month(time.March), wantMonths: 2},
+		{name: "before", start: month(time.January).AddDate(-1, 0, 0), end: month(time.December).AddDate(-1, 0, 0), wantMonths: 0},
+		{name: "after", start: month(time.July), wantMonths: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := Subscription{Price: 100, StartDate: tt.start, EndDate: tt.end}
+			if got := sub.ActiveMonths(month(time.January), month(time.June)); got != tt.wantMonths {
+				t.Errorf("ActiveMonths() = %d, want %d", got, tt.wantMonths)
+			}
+			if got := sub.Cost(month(time.January), month(time.June)); got != tt.wantMonths*100 {
+				t.Errorf("Cost() = %d, want %d", got, tt.wantMonths*100)
+			}
+		})
+	}
+}
+
+func TestTotalCost(t *testing.T) {
+	subs := []Subscription{
+		{Price: 100, StartDate: month(time.January)},
+		{Price: 10, StartDate: month(time.March), EndDate: month(time.April)},
+	}
+	if got := TotalCost(subs, month(time.January), month(time.June)); got != 6*100+2*10 {
+		t.Errorf("TotalCost() = %d, want %d", got, 6*100+2*10)
+	}
+	if got := TotalCost(nil, month(time.January), month(time.June)); got != 0 {
+		t.Errorf("TotalCost(nil) = %d, want 0", got)
+	}
+}
+
+// Months before the month of TrialEndsAt cost the trial price.
+func TestTrialCost(t *testing.T) {
+	tests := []struct {
+		name       string
+		trialEnds  time.Time
+		end        time.Time
+		from, to   time.Time
+		want       int64
+		wantCharge time.Time
+	}{
+		{name: "no trial", from: month(time.January), to: month(time.June), want: 6 * 100, wantCharge: month(time.January)},
+		{name: "two trial months", trialEnds: month(time.March).AddDate(0, 0, 14), from: month(time.January), to: month(time.June), want: 2*10 + 4*100, wantCharge: month(time.March).AddDate(0, 0, 14)},
+		{name: "ends on the first", trialEnds: month(time.March), from: month(time.January), to: month(time.June), want: 2*10 + 4*100, wantCharge: month(time.March)},
+		{name: "ends in the first month", trialEnds: month(time.January).AddDate(0, 0, 20), from: month(time.January), to: month(time.June), want: 6 * 100, wantCharge: month(time.January).AddDate(0, 0, 20)},
+		{name: "range inside the trial", trialEnds: month(time.May), from: month(time.February), to: month(time.March), want: 2 * 10, wantCharge: month(time.May)},
+		{name: "range after the trial", trialEnds: month(time.March), from: month(time.April), to: month(time.May), want: 2 * 100, wantCharge: month(time.March)},
+		{name: "ends during the trial", trialEnds: month(time.May), end: month(time.February), from: month(time.January), to: month(time.June), want: 2 * 10, wantCharge: month(time.May)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := Subscription{Price: 100, TrialPrice: 10, StartDate: month(time.January), EndDate: tt.end, TrialEndsAt: tt.trialEnds}
+			if got := sub.Cost(tt.from, tt.to); got != tt.want {
+				t.Errorf("Cost() = %d, want %d", got, tt.want)
+			}
+			if got := sub.FirstChargeAt(); !got.Equal(tt.wantCharge) {
+				t.Errorf("FirstChargeAt() = %v, want %v", got, tt.wantCharge)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package subs
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+	"usersubs/internal/subs/service"
+	"usersubs/internal/tenant"
+	"usersubs/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+type priceChangeJSON struct {
+	Price         int32          `json:"price"`
+	EffectiveFrom utils.JSONDate `json:"effective_from"`
+	Currency      string         `json:"currency,omitempty"`
+	// OldPrice only changes subscriptions paying it before the change.
+	OldPrice *int32 `json:"old_price,omitempty"`
+	DryRun   bool   `json:"dry_run,omitempty"`
+}
+
+type changedPriceJSON struct {
+	ID       int32     `json:"id"`
+	UserID   uuid.UUID `json:"user_id"`
+	OldPrice int32     `json:"old_price"`
+	NewPrice int32     `json:"new_price"`
+}
+
+type priceChangeResultJSON struct {
+	DryRun       bool               `json:"dry_run"`
+	Changed      []changedPriceJSON `json:"changed"`
+	MonthlyDelta int64              `json:"monthly_delta"`
+}
+
+// @Summary PostServicePriceChange
+// @Description Change the price of every subscription of a service active in the effective month, in one transaction. With dry_run only the affected subscriptions and the change of monthly spend are returned.
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param name path string true "Service name"
+// @Param request body priceChangeJSON true "New price and optional filter"
+// @Router /api/services/{name}/price-change [POST]
+func (h SubsHandler) PostServicePriceChange(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+
+	var change priceChangeJSON
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+		utils.SendError(w, "Error: something went wrong on decoding json", http.StatusBadRequest, err)
+		return
+	}
+
+	res, err := h.Service.ChangeServicePrice(r.Context(), tenantID, service.PriceChange{
+		ServiceName:   r.PathValue("name"),
+		Price:         change.Price,
+		EffectiveFrom: time.Time(change.EffectiveFrom),
+		Currency:      change.Currency,
+		OldPrice:      change.OldPrice,
+		DryRun:        change.DryRun,
+	})
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	result := priceChangeResultJSON{DryRun: change.DryRun, Changed: []changedPriceJSON{}, MonthlyDelta: res.MonthlyDelta}
+	for _, c := range res.Changed {
+		result.Changed = append(result.Changed, changedPriceJSON{
+			ID:       c.Subscription.ID,
+			UserID:   c.Subscription.UserID,
+			OldPrice: c.OldPrice,
+			NewPrice: c.NewPrice,
+		})
+	}
+
+	if err := utils.SendData(w, result, http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
@@ -0,0 +1,120 @@
+package subs
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+	"usersubs/internal/subs/service"
+	"usersubs/internal/tenant"
+	"usersubs/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+type statusChangeJSON struct {
+	From service.Status `json:"from"`
+	To   service.Status `json:"to"`
+	At   time.Time      `json:"at"`
+}
+
+// @Summary PauseSub
+// @Description Pause an active or trial subscription from the next month until it is resumed
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Router /api/sub/{id}/pause [POST]
+func (h SubsHandler) PauseSub(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.Service.Pause)
+}
+
+// @Summary ResumeSub
+// @Description Resume a paused subscription, the current month is charged again
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Router /api/sub/{id}/resume [POST]
+func (h SubsHandler) ResumeSub(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.Service.Resume)
+}
+
+// @Summary CancelSub
+// @Description Cancel a subscription, the current month stays paid
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Param at_period_end query bool false "Keep the status until the end of the current month"
+// @Router /api/sub/{id}/cancel [POST]
+func (h SubsHandler) CancelSub(w http.ResponseWriter, r *http.Request) {
+	atPeriodEnd := false
+	if s := r.URL.Query().Get("at_period_end"); s != "" {
+		var err error
+		atPeriodEnd, err = strconv.ParseBool(s)
+		if err != nil {
+			utils.SendError(w, "Error: could not parse url query", http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	h.changeStatus(w, r, func(ctx context.Context, tenantID uuid.UUID, id int32) (service.Subscription, error) {
+		return h.Service.Cancel(ctx, tenantID, id, atPeriodEnd)
+	})
+}
+
+// @Summary ReactivateSub
+// @Description Reactivate a cancelled or expired subscription, the months since it ended are not charged. Also undoes a pending cancel at period end
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Router /api/sub/{id}/reactivate [POST]
+func (h SubsHandler) ReactivateSub(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.Service.Reactivate)
+}
+
+// @Summary GetSubHistory
+// @Description Get the status changes of a subscription, oldest first
+// @Produce json
+// @Param X-Tenant-ID header string true "Organization ID"
+// @Param id path int true "ID of subscription"
+// @Router /api/sub/{id}/history [GET]
+func (h SubsHandler) GetSubHistory(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	subID, err := parseID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	changes, err := h.Service.StatusHistory(r.Context(), tenantID, subID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	history := []statusChangeJSON{}
+	for _, c := range changes {
+		history = append(history, statusChangeJSON{From: c.From, To: c.To, At: c.At})
+	}
+
+	if err := utils.SendData(w, history, http.StatusOK); err != nil {
+		utils.SendError(w, "Error: something went wrong on encoding json", http.StatusInternalServerError, err)
+		return
+	}
+}
+
+func (h SubsHandler) changeStatus(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, tenantID uuid.UUID, id int32) (service.Subscription, error)) {
+	tenantID, _ := tenant.FromContext(r.Context())
+	subID, err := parseID(r)
+	if err != nil {
+		utils.SendError(w, "Error: could not parse path value", http.StatusBadRequest, err)
+		return
+	}
+
+	sub, err := change(r.Context(), tenantID, subID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSub(w, r, sub)
+}
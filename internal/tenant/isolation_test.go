@@ -0,0 +1,192 @@
+package tenant_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+	"usersubs/internal/db"
+	"usersubs/internal/migrate"
+	"usersubs/internal/subs/service"
+	"usersubs/internal/tenant"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// openDB connects to DATABASE_URL as the owner of the tables and applies the
+// migrations, the tests are skipped without it.
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("DATABASE_URL")
+	if url == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+
+	sqlDB, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if _, err := migrate.Up(context.Background(), sqlDB); err != nil {
+		t.Fatal(err)
+	}
+	return sqlDB
+}
+
+func newOrganization(t *testing.T, sqlDB *sql.DB) uuid.UUID {
+	t.Helper()
+	id := uuid.New()
+	if _, err := sqlDB.Exec(`INSERT INTO organizations (id, name) VALUES ($1, $2)`, id, "test "+id.String()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := sqlDB.Exec(`DELETE FROM organizations WHERE id = $1`, id); err != nil {
+			t.Logf("could not delete organization %s - %v", id, err)
+		}
+	})
+	return id
+}
+
+func newSub(t *testing.T, svc *service.SubscriptionService, tenantID, userID uuid.UUID) service.Subscription {
+	t.Helper()
+	sub, err := svc.Create(context.Background(), tenantID, service.Subscription{
+		ServiceName: "Yandex Plus",
+		Price:       400,
+		UserID:      userID,
+		StartDate:   time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+func TestServiceIsolation(t *testing.T) {
+	sqlDB := openDB(t)
+	ctx := context.Background()
+	orgA, orgB := newOrganization(t, sqlDB), newOrganization(t, sqlDB)
+	svc := &service.SubscriptionService{Repo: service.PostgresRepository{DB: sqlDB}, QueryTimeout: 5 * time.Second}
+
+	userID := uuid.New()
+	sub := newSub(t, svc, orgA, userID)
+
+	if _, err := svc.Get(ctx, orgB, sub.ID); !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("Get from another tenant error = %v, want ErrNotFound", err)
+	}
+	if subs, err := svc.List(ctx, orgB, service.ListFilter{UserID: userID}); err != nil || len(subs) != 0 {
+		t.Errorf("List from another tenant = %v, %v, want none", subs, err)
+	}
+	if subs, err := svc.ListByUsers(ctx, orgB, []uuid.UUID{userID}); err != nil || len(subs) != 0 {
+		t.Errorf("ListByUsers from another tenant = %v, %v, want none", subs, err)
+	}
+	update := sub
+	update.Price = 1
+	if _, err := svc.Update(ctx, orgB, update); !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("Update from another tenant error = %v, want ErrNotFound", err)
+	}
+	if _, err := svc.Delete(ctx, orgB, sub.ID); !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("Delete from another tenant error = %v, want ErrNotFound", err)
+	}
+	if ids, err := svc.DeleteUser(ctx, orgB, userID); err != nil || len(ids) != 0 {
+		t.Errorf("DeleteUser from another tenant = %v, %v, want none", ids, err)
+	}
+
+	got, err := svc.Get(ctx, orgA, sub.ID)
+	if err != nil {
+		t.Fatalf("Get from the owner error = %v", err)
+	}
+	if got.Price != sub.Price {
+		t.Errorf("price = %d, changed by another tenant from %d", got.Price, sub.Price)
+	}
+}
+
+// TestRowLevelSecurity runs queries without a tenant filter as the app role,
+// only the policies keep the rows of other tenants out.
+func TestRowLevelSecurity(t *testing.T) {
+	sqlDB := openDB(t)
+	ctx := context.Background()
+	orgA, orgB := newOrganization(t, sqlDB), newOrganization(t, sqlDB)
+	svc := &service.SubscriptionService{Repo: service.PostgresRepository{DB: sqlDB}, QueryTimeout: 5 * time.Second}
+	sub := newSub(t, svc, orgA, uuid.New())
+
+	bypass, err := tenant.BypassesRLS(ctx, sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// asTenant runs fn in a transaction as the app role with app.tenant_id
+	// set to id, or not set at all for uuid.Nil.
+	asTenant := func(id uuid.UUID, fn func(tx *sql.Tx)) {
+		t.Helper()
+		tx, err := sqlDB.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+
+		if bypass {
+			if _, err := tx.Exec(`SET LOCAL ROLE usersubs_app`); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if id != uuid.Nil {
+			if err := db.New(tx).SetTenant(ctx, id.String()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		fn(tx)
+	}
+	count := func(tx *sql.Tx) int {
+		t.Helper()
+		var n int
+		if err := tx.QueryRow(`SELECT count(*) FROM subscriptions WHERE id = $1`, sub.ID).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	affected := func(tx *sql.Tx, query string) int64 {
+		t.Helper()
+		res, err := tx.Exec(query, sub.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, _ := res.RowsAffected()
+		return n
+	}
+
+	asTenant(orgA, func(tx *sql.Tx) {
+		if n := count(tx); n != 1 {
+			t.Errorf("owner sees %d rows, want 1", n)
+		}
+	})
+	asTenant(uuid.Nil, func(tx *sql.Tx) {
+		if n := count(tx); n != 0 {
+			t.Errorf("no tenant sees %d rows, want 0", n)
+		}
+	})
+	asTenant(orgB, func(tx *sql.Tx) {
+		if n := count(tx); n != 0 {
+			t.Errorf("another tenant sees %d rows, want 0", n)
+		}
+		if n := affected(tx, `UPDATE subscriptions SET price = 1 WHERE id = $1`); n != 0 {
+			t.Errorf("another tenant updated %d rows, want 0", n)
+		}
+		if n := affected(tx, `DELETE FROM subscriptions WHERE id = $1`); n != 0 {
+			t.Errorf("another tenant deleted %d rows, want 0", n)
+		}
+	})
+	asTenant(orgB, func(tx *sql.Tx) {
+		_, err := tx.Exec(`INSERT INTO subscriptions (service_name, price, user_id, started_at, tenant_id) VALUES ('x', 1, $1, now(), $2)`, uuid.New(), orgA)
+		if err == nil {
+			t.Error("another tenant inserted a row of the owner")
+		}
+	})
+
+	if _, err := svc.Get(ctx, orgA, sub.ID); err != nil {
+		t.Errorf("Get from the owner error = %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package tenant
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"usersubs/internal/db"
+	"usersubs/internal/metrics"
+	"usersubs/internal/tracing"
+	"usersubs/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// Header carries the organization ID of the caller.
+const Header = "X-Tenant-ID"
+
+// DefaultID is the organization created by the migration that introduced
+// tenants, it owns all rows made before.
+var DefaultID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+// ErrMissing, ErrInvalid and ErrForbidden are returned by Resolve, a
+// missing organization is reported as sql.ErrNoRows.
+var (
+	ErrMissing   = errors.New("no tenant id")
+	ErrInvalid   = errors.New("invalid tenant id")
+	ErrForbidden = errors.New("api key does not belong to the organization")
+)
+
+type (
+	ctxKey   struct{}
+	boundKey struct{}
+)
+
+type Resolver struct {
+	Repo *db.Queries
+	// Timeout bounds the organization lookup.
+	Timeout time.Duration
+}
+
+// Middleware resolves the tenant of the request and rejects requests
+// without a known organization.
+func (rs Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := rs.Resolve(r.Context(), r.Header.Get(Header))
+		switch {
+		case errors.Is(err, ErrMissing):
+			utils.SendError(w, "Error: header `X-Tenant-ID` is not provided", http.StatusBadRequest, err)
+			return
+		case errors.Is(err, ErrInvalid):
+			utils.SendError(w, "Error: could not parse header `X-Tenant-ID`", http.StatusBadRequest, err)
+			return
+		case errors.Is(err, ErrForbidden):
+			utils.SendError(w, "Error: api key does not belong to the organization", http.StatusForbidden, err)
+			return
+		case errors.Is(err, sql.ErrNoRows):
+			utils.SendError(w, "Error: organization not found", http.StatusNotFound, err)
+			return
+		case err != nil:
+			utils.SendError(w, "Error: something went wrong on sql query", http.StatusInternalServerError, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithID(r.Context(), id)))
+	})
+}
+
+// Resolve picks the tenant from the organization bound to the API key of
+// the request and the tenant header, which must agree when both are given.
+// Without a bound organization the header is required.
+func (rs Resolver) Resolve(ctx context.Context, header string) (uuid.UUID, error) {
+	bound, isBound := Bound(ctx)
+
+	id := bound
+	switch {
+	case header != "":
+		var err error
+		id, err = uuid.Parse(header)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("%w - %v", ErrInvalid, err)
+		}
+		if isBound && id != bound {
+			return uuid.Nil, ErrForbidden
+		}
+	case !isBound:
+		return uuid.Nil, ErrMissing
+	}
+
+	if err := rs.Lookup(ctx, id); err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+// Lookup checks that the organization exists, a missing one is reported as
+// sql.ErrNoRows.
+func (rs Resolver) Lookup(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, rs.Timeout)
+	defer cancel()
+	_, err := rs.Repo.GetOrganization(ctx, id)
+	return err
+}
+
+// Bind restricts the request to the organization of its credentials.
+func Bind(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, boundKey{}, id)
+}
+
+func Bound(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(boundKey{}).(uuid.UUID)
+	return id, ok
+}
+
+func WithID(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+func FromContext(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(ctxKey{}).(uuid.UUID)
+	return id, ok
+}
+
+// RunInTx runs fn in a transaction with `app.tenant_id` set, so the row
+// level security policies only expose rows of the given tenant.
+func RunInTx(ctx context.Context, sqlDB *sql.DB, id uuid.UUID, fn func(*db.Queries) error) error {
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("TENANT TX - could not begin transaction - %w", err)
+	}
+	defer tx.Rollback()
+
+	q := db.New(tracing.WrapDB(metrics.WrapDB(tx)))
+	if err := q.SetTenant(ctx, id.String()); err != nil {
+		return fmt.Errorf("TENANT TX - could not set tenant - %w", err)
+	}
+
+	if err := fn(q); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// BypassesRLS tells if the role of sqlDB skips the row level security
+// policies, as superusers and roles with BYPASSRLS do.
+func BypassesRLS(ctx context.Context, sqlDB *sql.DB) (bool, error) {
+	bypass, err := db.New(sqlDB).BypassesRowSecurity(ctx)
+	if err != nil {
+		return false, fmt.Errorf("TENANT - could not read db role - %w", err)
+	}
+	return bypass.Bool, nil
+}
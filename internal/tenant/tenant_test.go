@@ -0,0 +1,53 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("FromContext() found a tenant on an empty context")
+	}
+
+	id := uuid.New()
+	got, ok := FromContext(WithID(context.Background(), id))
+	if !ok || got != id {
+		t.Errorf("FromContext() = %v, %v, want %v", got, ok, id)
+	}
+}
+
+// Requests without a usable header are rejected before the organization is
+// looked up.
+func TestMiddlewareRejectsHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{name: "missing"},
+		{name: "not a uuid", header: "acme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("request reached the handler")
+			})
+			r := httptest.NewRequest(http.MethodGet, "/api/subs", nil)
+			if tt.header != "" {
+				r.Header.Set(Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			Resolver{}.Middleware(next).ServeHTTP(w, r)
+			if !strings.Contains(w.Body.String(), "X-Tenant-ID") {
+				t.Errorf("body = %q, want an error about the header", w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+var (
+	spanRecorder = tracetest.NewSpanRecorder()
+	recorderOnce sync.Once
+)
+
+// recordSpans installs a provider keeping the ended spans in memory. The
+// package tracer only follows the first provider set, so it is installed
+// once and reset for every caller.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorderOnce.Do(func() {
+		if _, err := Setup(context.Background(), "", ""); err != nil {
+			t.Fatal(err)
+		}
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+	})
+	spanRecorder.Reset()
+	return spanRecorder
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := recordSpans(t)
+
+	var traceID string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/subs", func(w http.ResponseWriter, r *http.Request) {
+		traceID = TraceID(r.Context())
+	})
+	mux.HandleFunc("GET /api/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	h := Middleware(RecordRoute(mux))
+
+	tests := []struct {
+		name        string
+		path        string
+		traceparent string
+		wantStatus  int
+		wantError   bool
+	}{
+		{name: "new trace", path: "/api/subs", wantStatus: http.StatusOK},
+		{name: "continued trace", path: "/api/subs", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", wantStatus: http.StatusOK},
+		{name: "server error", path: "/api/fail", wantStatus: http.StatusInternalServerError, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder.Reset()
+			traceID = ""
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.traceparent != "" {
+				r.Header.Set("traceparent", tt.traceparent)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("ended %d spans, want 1", len(spans))
+			}
+			span := spans[0]
+			if want := "GET " + tt.path; span.Name() != want {
+				t.Errorf("span name = %q, want %q", span.Name(), want)
+			}
+			if tt.traceparent != "" && span.Parent().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("parent trace = %s, want the one of traceparent", span.Parent().TraceID())
+			}
+			if tt.path == "/api/subs" && traceID != span.SpanContext().TraceID().String() {
+				t.Errorf("TraceID() in the handler = %q, want %s", traceID, span.SpanContext().TraceID())
+			}
+
+			status := 0
+			for _, attr := range span.Attributes() {
+				if attr.Key == semconv.HTTPResponseStatusCodeKey {
+					status = int(attr.Value.AsInt64())
+				}
+			}
+			if status != tt.wantStatus {
+				t.Errorf("status attribute = %d, want %d", status, tt.wantStatus)
+			}
+			if got := span.Status().Code == codes.Error; got != tt.wantError {
+				t.Errorf("span error = %v, want %v", got, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestTraceIDUntraced(t *testing.T) {
+	if got := TraceID(context.Background()); got != "" {
+		t.Errorf("TraceID() = %q, want empty", got)
+	}
+}
+
+func TestSetupUnknownExporter(t *testing.T) {
+	if _, err := Setup(context.Background(), "jaeger", ""); err == nil {
+		t.Error("Setup() accepted an unknown exporter")
+	}
+}
+
+type ctxKey struct{}
+
+// withValue stands for the middlewares between Middleware and the mux that
+// pass a copy of the request on.
+func withValue(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, true)))
+	})
+}
+
+func TestMiddlewareNamesSpanAfterRoute(t *testing.T) {
+	recorder := recordSpans(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/sub/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	var logged string
+	logRoute := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(r.Context()))
+			logged = Route(r.Context())
+		})
+	}
+	h := Middleware(logRoute(withValue(RecordRoute(mux))))
+
+	tests := []struct {
+		path      string
+		wantName  string
+		wantRoute string
+	}{
+		{path: "/api/sub/42", wantName: "GET /api/sub/{id}", wantRoute: "GET /api/sub/{id}"},
+		{path: "/missing", wantName: http.MethodGet, wantRoute: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			recorder.Reset()
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("ended %d spans, want 1", len(spans))
+			}
+			if name := spans[0].Name(); name != tt.wantName {
+				t.Errorf("span name = %q, want %q", name, tt.wantName)
+			}
+
+			route := ""
+			for _, attr := range spans[0].Attributes() {
+				if attr.Key == semconv.HTTPRouteKey {
+					route = attr.Value.AsString()
+				}
+			}
+			if route != tt.wantRoute {
+				t.Errorf("http.route = %q, want %q", route, tt.wantRoute)
+			}
+			if logged != tt.wantRoute {
+				t.Errorf("Route() after serving = %q, want %q", logged, tt.wantRoute)
+			}
+		})
+	}
+}
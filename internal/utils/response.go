@@ -1,9 +1,11 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
+	"usersubs/internal/logging"
 )
 
 type JSONData interface { any | []any }
@@ -12,33 +14,49 @@ type DataResponse struct {
 	Data JSONData `json:"data"`
 }
 
+// SendData writes nothing when encoding fails, so the caller can still send
+// an error.
 func SendData(w http.ResponseWriter, data JSONData, status int) error {
 	res := DataResponse{Data: data}
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	body, err := json.Marshal(res)
+	if err != nil {
 		return err
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	log.Printf("Send response - %v\n", res)
+	w.Write(append(body, '\n'))
+	if logging.LogBodies {
+		slog.Debug("Send response", "request_id", w.Header().Get(logging.RequestIDHeader), "body", string(body))
+	}
 	return nil
 }
 
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 func SendError(w http.ResponseWriter, message string, status int, e error) {
-	res := ErrorResponse{Error: message}
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	requestID := w.Header().Get(logging.RequestIDHeader)
+	res := ErrorResponse{Error: message, RequestID: requestID}
+	body, err := json.Marshal(res)
+	if err != nil {
 		msg := "Error: something went wrong with encoding json"
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(msg))
-		log.Printf("%v - %v\n", msg, err)
+		slog.Error(msg, "request_id", requestID, "error", err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	log.Printf("%v - %v\n", message, e)
+	w.Write(append(body, '\n'))
+
+	level := slog.LevelWarn
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	slog.Log(context.Background(), level, message, "request_id", requestID, "status", status, "error", e)
 }
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS organizations (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+INSERT INTO organizations (
+    id,
+    name
+) VALUES (
+    '00000000-0000-0000-0000-000000000001',
+    '[DEFAULT ORGANIZATION]'
+);
+
+ALTER TABLE subscriptions ADD COLUMN tenant_id UUID NOT NULL
+    DEFAULT '00000000-0000-0000-0000-000000000001'
+    REFERENCES organizations (id) ON DELETE CASCADE;
+ALTER TABLE subscriptions ALTER COLUMN tenant_id DROP DEFAULT;
+
+CREATE INDEX subscriptions_tenant_id_idx ON subscriptions (tenant_id);
+
+-- Row level security is a second line of defence behind the tenant filter in
+-- every query. The application sets app.tenant_id for each transaction. Note
+-- that table owners and superusers bypass the policy, so the app should
+-- connect with a separate role.
+ALTER TABLE subscriptions ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY subscriptions_tenant_isolation ON subscriptions
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP POLICY subscriptions_tenant_isolation ON subscriptions;
+ALTER TABLE subscriptions DISABLE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions DROP COLUMN tenant_id;
+DROP TABLE organizations;
+-- +goose StatementEnd
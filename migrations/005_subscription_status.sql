@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE subscriptions
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'active'
+        CHECK (status IN ('trial', 'active', 'paused', 'cancelled', 'expired')),
+    ADD COLUMN status_changed_at TIMESTAMP NOT NULL DEFAULT now(),
+    ADD COLUMN cancel_at_period_end BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX subscriptions_status_idx ON subscriptions (tenant_id, status);
+
+-- Every transition made through the API, the expiry after ended_at is
+-- derived when reading and has no row here.
+CREATE TABLE IF NOT EXISTS subscription_status_changes (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX subscription_status_changes_subscription_id_idx ON subscription_status_changes (subscription_id);
+
+ALTER TABLE subscription_status_changes ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY subscription_status_changes_tenant_isolation ON subscription_status_changes
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE subscription_status_changes;
+DROP INDEX subscriptions_status_idx;
+ALTER TABLE subscriptions
+    DROP COLUMN status,
+    DROP COLUMN status_changed_at,
+    DROP COLUMN cancel_at_period_end;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE subscriptions
+    ADD COLUMN trial_ends_at TIMESTAMP,
+    ADD COLUMN trial_price INT NOT NULL DEFAULT 0 CHECK (trial_price >= 0);
+
+CREATE INDEX subscriptions_trial_ends_at_idx ON subscriptions (tenant_id, trial_ends_at)
+    WHERE trial_ends_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX subscriptions_trial_ends_at_idx;
+ALTER TABLE subscriptions
+    DROP COLUMN trial_ends_at,
+    DROP COLUMN trial_price;
+-- +goose StatementEnd
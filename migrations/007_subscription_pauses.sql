@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Months a subscription is not charged for, both ends included. Overlaps
+-- and the range of the subscription are checked by the service.
+CREATE TABLE IF NOT EXISTS subscription_pauses (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    started_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    CHECK (ended_at >= started_at)
+);
+
+CREATE INDEX subscription_pauses_subscription_id_idx ON subscription_pauses (subscription_id, started_at);
+
+ALTER TABLE subscription_pauses ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY subscription_pauses_tenant_isolation ON subscription_pauses
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE subscription_pauses;
+-- +goose StatementEnd
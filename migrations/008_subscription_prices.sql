@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Price changes of a subscription, subscriptions.price stays the price from
+-- started_at until the first change.
+CREATE TABLE IF NOT EXISTS subscription_prices (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    effective_from TIMESTAMP NOT NULL,
+    price INT NOT NULL CHECK (price >= 0),
+    currency TEXT NOT NULL DEFAULT 'RUB',
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    UNIQUE (subscription_id, effective_from)
+);
+
+ALTER TABLE subscription_prices ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY subscription_prices_tenant_isolation ON subscription_prices
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE subscription_prices;
+-- +goose StatementEnd
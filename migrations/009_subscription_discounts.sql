@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Discounts of a subscription for a number of months or until a date,
+-- ends_at is the first month charged in full again.
+CREATE TABLE IF NOT EXISTS subscription_discounts (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('percent', 'fixed')),
+    amount INT NOT NULL CHECK (amount > 0),
+    started_at TIMESTAMP NOT NULL,
+    cycles INT CHECK (cycles > 0),
+    until TIMESTAMP,
+    ends_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    CHECK ((cycles IS NULL) <> (until IS NULL)),
+    CHECK (ends_at > started_at)
+);
+
+CREATE INDEX subscription_discounts_subscription_id_idx ON subscription_discounts (subscription_id, started_at);
+CREATE INDEX subscription_discounts_ends_at_idx ON subscription_discounts (tenant_id, ends_at);
+
+ALTER TABLE subscription_discounts ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY subscription_discounts_tenant_isolation ON subscription_discounts
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE subscription_discounts;
+-- +goose StatementEnd
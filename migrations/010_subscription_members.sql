@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Users sharing a subscription with its owner. Fixed and percent members
+-- pay their part first, the owner and equal members split the rest.
+-- Invited members pay nothing until they accept.
+CREATE TABLE IF NOT EXISTS subscription_members (
+    id SERIAL PRIMARY KEY,
+    subscription_id INT NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('invited', 'active')),
+    split TEXT NOT NULL CHECK (split IN ('equal', 'percent', 'fixed')),
+    amount INT NOT NULL DEFAULT 0 CHECK (amount >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    UNIQUE (subscription_id, user_id)
+);
+
+CREATE INDEX subscription_members_user_id_idx ON subscription_members (tenant_id, user_id);
+
+ALTER TABLE subscription_members ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY subscription_members_tenant_isolation ON subscription_members
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE subscription_members;
+-- +goose StatementEnd
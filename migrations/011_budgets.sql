@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Spending limits of a user over all their subscriptions or the ones of a
+-- service, in one currency for a month or a calendar year. Enforced budgets
+-- reject changes that take the user over them.
+CREATE TABLE IF NOT EXISTS budgets (
+    id SERIAL PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    scope TEXT NOT NULL CHECK (scope IN ('overall', 'service')),
+    -- The service name, empty for an overall budget.
+    target TEXT NOT NULL DEFAULT '',
+    amount INT NOT NULL CHECK (amount > 0),
+    currency TEXT NOT NULL,
+    period TEXT NOT NULL CHECK (period IN ('month', 'year')),
+    enforce BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    UNIQUE (tenant_id, user_id, scope, target, currency, period)
+);
+
+ALTER TABLE budgets ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY budgets_tenant_isolation ON budgets
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE budgets;
+-- +goose StatementEnd
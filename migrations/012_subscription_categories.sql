@@ -0,0 +1,73 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Categories are managed per organization, tags are free-form and created
+-- on first use. A subscription can have any number of both.
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    UNIQUE (tenant_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS subscription_categories (
+    subscription_id INT NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    category_id INT NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    PRIMARY KEY (subscription_id, category_id)
+);
+
+CREATE INDEX subscription_categories_category_id_idx ON subscription_categories (category_id);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id SERIAL PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    UNIQUE (tenant_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS subscription_tags (
+    subscription_id INT NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    tag_id INT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    PRIMARY KEY (subscription_id, tag_id)
+);
+
+CREATE INDEX subscription_tags_tag_id_idx ON subscription_tags (tag_id);
+
+ALTER TABLE categories ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscription_categories ENABLE ROW LEVEL SECURITY;
+ALTER TABLE tags ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscription_tags ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY categories_tenant_isolation ON categories
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+
+CREATE POLICY subscription_categories_tenant_isolation ON subscription_categories
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+
+CREATE POLICY tags_tenant_isolation ON tags
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+
+CREATE POLICY subscription_tags_tenant_isolation ON subscription_tags
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+
+-- Budgets can now cover the subscriptions of a category.
+ALTER TABLE budgets DROP CONSTRAINT budgets_scope_check;
+ALTER TABLE budgets ADD CONSTRAINT budgets_scope_check CHECK (scope IN ('overall', 'service', 'category'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM budgets WHERE scope = 'category';
+ALTER TABLE budgets DROP CONSTRAINT budgets_scope_check;
+ALTER TABLE budgets ADD CONSTRAINT budgets_scope_check CHECK (scope IN ('overall', 'service'));
+
+DROP TABLE subscription_tags;
+DROP TABLE tags;
+DROP TABLE subscription_categories;
+DROP TABLE categories;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Free-form data of integrators, a flat JSON object. GetSubs filters by
+-- containment, which the jsonb_path_ops index serves.
+ALTER TABLE subscriptions ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX subscriptions_metadata_idx ON subscriptions USING GIN (metadata jsonb_path_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX subscriptions_metadata_idx;
+
+ALTER TABLE subscriptions DROP COLUMN metadata;
+-- +goose StatementEnd
@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Without FORCE the policies skip the owner of the tables, who runs the
+-- migrations. Superusers and roles with BYPASSRLS still skip them, so the
+-- server connects as a member of usersubs_app.
+ALTER TABLE subscriptions FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_status_changes FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_pauses FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_prices FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_discounts FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_members FORCE ROW LEVEL SECURITY;
+ALTER TABLE budgets FORCE ROW LEVEL SECURITY;
+ALTER TABLE categories FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_categories FORCE ROW LEVEL SECURITY;
+ALTER TABLE tags FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_tags FORCE ROW LEVEL SECURITY;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'usersubs_app') THEN
+        CREATE ROLE usersubs_app NOLOGIN;
+    END IF;
+END
+$$;
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO usersubs_app;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO usersubs_app;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO usersubs_app;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT USAGE, SELECT ON SEQUENCES TO usersubs_app;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- The role is shared by the cluster and may have members, it is kept.
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE USAGE, SELECT ON SEQUENCES FROM usersubs_app;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE SELECT, INSERT, UPDATE, DELETE ON TABLES FROM usersubs_app;
+REVOKE USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public FROM usersubs_app;
+REVOKE SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public FROM usersubs_app;
+
+ALTER TABLE subscription_tags NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE tags NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_categories NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE categories NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE budgets NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_members NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_discounts NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_prices NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_pauses NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_status_changes NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions NO FORCE ROW LEVEL SECURITY;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A pause without ended_at lasts until the subscription is resumed, it is
+-- opened by the paused status. Subscriptions paused before get one from the
+-- month after they were paused, that month was already paid. The owner is
+-- not exempt from the policies, so FORCE is lifted for the backfill.
+ALTER TABLE subscription_pauses ALTER COLUMN ended_at DROP NOT NULL;
+
+ALTER TABLE subscriptions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_pauses NO FORCE ROW LEVEL SECURITY;
+
+INSERT INTO subscription_pauses (subscription_id, tenant_id, started_at)
+SELECT id, tenant_id, GREATEST(date_trunc('month', status_changed_at) + interval '1 month', started_at)
+FROM subscriptions
+WHERE status = 'paused'
+    AND (ended_at IS NULL OR ended_at >= date_trunc('month', status_changed_at) + interval '1 month');
+
+ALTER TABLE subscriptions FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_pauses FORCE ROW LEVEL SECURITY;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE subscription_pauses NO FORCE ROW LEVEL SECURITY;
+DELETE FROM subscription_pauses WHERE ended_at IS NULL;
+ALTER TABLE subscription_pauses FORCE ROW LEVEL SECURITY;
+
+ALTER TABLE subscription_pauses ALTER COLUMN ended_at SET NOT NULL;
+-- +goose StatementEnd
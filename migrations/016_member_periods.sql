@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A member pays from the month of joined_at, set when they are added or
+-- accept the invite, up to the month before removed_at. Removed members are
+-- kept so past months keep their split, a user may join again.
+ALTER TABLE subscription_members
+    ADD COLUMN joined_at TIMESTAMP,
+    ADD COLUMN removed_at TIMESTAMP,
+    DROP CONSTRAINT subscription_members_subscription_id_user_id_key;
+
+CREATE UNIQUE INDEX subscription_members_current_idx ON subscription_members (subscription_id, user_id)
+    WHERE removed_at IS NULL;
+
+ALTER TABLE subscription_members NO FORCE ROW LEVEL SECURITY;
+UPDATE subscription_members SET joined_at = created_at WHERE status = 'active';
+ALTER TABLE subscription_members FORCE ROW LEVEL SECURITY;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE subscription_members NO FORCE ROW LEVEL SECURITY;
+DELETE FROM subscription_members WHERE removed_at IS NOT NULL;
+ALTER TABLE subscription_members FORCE ROW LEVEL SECURITY;
+
+DROP INDEX subscription_members_current_idx;
+ALTER TABLE subscription_members
+    DROP COLUMN joined_at,
+    DROP COLUMN removed_at,
+    ADD CONSTRAINT subscription_members_subscription_id_user_id_key UNIQUE (subscription_id, user_id);
+-- +goose StatementEnd
@@ -0,0 +1,201 @@
+// Package client is a typed client for the user subscriptions API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultBackoff    = 200 * time.Millisecond
+	maxBackoff        = 10 * time.Second
+)
+
+type Config struct {
+	// BaseURL of the server, e.g. `http://localhost:5500`.
+	BaseURL string
+	// TenantID is sent as `X-Tenant-ID`.
+	TenantID uuid.UUID
+	// APIKey is sent as `X-API-Key` when set.
+	APIKey string
+	// HTTPClient defaults to a client with a 30 second timeout.
+	HTTPClient *http.Client
+	// MaxRetries of idempotent calls on network errors, 429 and 502-504.
+	// Zero means the default of 3, negative disables retries.
+	MaxRetries int
+	// Backoff is the first delay between retries, doubled on every attempt.
+	Backoff time.Duration
+}
+
+type Client struct {
+	baseURL    *url.URL
+	tenantID   uuid.UUID
+	apiKey     string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+}
+
+func New(cfg Config) (*Client, error) {
+	base, err := url.Parse(strings.TrimSuffix(cfg.BaseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("client: bad base url: %w", err)
+	}
+	if base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("client: base url %q needs a scheme and a host", cfg.BaseURL)
+	}
+
+	c := &Client{
+		baseURL:    base,
+		tenantID:   cfg.TenantID,
+		apiKey:     cfg.APIKey,
+		httpClient: cfg.HTTPClient,
+		maxRetries: cfg.MaxRetries,
+		backoff:    cfg.Backoff,
+	}
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if c.maxRetries == 0 {
+		c.maxRetries = defaultMaxRetries
+	}
+	if c.maxRetries < 0 {
+		c.maxRetries = 0
+	}
+	if c.backoff <= 0 {
+		c.backoff = defaultBackoff
+	}
+	return c, nil
+}
+
+// do sends the request and decodes the `data` of the response into out.
+// Idempotent methods are retried.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("client: could not encode request: %w", err)
+		}
+	}
+
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	retries := 0
+	if method != http.MethodPost {
+		retries = c.maxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := c.send(ctx, method, u.String(), payload)
+		if err == nil && !retryableStatus(res.StatusCode) || attempt >= retries {
+			if err != nil {
+				return err
+			}
+			return decode(res, out)
+		}
+
+		delay := c.delay(attempt, res)
+		if res != nil {
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, u string, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.tenantID != uuid.Nil {
+		req.Header.Set("X-Tenant-ID", c.tenantID.String())
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// delay is exponential with full jitter, or Retry-After when the server
+// sent it.
+func (c *Client) delay(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, maxBackoff)
+		}
+	}
+
+	d := min(c.backoff<<attempt, maxBackoff)
+	return time.Duration(rand.Int64N(int64(d) + 1))
+}
+
+func decode(res *http.Response, out any) error {
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		apiErr := &Error{StatusCode: res.StatusCode, RequestID: res.Header.Get("X-Request-ID")}
+		var body struct {
+			Error     string `json:"error"`
+			RequestID string `json:"request_id"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&body); err == nil {
+			apiErr.Message = body.Error
+			if body.RequestID != "" {
+				apiErr.RequestID = body.RequestID
+			}
+		}
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	envelope := struct {
+		Data any `json:"data"`
+	}{Data: out}
+	if err := json.NewDecoder(res.Body).Decode(&envelope); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("client: could not decode response: %w", err)
+	}
+	return nil
+}
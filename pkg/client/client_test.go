@@ -0,0 +1,328 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var tenantID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+// newTestClient points a client at h with retries fast enough for tests.
+func newTestClient(t *testing.T, h http.Handler, cfg Config) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	cfg.BaseURL = srv.URL
+	if cfg.Backoff == 0 {
+		cfg.Backoff = time.Millisecond
+	}
+	c, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func sendData(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"data": data})
+}
+
+func sendError(w http.ResponseWriter, status int, message, requestID string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"error": message, "request_id": requestID})
+}
+
+func TestDecodesData(t *testing.T) {
+	var header http.Header
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		if r.URL.Path != "/api/sub/7" {
+			t.Errorf("path = %s, want /api/sub/7", r.URL.Path)
+		}
+		sendData(w, map[string]any{"id": 7, "service_name": "Netflix", "price": 799, "start_date": "07-2025"})
+	}), Config{TenantID: tenantID, APIKey: "secret"})
+
+	sub, err := c.GetSub(context.Background(), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.ID != 7 || sub.ServiceName != "Netflix" || sub.Price != 799 {
+		t.Errorf("GetSub() = %+v", sub)
+	}
+	if want := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC); !sub.StartDate.Equal(want) {
+		t.Errorf("start date = %v, want %v", sub.StartDate, want)
+	}
+	if got := header.Get("X-Tenant-ID"); got != tenantID.String() {
+		t.Errorf("X-Tenant-ID = %q, want %q", got, tenantID)
+	}
+	if got := header.Get("X-API-Key"); got != "secret" {
+		t.Errorf("X-API-Key = %q, want secret", got)
+	}
+}
+
+func TestDecodesErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{status: http.StatusBadRequest, want: ErrBadRequest},
+		{status: http.StatusUnauthorized, want: ErrUnauthorized},
+		{status: http.StatusForbidden, want: ErrUnauthorized},
+		{status: http.StatusNotFound, want: ErrNotFound},
+		{status: http.StatusConflict, want: ErrConflict},
+		{status: http.StatusTooManyRequests, want: ErrRateLimited},
+		{status: http.StatusInternalServerError, want: ErrServer},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sendError(w, tt.status, "Error: something", "req-1")
+			}), Config{MaxRetries: -1})
+
+			_, err := c.GetSub(context.Background(), 1)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+			var apiErr *Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error %T is not an *Error", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Message != "Error: something" || apiErr.RequestID != "req-1" {
+				t.Errorf("error = %+v", apiErr)
+			}
+			for _, other := range []error{ErrBadRequest, ErrNotFound, ErrConflict} {
+				if other != tt.want && errors.Is(err, other) {
+					t.Errorf("error %v also matches %v", err, other)
+				}
+			}
+		})
+	}
+}
+
+// An error without a JSON body still has its status and the request ID
+// of the header.
+func TestDecodesPlainErrors(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", "req-2")
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}), Config{MaxRetries: -1})
+
+	_, err := c.GetSub(context.Background(), 1)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway || apiErr.RequestID != "req-2" {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	sub := Subscription{ServiceName: "Netflix", Price: 799, UserID: uuid.New()}
+	tests := []struct {
+		name       string
+		call       func(c *Client) error
+		status     int
+		failures   int
+		maxRetries int
+		wantCalls  int32
+		wantErr    error
+	}{
+		{
+			name:     "get retried until it succeeds",
+			call:     func(c *Client) error { _, err := c.GetSub(context.Background(), 1); return err },
+			status:   http.StatusServiceUnavailable,
+			failures: 2, wantCalls: 3,
+		},
+		{
+			name:     "delete retried on 429",
+			call:     func(c *Client) error { return c.DeleteSub(context.Background(), 1) },
+			status:   http.StatusTooManyRequests,
+			failures: 1, wantCalls: 2,
+		},
+		{
+			name:     "get gives up after max retries",
+			call:     func(c *Client) error { _, err := c.GetSub(context.Background(), 1); return err },
+			status:   http.StatusBadGateway,
+			failures: 10, maxRetries: 2, wantCalls: 3, wantErr: ErrServer,
+		},
+		{
+			name:     "post not retried",
+			call:     func(c *Client) error { _, err := c.CreateSub(context.Background(), sub); return err },
+			status:   http.StatusServiceUnavailable,
+			failures: 1, wantCalls: 1, wantErr: ErrServer,
+		},
+		{
+			name:     "500 not retried",
+			call:     func(c *Client) error { _, err := c.GetSub(context.Background(), 1); return err },
+			status:   http.StatusInternalServerError,
+			failures: 1, wantCalls: 1, wantErr: ErrServer,
+		},
+		{
+			name:     "retries disabled",
+			call:     func(c *Client) error { _, err := c.GetSub(context.Background(), 1); return err },
+			status:   http.StatusServiceUnavailable,
+			failures: 1, maxRetries: -1, wantCalls: 1, wantErr: ErrServer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if int(calls.Add(1)) <= tt.failures {
+					sendError(w, tt.status, "Error: try again", "")
+					return
+				}
+				sendData(w, map[string]any{"id": 1})
+			}), Config{MaxRetries: tt.maxRetries})
+
+			err := tt.call(c)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDelay(t *testing.T) {
+	c := &Client{backoff: 100 * time.Millisecond}
+	for attempt := range 4 {
+		if d := c.delay(attempt, nil); d < 0 || d > c.backoff<<attempt {
+			t.Errorf("delay(%d) = %v, want at most %v", attempt, d, c.backoff<<attempt)
+		}
+	}
+	if d := c.delay(20, nil); d > maxBackoff {
+		t.Errorf("delay(20) = %v, want at most %v", d, maxBackoff)
+	}
+
+	res := &http.Response{Header: http.Header{"Retry-After": {"3"}}}
+	if d := c.delay(0, res); d != 3*time.Second {
+		t.Errorf("delay with Retry-After: 3 = %v, want 3s", d)
+	}
+	res.Header.Set("Retry-After", "3600")
+	if d := c.delay(0, res); d != maxBackoff {
+		t.Errorf("delay with Retry-After: 3600 = %v, want %v", d, maxBackoff)
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	t.Run("during the request", func(t *testing.T) {
+		c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}), Config{})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := c.GetSub(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("during the backoff", func(t *testing.T) {
+		var calls atomic.Int32
+		c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.Header().Set("Retry-After", "5")
+			sendError(w, http.StatusServiceUnavailable, "Error: try again", "")
+		}), Config{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		start := time.Now()
+		if _, err := c.GetSub(ctx, 1); !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want %v", err, context.Canceled)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("returned after %v, want right after the cancellation", elapsed)
+		}
+		if got := calls.Load(); got != 1 {
+			t.Errorf("calls = %d, want 1", got)
+		}
+	})
+}
+
+// pages serves total subscriptions by limit and offset, like `GET /api/subs`,
+// failing the request at offset failAt when it is positive.
+func pages(total, failAt int, offsets *[]int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		*offsets = append(*offsets, offset)
+		if failAt > 0 && offset == failAt {
+			sendError(w, http.StatusBadRequest, "Error: bad page", "")
+			return
+		}
+
+		page := []map[string]any{}
+		for id := offset + 1; id <= min(offset+limit, total); id++ {
+			page = append(page, map[string]any{"id": id})
+		}
+		sendData(w, page)
+	})
+}
+
+func TestAllSubs(t *testing.T) {
+	tests := []struct {
+		name        string
+		total       int
+		failAt      int
+		stopAfter   int
+		wantIDs     []int32
+		wantOffsets []int
+		wantErr     error
+	}{
+		{name: "all pages", total: 5, wantIDs: []int32{1, 2, 3, 4, 5}, wantOffsets: []int{0, 2, 4}},
+		{name: "last page full", total: 4, wantIDs: []int32{1, 2, 3, 4}, wantOffsets: []int{0, 2, 4}},
+		{name: "empty", total: 0, wantOffsets: []int{0}},
+		{name: "stop early", total: 5, stopAfter: 3, wantIDs: []int32{1, 2, 3}, wantOffsets: []int{0, 2}},
+		{name: "error", total: 5, failAt: 2, wantIDs: []int32{1, 2}, wantOffsets: []int{0, 2}, wantErr: ErrBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var offsets []int
+			c := newTestClient(t, pages(tt.total, tt.failAt, &offsets), Config{})
+
+			var ids []int32
+			var errs []error
+			for sub, err := range c.AllSubs(context.Background(), ListOptions{Limit: 2}) {
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				ids = append(ids, sub.ID)
+				if len(ids) == tt.stopAfter {
+					break
+				}
+			}
+
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+			if !slices.Equal(offsets, tt.wantOffsets) {
+				t.Errorf("offsets = %v, want %v", offsets, tt.wantOffsets)
+			}
+			switch {
+			case tt.wantErr == nil && len(errs) > 0:
+				t.Errorf("errors = %v, want none", errs)
+			case tt.wantErr != nil && (len(errs) != 1 || !errors.Is(errs[0], tt.wantErr)):
+				t.Errorf("errors = %v, want one %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
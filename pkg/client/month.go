@@ -0,0 +1,55 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const monthFormat = "01-2006"
+
+// Month is a calendar month, sent by the API as `MM-YYYY`.
+type Month struct {
+	time.Time
+}
+
+func NewMonth(year int, month time.Month) Month {
+	return Month{time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func ParseMonth(s string) (Month, error) {
+	t, err := time.Parse(monthFormat, s)
+	if err != nil {
+		return Month{}, fmt.Errorf("client: month %q is not MM-YYYY: %w", s, err)
+	}
+	return Month{t}, nil
+}
+
+func (m Month) String() string {
+	if m.IsZero() {
+		return ""
+	}
+	return m.Format(monthFormat)
+}
+
+func (m Month) MarshalJSON() ([]byte, error) {
+	if m.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(`"` + m.String() + `"`), nil
+}
+
+func (m *Month) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "null" || s == "" {
+		*m = Month{}
+		return nil
+	}
+
+	parsed, err := ParseMonth(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
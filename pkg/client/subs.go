@@ -0,0 +1,526 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const defaultPageSize = 100
+
+type Subscription struct {
+	ID          int32     `json:"id,omitempty"`
+	ServiceName string    `json:"service_name"`
+	Price       int32     `json:"price"`
+	UserID      uuid.UUID `json:"user_id"`
+	StartDate   Month     `json:"start_date"`
+	EndDate     Month     `json:"end_date,omitzero"`
+	// TrialEndsAt is the day of the first paid charge, months before it
+	// cost TrialPrice.
+	TrialEndsAt time.Time `json:"trial_ends_at,omitzero"`
+	TrialPrice  int32     `json:"trial_price,omitempty"`
+	// Metadata holds string, number and boolean values of the integrator,
+	// UpdateSub keeps the stored metadata when it is nil.
+	Metadata map[string]any `json:"metadata,omitempty"`
+	// Pauses are set by the server, use AddPause and RemovePause.
+	Pauses []Pause `json:"pauses,omitempty"`
+	// Prices is the price timeline set by the server, Price is the price in
+	// effect now. Use SchedulePrice for future changes.
+	Prices []Price `json:"prices,omitempty"`
+	// Discounts are set by the server, use AddDiscount and RemoveDiscount.
+	Discounts []Discount `json:"discounts,omitempty"`
+	// Members are set by the server, use AddMember, InviteMember and
+	// RemoveMember.
+	Members []Member `json:"members,omitempty"`
+	// Categories and Tags are set by the server, use Categorize and AddTag.
+	Categories []string `json:"categories,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	// FirstChargeAt is the day of the first charge at the full price, set
+	// by the server.
+	FirstChargeAt time.Time `json:"first_charge_at,omitzero"`
+	// Charge is the amount charged for the current month, set by the
+	// server.
+	Charge int32 `json:"charge,omitempty"`
+	// Status is set by the server, it is ignored on create and update.
+	Status string `json:"status,omitempty"`
+	// Warnings are the budgets of the user over after CreateSub or
+	// UpdateSub.
+	Warnings []BudgetStatus `json:"warnings,omitempty"`
+}
+
+// Pause is a range of months a subscription is not charged for, both ends
+// included. EndDate is zero for the pause of a paused subscription, which
+// lasts until it is resumed.
+type Pause struct {
+	ID        int32 `json:"id,omitempty"`
+	StartDate Month `json:"start_date"`
+	EndDate   Month `json:"end_date,omitzero"`
+}
+
+// Price is the monthly price of a subscription from EffectiveFrom until the
+// next change.
+type Price struct {
+	ID            int32  `json:"id,omitempty"`
+	EffectiveFrom Month  `json:"effective_from"`
+	Price         int32  `json:"price"`
+	Currency      string `json:"currency,omitempty"`
+}
+
+// Discount lowers the price from StartDate for Cycles months or until the
+// Until date, exactly one of them is set.
+type Discount struct {
+	ID int32 `json:"id,omitempty"`
+	// Kind is "percent" or "fixed".
+	Kind      string    `json:"kind"`
+	Amount    int32     `json:"amount"`
+	StartDate Month     `json:"start_date"`
+	Cycles    int32     `json:"cycles,omitempty"`
+	Until     time.Time `json:"until,omitzero"`
+	// EndsAt is the first month charged in full again, set by the server.
+	EndsAt Month `json:"ends_at,omitzero"`
+}
+
+// Member is a user sharing a subscription with its owner.
+type Member struct {
+	UserID uuid.UUID `json:"user_id"`
+	// Split is "equal", "percent" or "fixed", Amount is empty for equal.
+	Split  string `json:"split"`
+	Amount int32  `json:"amount,omitempty"`
+	// Status, "invited" or "active", and Share, the part of the current
+	// month's charge, are set by the server.
+	Status    string    `json:"status,omitempty"`
+	Share     int32     `json:"share,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// The member pays from the month of JoinedAt up to the month before
+	// RemovedAt, removed members are listed too.
+	JoinedAt  time.Time `json:"joined_at,omitzero"`
+	RemovedAt time.Time `json:"removed_at,omitzero"`
+}
+
+// ExpiringDiscount is an item of ExpiringDiscounts.
+type ExpiringDiscount struct {
+	SubscriptionID int32     `json:"subscription_id"`
+	ServiceName    string    `json:"service_name"`
+	UserID         uuid.UUID `json:"user_id"`
+	Discount       Discount  `json:"discount"`
+	Charge         int32     `json:"charge"`
+	NextCharge     int32     `json:"next_charge"`
+}
+
+type ListOptions struct {
+	// UserID filters by user when set.
+	UserID uuid.UUID
+	// IncludeShared also lists the subscriptions shared with UserID.
+	IncludeShared bool
+	// Status filters by status when set.
+	Status string
+	// Category and Tag filter by category and tag name when set.
+	Category string
+	Tag      string
+	// Metadata filters by the value of each key, the stored string or the
+	// number or boolean the value reads as.
+	Metadata map[string]string
+	// Limit and Offset select a page ordered by ID, all rows if Limit is 0.
+	Limit  int
+	Offset int
+}
+
+func (o ListOptions) query() url.Values {
+	q := url.Values{}
+	if o.UserID != uuid.Nil {
+		q.Set("user_id", o.UserID.String())
+	}
+	if o.IncludeShared {
+		q.Set("include_shared", "true")
+	}
+	if o.Status != "" {
+		q.Set("status", o.Status)
+	}
+	if o.Category != "" {
+		q.Set("category", o.Category)
+	}
+	if o.Tag != "" {
+		q.Set("tag", o.Tag)
+	}
+	for k, v := range o.Metadata {
+		q.Set("metadata."+k, v)
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Offset > 0 {
+		q.Set("offset", strconv.Itoa(o.Offset))
+	}
+	return q
+}
+
+// ListSubs calls `GET /api/subs`.
+func (c *Client) ListSubs(ctx context.Context, opts ListOptions) ([]Subscription, error) {
+	var subs []Subscription
+	err := c.do(ctx, http.MethodGet, "/api/subs", opts.query(), nil, &subs)
+	return subs, err
+}
+
+// AllSubs iterates over all subscriptions matching opts, fetching pages of
+// opts.Limit (100 by default) as it goes. Iteration stops at the first
+// error.
+func (c *Client) AllSubs(ctx context.Context, opts ListOptions) iter.Seq2[Subscription, error] {
+	if opts.Limit <= 0 {
+		opts.Limit = defaultPageSize
+	}
+
+	return func(yield func(Subscription, error) bool) {
+		for {
+			page, err := c.ListSubs(ctx, opts)
+			if err != nil {
+				yield(Subscription{}, err)
+				return
+			}
+
+			for _, s := range page {
+				if !yield(s, nil) {
+					return
+				}
+			}
+
+			if len(page) < opts.Limit {
+				return
+			}
+			opts.Offset += len(page)
+		}
+	}
+}
+
+// EndingTrials calls `GET /api/subs/ending-trials` for trials converting
+// to paid within days, of one user when userID is set.
+func (c *Client) EndingTrials(ctx context.Context, userID uuid.UUID, days int) ([]Subscription, error) {
+	q := url.Values{"days": {strconv.Itoa(days)}}
+	if userID != uuid.Nil {
+		q.Set("user_id", userID.String())
+	}
+	var subs []Subscription
+	err := c.do(ctx, http.MethodGet, "/api/subs/ending-trials", q, nil, &subs)
+	return subs, err
+}
+
+// GetSub calls `GET /api/sub/{id}`.
+func (c *Client) GetSub(ctx context.Context, id int32) (Subscription, error) {
+	var s Subscription
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/sub/%d", id), nil, nil, &s)
+	return s, err
+}
+
+// CreateSub calls `POST /api/sub` and returns the stored subscription. It is
+// never retried, as a retry could create a duplicate.
+func (c *Client) CreateSub(ctx context.Context, s Subscription) (Subscription, error) {
+	var created Subscription
+	err := c.do(ctx, http.MethodPost, "/api/sub", nil, s, &created)
+	return created, err
+}
+
+// UpdateSub calls `PUT /api/sub/{id}` with s.ID.
+func (c *Client) UpdateSub(ctx context.Context, s Subscription) (Subscription, error) {
+	var updated Subscription
+	err := c.do(ctx, http.MethodPut, fmt.Sprintf("/api/sub/%d", s.ID), nil, s, &updated)
+	return updated, err
+}
+
+// Patch holds the fields changed by PatchSub, nil fields are kept.
+type Patch struct {
+	ServiceName *string    `json:"service_name,omitempty"`
+	Price       *int32     `json:"price,omitempty"`
+	UserID      *uuid.UUID `json:"user_id,omitempty"`
+	StartDate   *Month     `json:"start_date,omitempty"`
+	EndDate     *Month     `json:"end_date,omitempty"`
+	// Metadata is merged into the stored metadata, a nil value removes its
+	// key.
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// PatchSub calls `PATCH /api/sub/{id}`.
+func (c *Client) PatchSub(ctx context.Context, id int32, p Patch) (Subscription, error) {
+	var patched Subscription
+	err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/api/sub/%d", id), nil, p, &patched)
+	return patched, err
+}
+
+// DeleteSub calls `DELETE /api/sub/{id}`.
+func (c *Client) DeleteSub(ctx context.Context, id int32) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/sub/%d", id), nil, nil, nil)
+}
+
+// DeleteUserSubs calls `DELETE /api/subs` and returns the deleted IDs.
+func (c *Client) DeleteUserSubs(ctx context.Context, userID uuid.UUID) ([]int32, error) {
+	q := url.Values{}
+	q.Set("user_id", userID.String())
+
+	var ids []int32
+	err := c.do(ctx, http.MethodDelete, "/api/subs", q, nil, &ids)
+	return ids, err
+}
+
+// AddPause calls `POST /api/sub/{id}/pauses`.
+func (c *Client) AddPause(ctx context.Context, subID int32, p Pause) (Pause, error) {
+	var added Pause
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/sub/%d/pauses", subID), nil, p, &added)
+	return added, err
+}
+
+// Pauses calls `GET /api/sub/{id}/pauses`.
+func (c *Client) Pauses(ctx context.Context, subID int32) ([]Pause, error) {
+	var pauses []Pause
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/sub/%d/pauses", subID), nil, nil, &pauses)
+	return pauses, err
+}
+
+// RemovePause calls `DELETE /api/sub/{id}/pauses/{pause_id}`.
+func (c *Client) RemovePause(ctx context.Context, subID, pauseID int32) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/sub/%d/pauses/%d", subID, pauseID), nil, nil, nil)
+}
+
+// SchedulePrice calls `POST /api/sub/{id}/prices` and returns the new
+// timeline.
+func (c *Client) SchedulePrice(ctx context.Context, subID int32, p Price) ([]Price, error) {
+	var prices []Price
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/sub/%d/prices", subID), nil, p, &prices)
+	return prices, err
+}
+
+// Prices calls `GET /api/sub/{id}/prices`.
+func (c *Client) Prices(ctx context.Context, subID int32) ([]Price, error) {
+	var prices []Price
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/sub/%d/prices", subID), nil, nil, &prices)
+	return prices, err
+}
+
+// PriceChange is the body of ChangeServicePrice.
+type PriceChange struct {
+	Price         int32  `json:"price"`
+	EffectiveFrom Month  `json:"effective_from"`
+	Currency      string `json:"currency,omitempty"`
+	// OldPrice only changes subscriptions paying it before the change.
+	OldPrice *int32 `json:"old_price,omitempty"`
+	DryRun   bool   `json:"dry_run,omitempty"`
+}
+
+type PriceChangeResult struct {
+	DryRun  bool `json:"dry_run"`
+	Changed []struct {
+		ID       int32     `json:"id"`
+		UserID   uuid.UUID `json:"user_id"`
+		OldPrice int32     `json:"old_price"`
+		NewPrice int32     `json:"new_price"`
+	} `json:"changed"`
+	MonthlyDelta int64 `json:"monthly_delta"`
+}
+
+// ChangeServicePrice calls `POST /api/services/{name}/price-change`, like
+// every POST it is never retried.
+func (c *Client) ChangeServicePrice(ctx context.Context, service string, change PriceChange) (PriceChangeResult, error) {
+	var res PriceChangeResult
+	err := c.do(ctx, http.MethodPost, "/api/services/"+url.PathEscape(service)+"/price-change", nil, change, &res)
+	return res, err
+}
+
+// AddDiscount calls `POST /api/sub/{id}/discounts`.
+func (c *Client) AddDiscount(ctx context.Context, subID int32, d Discount) (Discount, error) {
+	var added Discount
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/sub/%d/discounts", subID), nil, d, &added)
+	return added, err
+}
+
+// Discounts calls `GET /api/sub/{id}/discounts`.
+func (c *Client) Discounts(ctx context.Context, subID int32) ([]Discount, error) {
+	var discounts []Discount
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/sub/%d/discounts", subID), nil, nil, &discounts)
+	return discounts, err
+}
+
+// RemoveDiscount calls `DELETE /api/sub/{id}/discounts/{discount_id}`.
+func (c *Client) RemoveDiscount(ctx context.Context, subID, discountID int32) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/sub/%d/discounts/%d", subID, discountID), nil, nil, nil)
+}
+
+// ExpiringDiscounts calls `GET /api/subs/expiring-discounts` for discounts
+// ending within days, of one user when userID is set.
+func (c *Client) ExpiringDiscounts(ctx context.Context, userID uuid.UUID, days int) ([]ExpiringDiscount, error) {
+	q := url.Values{"days": {strconv.Itoa(days)}}
+	if userID != uuid.Nil {
+		q.Set("user_id", userID.String())
+	}
+	var expiring []ExpiringDiscount
+	err := c.do(ctx, http.MethodGet, "/api/subs/expiring-discounts", q, nil, &expiring)
+	return expiring, err
+}
+
+// AddMember calls `POST /api/sub/{id}/members`.
+func (c *Client) AddMember(ctx context.Context, subID int32, m Member) (Member, error) {
+	var added Member
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/sub/%d/members", subID), nil, m, &added)
+	return added, err
+}
+
+// InviteMember calls `POST /api/sub/{id}/invites`.
+func (c *Client) InviteMember(ctx context.Context, subID int32, m Member) (Member, error) {
+	var invited Member
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/sub/%d/invites", subID), nil, m, &invited)
+	return invited, err
+}
+
+// AcceptInvite calls `POST /api/sub/{id}/invites/{user_id}/accept`.
+func (c *Client) AcceptInvite(ctx context.Context, subID int32, userID uuid.UUID) (Member, error) {
+	var m Member
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/sub/%d/invites/%s/accept", subID, userID), nil, nil, &m)
+	return m, err
+}
+
+// Members calls `GET /api/sub/{id}/members`.
+func (c *Client) Members(ctx context.Context, subID int32) ([]Member, error) {
+	var members []Member
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/sub/%d/members", subID), nil, nil, &members)
+	return members, err
+}
+
+// RemoveMember calls `DELETE /api/sub/{id}/members/{user_id}`.
+func (c *Client) RemoveMember(ctx context.Context, subID int32, userID uuid.UUID) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/sub/%d/members/%s", subID, userID), nil, nil, nil)
+}
+
+// Budget limits the spend of a user in Currency per "month" or "year",
+// over all subscriptions (Scope "overall") or the ones of the service or
+// category named by Target (Scope "service" or "category").
+type Budget struct {
+	ID       int32  `json:"id,omitempty"`
+	Scope    string `json:"scope"`
+	Target   string `json:"target,omitempty"`
+	Amount   int32  `json:"amount"`
+	Currency string `json:"currency"`
+	Period   string `json:"period"`
+	// Enforce makes CreateSub and UpdateSub fail with ErrConflict instead
+	// of warning.
+	Enforce bool `json:"enforce,omitempty"`
+}
+
+type BudgetStatus struct {
+	Budget    Budget `json:"budget"`
+	From      Month  `json:"from"`
+	To        Month  `json:"to"`
+	Spent     int64  `json:"spent"`
+	Remaining int64  `json:"remaining"`
+	Over      bool   `json:"over"`
+}
+
+// AddBudget calls `POST /api/users/{id}/budgets`.
+func (c *Client) AddBudget(ctx context.Context, userID uuid.UUID, b Budget) (Budget, error) {
+	var added Budget
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/users/%s/budgets", userID), nil, b, &added)
+	return added, err
+}
+
+// Budgets calls `GET /api/users/{id}/budgets`.
+func (c *Client) Budgets(ctx context.Context, userID uuid.UUID) ([]Budget, error) {
+	var budgets []Budget
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/users/%s/budgets", userID), nil, nil, &budgets)
+	return budgets, err
+}
+
+// RemoveBudget calls `DELETE /api/users/{id}/budgets/{budget_id}`.
+func (c *Client) RemoveBudget(ctx context.Context, userID uuid.UUID, budgetID int32) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/users/%s/budgets/%d", userID, budgetID), nil, nil, nil)
+}
+
+// BudgetStatus calls `GET /api/users/{id}/budget-status`.
+func (c *Client) BudgetStatus(ctx context.Context, userID uuid.UUID) ([]BudgetStatus, error) {
+	var statuses []BudgetStatus
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/users/%s/budget-status", userID), nil, nil, &statuses)
+	return statuses, err
+}
+
+type Category struct {
+	ID   int32  `json:"id,omitempty"`
+	Name string `json:"name"`
+}
+
+// CategorySpend is an item of SpendByCategory, Category is empty for the
+// subscriptions without one.
+type CategorySpend struct {
+	Category      string `json:"category"`
+	Subscriptions int    `json:"subscriptions"`
+	Total         int64  `json:"total"`
+}
+
+// AddCategory calls `POST /api/categories`, it fails with ErrConflict when
+// the organization already has the name.
+func (c *Client) AddCategory(ctx context.Context, name string) (Category, error) {
+	var added Category
+	err := c.do(ctx, http.MethodPost, "/api/categories", nil, Category{Name: name}, &added)
+	return added, err
+}
+
+// Categories calls `GET /api/categories`.
+func (c *Client) Categories(ctx context.Context) ([]Category, error) {
+	var categories []Category
+	err := c.do(ctx, http.MethodGet, "/api/categories", nil, nil, &categories)
+	return categories, err
+}
+
+// RemoveCategory calls `DELETE /api/categories/{category_id}`.
+func (c *Client) RemoveCategory(ctx context.Context, id int32) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/categories/%d", id), nil, nil, nil)
+}
+
+// Tags calls `GET /api/tags`.
+func (c *Client) Tags(ctx context.Context) ([]string, error) {
+	var tags []string
+	err := c.do(ctx, http.MethodGet, "/api/tags", nil, nil, &tags)
+	return tags, err
+}
+
+// Categorize calls `POST /api/sub/{id}/categories`.
+func (c *Client) Categorize(ctx context.Context, subID int32, category string) (Subscription, error) {
+	var s Subscription
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/sub/%d/categories", subID), nil, Category{Name: category}, &s)
+	return s, err
+}
+
+// Uncategorize calls `DELETE /api/sub/{id}/categories/{name}`.
+func (c *Client) Uncategorize(ctx context.Context, subID int32, category string) (Subscription, error) {
+	var s Subscription
+	err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/sub/%d/categories/%s", subID, url.PathEscape(category)), nil, nil, &s)
+	return s, err
+}
+
+// AddTag calls `POST /api/sub/{id}/tags`.
+func (c *Client) AddTag(ctx context.Context, subID int32, tag string) (Subscription, error) {
+	var s Subscription
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/sub/%d/tags", subID), nil, map[string]string{"name": tag}, &s)
+	return s, err
+}
+
+// RemoveTag calls `DELETE /api/sub/{id}/tags/{name}`.
+func (c *Client) RemoveTag(ctx context.Context, subID int32, tag string) (Subscription, error) {
+	var s Subscription
+	err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/sub/%d/tags/%s", subID, url.PathEscape(tag)), nil, nil, &s)
+	return s, err
+}
+
+// SpendByCategory calls `GET /api/subs/spend-by-category` for the
+// subscriptions matching opts over [from, to], Limit and Offset are
+// ignored.
+func (c *Client) SpendByCategory(ctx context.Context, opts ListOptions, from, to time.Time) ([]CategorySpend, error) {
+	q := opts.query()
+	q.Del("limit")
+	q.Del("offset")
+	q.Set("from", from.Format(monthFormat))
+	q.Set("to", to.Format(monthFormat))
+
+	var spend []CategorySpend
+	err := c.do(ctx, http.MethodGet, "/api/subs/spend-by-category", q, nil, &spend)
+	return spend, err
+}